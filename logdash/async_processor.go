@@ -2,64 +2,226 @@ package logdash
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // asyncProcessor is a generic processor for handling asynchronous operations.
 type asyncProcessor[T any] struct {
-	processChan    chan T
-	stoppedChan    chan struct{}
-	processChanMu  sync.RWMutex
+	processChan   chan queuedItem[T]
+	stoppedChan   chan struct{}
+	workersWg     sync.WaitGroup
+	processChanMu sync.RWMutex
+	// workers is the number of workers processing the channel
+	workers int
+	// generation tracks workers of the current channel, so workers of a resized channel start after them
+	generation     *sync.WaitGroup
 	overflowPolicy OverflowPolicy
 	processFunc    func(T) error
 	errorHandler   func(T, error)
+	// dropHandler receives items dropped due to overflow, after errorHandler, nil means no handler
+	dropHandler func(T)
+	// releaseHandler receives items which are not used anymore, after they're processed or dropped,
+	// nil means no handler
+	releaseHandler func(T)
+
+	// sequence numbers items as they're enqueued, so flush can wait for items enqueued before it
+	sequence atomic.Int64
+	// completion tracks sequence numbers of items which are processed or dropped
+	completion completionTracker
+
+	// watermarks notify about the fill of the channel, nil means no notifications
+	watermarks *bufferWatermarks
+}
+
+// queuedItem is an item waiting to be processed with its sequence number.
+type queuedItem[T any] struct {
+	item T
+	seq  int64
 }
 
-// errChannelOverflow is returned when the channel is full and the overflow policy is set to drop.
-var errChannelOverflow = errors.New("channel overflow")
+// completionTracker tracks sequence numbers of finished items, which may finish out of order.
+type completionTracker struct {
+	mu sync.Mutex
+	// done is the sequence number up to which all items are finished
+	done int64
+	// finishedAhead holds sequence numbers above done of finished items
+	finishedAhead map[int64]struct{}
+}
+
+// bufferWatermarks notifies when the fill of a buffer crosses watermarks (see: [WithBufferWatermarks]).
+type bufferWatermarks struct {
+	high   float64
+	low    float64
+	onHigh func()
+	onLow  func()
+	// above is true since the fill reached the high watermark until it drops to the low watermark,
+	// so callbacks don't flap when the fill oscillates around a single watermark
+	above atomic.Bool
+}
 
 // newAsyncProcessor creates a new async processor instance.
-func newAsyncProcessor[T any](bufferSize int, processFunc func(T) error, errorHandler func(T, error)) *asyncProcessor[T] {
+//
+// Items are processed by the given number of concurrent workers, at least one.
+// With more than one worker, items may be processed out of order.
+func newAsyncProcessor[T any](bufferSize int, workers int, processFunc func(T) error, errorHandler func(T, error)) *asyncProcessor[T] {
 	processor := &asyncProcessor[T]{
-		processChan:    make(chan T, bufferSize),
+		processChan:    make(chan queuedItem[T], bufferSize),
 		stoppedChan:    make(chan struct{}),
 		overflowPolicy: OverflowPolicyBlock, // Default to blocking
 		processFunc:    processFunc,
 		errorHandler:   errorHandler,
 	}
 
-	// Start background worker
-	go processor.process(processor.processChan)
+	// Start background workers
+	workers = max(workers, 1)
+	processor.workers = workers
+	processor.generation = &sync.WaitGroup{}
+	processor.generation.Add(workers)
+	processor.workersWg.Add(workers)
+	for range workers {
+		go processor.process(processor.processChan, processor.generation)
+	}
+	go func() {
+		processor.workersWg.Wait()
+		close(processor.stoppedChan)
+	}()
 
 	return processor
 }
 
 // process handles the background processing of items
-func (p *asyncProcessor[T]) process(ch chan T) {
-	defer close(p.stoppedChan)
-	for item := range ch {
-		if err := p.processFunc(item); err != nil {
-			p.errorHandler(item, err)
-		}
+func (p *asyncProcessor[T]) process(ch chan queuedItem[T], generation *sync.WaitGroup) {
+	defer p.workersWg.Done()
+	defer generation.Done()
+	for queued := range ch {
+		p.handle(queued)
+		p.watermarks.check(len(ch), cap(ch))
+	}
+}
+
+// handle processes a single item.
+func (p *asyncProcessor[T]) handle(queued queuedItem[T]) {
+	if err := p.processFunc(queued.item); err != nil {
+		p.errorHandler(queued.item, err)
 	}
+	p.release(queued.item)
+	p.completion.complete(queued.seq)
+}
+
+// release passes the item, which is not used anymore, to the release handler.
+func (p *asyncProcessor[T]) release(item T) {
+	if p.releaseHandler != nil {
+		p.releaseHandler(item)
+	}
+}
+
+// startFlushTicker checks every interval whether items enqueued before the previous tick are processed,
+// calling onLate when they're still waiting, e.g. because workers wait for a slow request.
+//
+// Items are processed only by the workers, so they keep their order. onLate is called once
+// until the items are processed, rather than on every tick.
+func (p *asyncProcessor[T]) startFlushTicker(interval time.Duration, onLate func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var target int64
+		late := false
+		for {
+			select {
+			case <-p.stoppedChan:
+				return
+			case <-ticker.C:
+				if p.completion.finished(target) {
+					late = false
+					target = p.sequence.Load()
+				} else if !late {
+					late = true
+					onLate()
+				}
+			}
+		}
+	}()
 }
 
-// send sends an item to be processed asynchronously
-func (p *asyncProcessor[T]) send(item T) {
+// send sends an item to be processed asynchronously.
+//
+// Returns ErrOverflow when the item is dropped due to overflow,
+// and ErrAlreadyClosed when the processor is already shut down or closed.
+func (p *asyncProcessor[T]) send(item T) error {
 	p.processChanMu.RLock()
 	defer p.processChanMu.RUnlock()
+	if p.processChan == nil {
+		p.release(item)
+		return ErrAlreadyClosed
+	}
+	queued := queuedItem[T]{item: item, seq: p.sequence.Add(1)}
 	select {
-	case p.processChan <- item:
+	case p.processChan <- queued:
 		// Item sent to channel
 	default:
 		// Channel is full
 		if p.overflowPolicy == OverflowPolicyDrop {
-			p.errorHandler(item, errChannelOverflow)
-			return
+			p.errorHandler(item, ErrOverflow)
+			if p.dropHandler != nil {
+				p.dropHandler(item)
+			}
+			p.release(item)
+			p.completion.complete(queued.seq)
+			return ErrOverflow
 		}
 		// Block until there's space in the channel
-		p.processChan <- item
+		p.processChan <- queued
+	}
+	p.watermarks.check(len(p.processChan), cap(p.processChan))
+	return nil
+}
+
+// check invokes callbacks when the fill of the buffer with the given length and capacity crosses watermarks.
+func (w *bufferWatermarks) check(length, capacity int) {
+	if w == nil || capacity == 0 {
+		return
+	}
+
+	fill := float64(length) / float64(capacity)
+	if fill >= w.high {
+		if w.above.CompareAndSwap(false, true) && w.onHigh != nil {
+			w.onHigh()
+		}
+	} else if fill <= w.low {
+		if w.above.CompareAndSwap(true, false) && w.onLow != nil {
+			w.onLow()
+		}
+	}
+}
+
+// flushPollInterval is the interval of checking whether items are processed by flush.
+const flushPollInterval = time.Millisecond
+
+// flush waits until items enqueued before the call are processed, without stopping the workers.
+//
+// Items enqueued after the call may be processed earlier by other workers, so it waits for sequence numbers
+// of the items rather than their count.
+func (p *asyncProcessor[T]) flush(ctx context.Context) error {
+	target := p.sequence.Load()
+	if p.completion.finished(target) {
+		return nil
+	}
+
+	ticker := time.NewTicker(flushPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if p.completion.finished(target) {
+				return nil
+			}
+		}
 	}
 }
 
@@ -86,6 +248,7 @@ func (p *asyncProcessor[T]) safeClear() error {
 func (p *asyncProcessor[T]) Shutdown(ctx context.Context) error {
 	p.processChanMu.Lock()
 	if err := p.safeClear(); err != nil {
+		p.processChanMu.Unlock()
 		return err
 	}
 	p.processChanMu.Unlock()
@@ -98,7 +261,82 @@ func (p *asyncProcessor[T]) Shutdown(ctx context.Context) error {
 	}
 }
 
+// resize replaces the channel with one of the given capacity, moving buffered items to it in order.
+//
+// Workers of the new channel start after workers of the previous channel finish items they're processing,
+// so items are processed in the same order as without the resize, and never by more workers than configured.
+func (p *asyncProcessor[T]) resize(size int) error {
+	p.processChanMu.Lock()
+	defer p.processChanMu.Unlock()
+
+	if p.processChan == nil {
+		return ErrAlreadyClosed
+	}
+	old := p.processChan
+	if size < len(old) {
+		return fmt.Errorf("%w: %d items buffered", ErrBufferTooSmall, len(old))
+	}
+
+	// senders wait for the lock, so the old channel only shrinks and the new one never blocks
+	ch := make(chan queuedItem[T], size)
+MOVE:
+	for {
+		select {
+		case item := <-old:
+			ch <- item
+		default:
+			break MOVE
+		}
+	}
+
+	// workers of the new channel are counted before workers of the old one exit,
+	// so the processor isn't considered stopped meanwhile
+	previous := p.generation
+	generation := &sync.WaitGroup{}
+	generation.Add(p.workers)
+	p.workersWg.Add(p.workers)
+	go func() {
+		previous.Wait()
+		for range p.workers {
+			go p.process(ch, generation)
+		}
+	}()
+	p.generation = generation
+	close(old)
+	p.processChan = ch
+	return nil
+}
+
 // SetOverflowPolicy sets the overflow policy for the processor
 func (p *asyncProcessor[T]) SetOverflowPolicy(policy OverflowPolicy) {
 	p.overflowPolicy = policy
 }
+
+// complete marks the item with the sequence number as finished.
+func (c *completionTracker) complete(seq int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if seq != c.done+1 {
+		if c.finishedAhead == nil {
+			c.finishedAhead = make(map[int64]struct{})
+		}
+		c.finishedAhead[seq] = struct{}{}
+		return
+	}
+	c.done = seq
+	for {
+		if _, ok := c.finishedAhead[c.done+1]; !ok {
+			return
+		}
+		delete(c.finishedAhead, c.done+1)
+		c.done++
+	}
+}
+
+// finished returns whether all items up to the sequence number are finished.
+func (c *completionTracker) finished(seq int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done >= seq
+}
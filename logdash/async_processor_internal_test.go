@@ -0,0 +1,72 @@
+package logdash
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncProcessorShutdown(t *testing.T) {
+	t.Run("should release lock when already shut down", func(t *testing.T) {
+		// GIVEN
+		processor := newAsyncProcessor(1, 1, func(int) error { return nil }, func(int, error) {})
+		assert.NoError(t, processor.Shutdown(context.Background()))
+
+		// WHEN
+		err := processor.Shutdown(context.Background())
+		closed := make(chan error)
+		go func() { closed <- processor.Close() }()
+
+		// THEN
+		assert.ErrorIs(t, err, ErrAlreadyClosed)
+		select {
+		case err := <-closed:
+			assert.ErrorIs(t, err, ErrAlreadyClosed)
+		case <-time.After(time.Second):
+			t.Fatal("Close blocked after repeated Shutdown")
+		}
+	})
+}
+
+func TestAsyncProcessorFlush(t *testing.T) {
+	t.Run("should wait for slow item when items enqueued later finish first", func(t *testing.T) {
+		// GIVEN
+		release := make(chan struct{})
+		var processed atomic.Int64
+		processor := newAsyncProcessor(10, 2, func(item int) error {
+			if item == 0 {
+				<-release
+			}
+			processed.Add(1)
+			return nil
+		}, func(int, error) {})
+		defer processor.Close()
+		assert.NoError(t, processor.send(0))
+
+		// WHEN
+		flushed := make(chan error, 1)
+		go func() { flushed <- processor.flush(context.Background()) }()
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, processor.send(1))
+		assert.NoError(t, processor.send(2))
+		assert.Eventually(t, func() bool { return processed.Load() == 2 }, time.Second, time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+
+		// THEN
+		select {
+		case <-flushed:
+			t.Fatal("flush returned before the slow item was processed")
+		default:
+		}
+		close(release)
+		select {
+		case err := <-flushed:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("flush didn't return after the slow item was processed")
+		}
+	})
+}
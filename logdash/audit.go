@@ -0,0 +1,85 @@
+package logdash
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// defaultAuditRetryWait is the wait between rounds of retries in the audit mode,
+// when the maximum wait of HTTP retries is not set (see: [WithHTTPRetryMax]).
+const defaultAuditRetryWait = time.Second
+
+// errAuditUndelivered is returned for logs abandoned on shutdown in the audit mode without a spill writer.
+var errAuditUndelivered = errors.New("log not delivered before shutdown")
+
+// auditDelivery sends logs until they're delivered, in the audit mode (see: [WithAuditMode]).
+//
+// Logs are processed by a single worker, so they're delivered in order and the spill is written sequentially.
+type auditDelivery struct {
+	// ctx is canceled when undelivered logs are abandoned, i.e. when the shutdown context is done
+	ctx    context.Context
+	cancel context.CancelFunc
+	// retryWait is the wait between rounds of retries
+	retryWait time.Duration
+	// spill receives abandoned logs as JSON lines, nil means they're lost
+	spill          io.Writer
+	internalLogger *Logger
+}
+
+// newAuditDelivery creates a new auditDelivery instance.
+func newAuditDelivery(o *options, internalLogger *Logger) *auditDelivery {
+	ctx, cancel := context.WithCancel(context.Background())
+	retryWait := o.httpRetryMax
+	if retryWait <= 0 {
+		retryWait = defaultAuditRetryWait
+	}
+	return &auditDelivery{
+		ctx:            ctx,
+		cancel:         cancel,
+		retryWait:      retryWait,
+		spill:          o.auditSpill,
+		internalLogger: internalLogger,
+	}
+}
+
+// deliver sends the payload until it's delivered or abandoned, abandoned payloads are spilled.
+func (a *auditDelivery) deliver(client *httpClient, method string, payload any) error {
+	for a.ctx.Err() == nil {
+		err := client.sendDataNoDeadline(a.ctx, "/logs", method, payload)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrPayloadTooLarge) {
+			// the server never accepts the payload, so it's not retried
+			return errors.Join(err, a.spillPayload(payload))
+		}
+		a.internalLogger.VerboseF("Audit log not delivered, retrying in %s: %v", a.retryWait, err)
+
+		timer := time.NewTimer(a.retryWait)
+		select {
+		case <-a.ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+	return a.spillPayload(payload)
+}
+
+// spillPayload writes the abandoned payload to the spill as a JSON line.
+func (a *auditDelivery) spillPayload(payload any) error {
+	if a.spill == nil {
+		return errAuditUndelivered
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+	if _, err := a.spill.Write(append(jsonData, '\n')); err != nil {
+		return fmt.Errorf("failed to spill audit log: %w", err)
+	}
+	return nil
+}
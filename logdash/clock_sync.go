@@ -0,0 +1,68 @@
+package logdash
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// clockSyncResolution is the resolution of the Date header, smaller skews can't be measured.
+	clockSyncResolution = time.Second
+	// clockSyncWeight is the weight of a new sample in the rolling skew estimate.
+	clockSyncWeight = 0.25
+)
+
+// clockSync corrects the time source by the skew from the server clock (see: [WithAutoClockSync]).
+//
+// The skew is estimated from the Date header of successful responses as a rolling average.
+type clockSync struct {
+	// source is the time source being corrected
+	source func() time.Time
+	// skew applied to the time source in nanoseconds
+	skew atomic.Int64
+
+	// mu guards the estimate
+	mu       sync.Mutex
+	estimate float64
+	sampled  bool
+}
+
+// newClockSync creates a new clockSync instance correcting the time source.
+func newClockSync(source func() time.Time) *clockSync {
+	return &clockSync{source: source}
+}
+
+// now returns the time of the source corrected by the estimated skew.
+func (s *clockSync) now() time.Time {
+	return s.source().Add(time.Duration(s.skew.Load()))
+}
+
+// observe updates the skew estimate from the Date header of a response received after the latency.
+func (s *clockSync) observe(date string, latency time.Duration) {
+	if s == nil || date == "" {
+		return
+	}
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return
+	}
+	// the header is truncated to seconds and the response was created about half of the latency ago
+	localTime := s.source().Add(-latency / 2)
+	sample := float64(serverTime.Add(clockSyncResolution / 2).Sub(localTime))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sampled {
+		s.estimate += clockSyncWeight * (sample - s.estimate)
+	} else {
+		s.estimate, s.sampled = sample, true
+	}
+	// skews within the resolution are indistinguishable from truncation of the header
+	skew := time.Duration(s.estimate)
+	if skew.Abs() < clockSyncResolution {
+		skew = 0
+	}
+	s.skew.Store(int64(skew))
+}
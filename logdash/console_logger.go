@@ -1,7 +1,12 @@
 package logdash
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -12,40 +17,176 @@ import (
 // consoleLogger implements syncLogger interface for console output.
 type consoleLogger struct {
 	noopResourceManager
-	// mu is used to ensure the log message is printed as a single line
+	// mu is used to ensure the log message is printed as a single line, across both outputs
 	mu sync.Mutex
+	// out is where the log messages are printed
+	out io.Writer
+	// errOut is where the log messages at least as severe as errLevel are printed, nil means out
+	errOut   io.Writer
+	errLevel Level
+	// alignLevels pads level names, so messages are aligned in columns
+	alignLevels bool
+	// shortLevels prints three-letter level names
+	shortLevels bool
+	// levelWidth is the width of the longest level name
+	levelWidth int
+	// format of printed lines
+	format ConsoleFormat
+	// levelColors override colors of levels, nil means colors of levels are used
+	levelColors map[Level]color.RGBColor
+	// timestampColor is the color of printed timestamps
+	timestampColor color.RGBColor
+	// lineClear clears the current terminal line before each printed line
+	lineClear bool
 }
 
-var (
-	levelColors = map[logLevel]color.RGBColor{
-		logLevelError:   color.RGB(231, 0, 11),  // Red
-		logLevelWarn:    color.RGB(254, 154, 0), // Orange
-		logLevelInfo:    color.RGB(21, 93, 252), // Blue
-		logLevelHTTP:    color.RGB(0, 166, 166), // Teal
-		logLevelVerbose: color.RGB(0, 166, 0),   // Green
-		logLevelDebug:   color.RGB(0, 166, 62),  // Light Green
-		logLevelSilly:   color.RGB(80, 80, 80),  // Gray
-	}
+// lineClearSequence moves the cursor to the start of the line and erases the line.
+const lineClearSequence = "\r\x1b[K"
 
-	timestampColor = color.RGB(150, 150, 150)
-)
+var defaultTimestampColor = color.RGB(150, 150, 150)
+
+// consoleBufferPool holds buffers in which lines are built before they are printed.
+var consoleBufferPool = sync.Pool{
+	New: func() any {
+		return &bytes.Buffer{}
+	},
+}
+
+// maxPooledConsoleBufferSize limits the size of buffers returned to the pool,
+// so a single huge line doesn't keep its memory around.
+const maxPooledConsoleBufferSize = 64 << 10
 
 // newConsoleLogger creates a new ConsoleLogger instance.
-func newConsoleLogger() *consoleLogger {
-	return &consoleLogger{}
+func newConsoleLogger(o *options) *consoleLogger {
+	l := &consoleLogger{
+		out:         os.Stdout,
+		alignLevels: o.alignLevels,
+		shortLevels: o.shortLevels,
+		format:      o.consoleFormat,
+		lineClear:   o.consoleLineClear,
+		levelColors: maps.Clone(o.consoleLevelColors),
+		// the default is copied, so it isn't shared between loggers
+		timestampColor: defaultTimestampColor,
+	}
+	if o.consoleTimestampColor != nil {
+		l.timestampColor = *o.consoleTimestampColor
+	}
+	if o.consoleStdout != nil {
+		l.out = o.consoleStdout
+	}
+	if o.consoleStderr != nil {
+		l.errOut = o.consoleStderr
+		l.errLevel = o.consoleErrorLevel
+	}
+	for level, spec := range registeredLevels.Load().levels {
+		l.levelWidth = max(l.levelWidth, len(l.levelName(level, spec)))
+	}
+	return l
+}
+
+// withOutput returns a copy of the logger printing all logs to the writer.
+func (l *consoleLogger) withOutput(out io.Writer) *consoleLogger {
+	return &consoleLogger{
+		out:            out,
+		alignLevels:    l.alignLevels,
+		shortLevels:    l.shortLevels,
+		levelWidth:     l.levelWidth,
+		format:         l.format,
+		levelColors:    l.levelColors,
+		timestampColor: l.timestampColor,
+		lineClear:      l.lineClear,
+	}
 }
 
 const (
-	// For console output, we use ISO 8601, fractional seconds with trailing zeros, no timezone info
+	// For console output, we use ISO 8601, fractional seconds with trailing zeros, no timezone info.
+	// Unlike timestamps sent to the server, which are always in UTC, console uses local time for readability.
 	timestampFormat = "2006-01-02T15:04:05.0000000"
 )
 
 // syncLog implements the syncLogger interface.
-func (l *consoleLogger) syncLog(timestamp time.Time, level logLevel, message string) {
+//
+// The line is built outside of the lock and printed with a single write,
+// so concurrent logs hold the lock only for the write itself.
+func (l *consoleLogger) syncLog(timestamp time.Time, level Level, message string, data map[string]any) {
+	buf := consoleBufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledConsoleBufferSize {
+			buf.Reset()
+			consoleBufferPool.Put(buf)
+		}
+	}()
+
+	if l.lineClear {
+		buf.WriteString(lineClearSequence)
+	}
+	if l.format == ConsoleFormatLogfmt {
+		buf.WriteString(formatLogfmt(timestamp, level, message, data))
+	} else {
+		l.formatLine(buf, timestamp, level, message, data)
+	}
+	buf.WriteByte('\n')
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	_, _ = l.output(level).Write(buf.Bytes())
+}
 
-	timestampColor.Printf("[%s] ", timestamp.Format(timestampFormat))
-	levelColors[level].Print(strings.ToUpper(string(level)))
-	fmt.Println("", message)
+// formatLine appends the line of the log, colored unless the format is plain, without the trailing newline, to the buffer.
+func (l *consoleLogger) formatLine(buf *bytes.Buffer, timestamp time.Time, level Level, message string, data map[string]any) {
+	if len(data) > 0 {
+		message = joinMessageAndData(message, data)
+	}
+
+	spec := level.spec()
+	name := l.levelName(level, spec)
+
+	levelColor := spec.color
+	if c, ok := l.levelColors[level]; ok {
+		levelColor = c
+	}
+
+	if l.format == ConsoleFormatPlain {
+		buf.WriteString("[" + timestamp.Format(timestampFormat) + "] ")
+		buf.WriteString(name)
+	} else {
+		buf.WriteString(l.timestampColor.Sprint("[" + timestamp.Format(timestampFormat) + "] "))
+		buf.WriteString(levelColor.Sprint(name))
+	}
+	if l.alignLevels {
+		// levels registered after the logger was created may be longer
+		for range l.levelWidth - len(name) {
+			buf.WriteByte(' ')
+		}
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(message)
+}
+
+// output returns where the log of the level is printed.
+func (l *consoleLogger) output(level Level) io.Writer {
+	if l.errOut != nil && level.severity() >= l.errLevel.severity() {
+		return l.errOut
+	}
+	return l.out
+}
+
+// levelName returns the level name printed to the console.
+func (l *consoleLogger) levelName(level Level, spec levelSpec) string {
+	if l.shortLevels {
+		return spec.shortName
+	}
+	return strings.ToUpper(string(level))
+}
+
+// joinMessageAndData appends the data as compact JSON to the message.
+func joinMessageAndData(message string, data map[string]any) string {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		jsonData = []byte(fmt.Sprintf("%+v", data))
+	}
+	if message == "" {
+		return string(jsonData)
+	}
+	return message + " " + string(jsonData)
 }
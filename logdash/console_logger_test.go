@@ -0,0 +1,355 @@
+package logdash
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestConsoleLogger(o *options) (*consoleLogger, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	l := newConsoleLogger(o)
+	l.out = out
+	return l, out
+}
+
+// consoleLines returns printed lines without color codes and timestamps.
+func consoleLines(out *bytes.Buffer) []string {
+	lines := strings.Split(strings.TrimSuffix(color.ClearCode(out.String()), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = line[strings.Index(line, "] ")+2:]
+	}
+	return lines
+}
+
+func TestConsoleLoggerLevelAlignment(t *testing.T) {
+	testCases := []struct {
+		name          string
+		opts          options
+		expectedLines []string
+	}{
+		{
+			name: "should not align levels by default",
+			expectedLines: []string{
+				"WARNING message",
+				"INFO message",
+				"SILLY message",
+			},
+		},
+		{
+			name: "should pad levels to the longest level name",
+			opts: options{alignLevels: true},
+			expectedLines: []string{
+				"WARNING message",
+				"INFO    message",
+				"SILLY   message",
+			},
+		},
+		{
+			name: "should print short levels",
+			opts: options{shortLevels: true, alignLevels: true},
+			expectedLines: []string{
+				"WRN message",
+				"INF message",
+				"SLY message",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			l, out := newTestConsoleLogger(&tc.opts)
+
+			// WHEN
+			l.syncLog(time.Now(), LevelWarn, "message", nil)
+			l.syncLog(time.Now(), LevelInfo, "message", nil)
+			l.syncLog(time.Now(), LevelSilly, "message", nil)
+
+			// THEN
+			assert.Equal(t, tc.expectedLines, consoleLines(out))
+		})
+	}
+}
+
+func TestConsoleLoggerData(t *testing.T) {
+	t.Run("should print data as compact JSON after the message", func(t *testing.T) {
+		// GIVEN
+		l, out := newTestConsoleLogger(&options{})
+
+		// WHEN
+		l.syncLog(time.Now(), LevelInfo, "message", map[string]any{"b": 1, "a": "x"})
+		l.syncLog(time.Now(), LevelInfo, "", map[string]any{"a": true})
+
+		// THEN
+		assert.Equal(t, []string{
+			`INFO message {"a":"x","b":1}`,
+			`INFO {"a":true}`,
+		}, consoleLines(out))
+	})
+}
+
+// parseLogfmt parses the logfmt line into key-value pairs, failing on invalid syntax.
+func parseLogfmt(t *testing.T, line string) map[string]string {
+	t.Helper()
+
+	pairs := map[string]string{}
+	for line != "" {
+		key, rest, ok := strings.Cut(line, "=")
+		if !assert.True(t, ok, "missing = in %q", line) || !assert.NotContains(t, key, " ") {
+			return pairs
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if !assert.NoError(t, err) {
+				return pairs
+			}
+			value, _ = strconv.Unquote(quoted)
+			rest = rest[len(quoted):]
+		} else {
+			value, rest, _ = strings.Cut(rest, " ")
+			rest = " " + rest
+		}
+		pairs[key] = value
+		line = strings.TrimPrefix(rest, " ")
+	}
+	return pairs
+}
+
+func TestConsoleLoggerLogfmt(t *testing.T) {
+	t.Run("should print valid logfmt line with quoted values", func(t *testing.T) {
+		// GIVEN
+		l, out := newTestConsoleLogger(&options{consoleFormat: ConsoleFormatLogfmt})
+		timestamp := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		// WHEN
+		l.syncLog(timestamp, LevelInfo, "user logged in", map[string]any{
+			"user":    "john",
+			"name":    "John Smith",
+			"quote":   `say "hi"`,
+			"empty":   "",
+			"count":   3,
+			"details": map[string]any{"a": 1},
+		})
+
+		// THEN
+		line := strings.TrimSuffix(out.String(), "\n")
+		assert.Equal(t, `time=2025-01-02T03:04:05Z level=info msg="user logged in" count=3 details="{\"a\":1}" `+
+			`empty="" name="John Smith" quote="say \"hi\"" user=john`, line)
+		assert.Equal(t, map[string]string{
+			"time":    "2025-01-02T03:04:05Z",
+			"level":   "info",
+			"msg":     "user logged in",
+			"count":   "3",
+			"details": `{"a":1}`,
+			"empty":   "",
+			"name":    "John Smith",
+			"quote":   `say "hi"`,
+			"user":    "john",
+		}, parseLogfmt(t, line))
+	})
+
+	t.Run("should prefix data keys colliding with other keys", func(t *testing.T) {
+		// GIVEN
+		l, out := newTestConsoleLogger(&options{consoleFormat: ConsoleFormatLogfmt})
+		timestamp := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		// WHEN
+		l.syncLog(timestamp, LevelInfo, "request", map[string]any{
+			"time":  "12ms",
+			"level": 3,
+			"msg":   "original",
+			"a b":   1,
+			"a_b":   2,
+		})
+
+		// THEN
+		line := strings.TrimSuffix(out.String(), "\n")
+		assert.Equal(t, "time=2025-01-02T03:04:05Z level=info msg=request a_b=1 data.a_b=2 "+
+			"data.level=3 data.msg=original data.time=12ms", line)
+	})
+}
+
+func TestConsoleLoggerStreams(t *testing.T) {
+	t.Run("should print severe logs to stderr and others to stdout", func(t *testing.T) {
+		// GIVEN
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		l := newConsoleLogger(&options{
+			consoleStdout:     stdout,
+			consoleStderr:     stderr,
+			consoleErrorLevel: LevelWarn,
+		})
+
+		// WHEN
+		l.syncLog(time.Now(), LevelError, "message", nil)
+		l.syncLog(time.Now(), LevelWarn, "message", nil)
+		l.syncLog(time.Now(), LevelInfo, "message", nil)
+		l.syncLog(time.Now(), LevelDebug, "message", nil)
+
+		// THEN
+		assert.Equal(t, []string{"ERROR message", "WARNING message"}, consoleLines(stderr))
+		assert.Equal(t, []string{"INFO message", "DEBUG message"}, consoleLines(stdout))
+	})
+
+	t.Run("should print all logs to stdout without stderr", func(t *testing.T) {
+		// GIVEN
+		stdout := &bytes.Buffer{}
+		l := newConsoleLogger(&options{consoleStdout: stdout, consoleErrorLevel: LevelWarn})
+
+		// WHEN
+		l.syncLog(time.Now(), LevelError, "message", nil)
+		l.syncLog(time.Now(), LevelInfo, "message", nil)
+
+		// THEN
+		assert.Equal(t, []string{"ERROR message", "INFO message"}, consoleLines(stdout))
+	})
+}
+
+func TestConsoleLoggerColors(t *testing.T) {
+	t.Run("should print level and timestamp with custom colors", func(t *testing.T) {
+		// GIVEN
+		defer color.ForceSetColorLevel(color.ForceOpenColor())
+		infoColor := color.RGB(255, 255, 0)
+		warnColor := color.RGB(0, 255, 255)
+		timestampColor := color.RGB(10, 20, 30)
+		o := &options{}
+		WithConsoleLevelColor(LevelInfo, infoColor)(o)
+		WithColorScheme(map[Level]color.RGBColor{LevelWarn: warnColor})(o)
+		WithTimestampColor(timestampColor)(o)
+		l, out := newTestConsoleLogger(o)
+
+		// WHEN
+		l.syncLog(time.Now(), LevelInfo, "message", nil)
+		l.syncLog(time.Now(), LevelWarn, "message", nil)
+		l.syncLog(time.Now(), LevelError, "message", nil)
+
+		// THEN
+		lines := strings.Split(out.String(), "\n")
+		assert.Contains(t, lines[0], infoColor.Sprint("INFO"))
+		assert.Contains(t, lines[1], warnColor.Sprint("WARNING"))
+		assert.Contains(t, lines[2], registeredLevels.Load().levels[LevelError].color.Sprint("ERROR"))
+		assert.True(t, strings.HasPrefix(lines[0], "\x1b["+timestampColor.String()+"m["))
+	})
+
+	t.Run("should print plain lines without colors", func(t *testing.T) {
+		// GIVEN
+		defer color.ForceSetColorLevel(color.ForceOpenColor())
+		l, out := newTestConsoleLogger(&options{consoleFormat: ConsoleFormatPlain})
+		timestamp := time.Date(2025, 1, 2, 3, 4, 5, 0, time.Local)
+
+		// WHEN
+		l.syncLog(timestamp, LevelInfo, "message", map[string]any{"user": "john"})
+
+		// THEN
+		assert.Equal(t, "[2025-01-02T03:04:05.0000000] INFO message {\"user\":\"john\"}\n", out.String())
+	})
+}
+
+func TestConsoleLoggerLineClear(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format ConsoleFormat
+	}{
+		{name: "should bracket each text line with clear sequence and newline", format: ConsoleFormatText},
+		{name: "should bracket each plain line with clear sequence and newline", format: ConsoleFormatPlain},
+		{name: "should bracket each logfmt line with clear sequence and newline", format: ConsoleFormatLogfmt},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			o := &options{consoleFormat: tc.format}
+			WithConsoleLineClear()(o)
+			l, out := newTestConsoleLogger(o)
+
+			// WHEN
+			l.syncLog(time.Now(), LevelInfo, "first", nil)
+			l.syncLog(time.Now(), LevelError, "second", map[string]any{"user": "john"})
+
+			// THEN
+			lines := strings.SplitAfter(out.String(), "\n")
+			assert.Equal(t, "", lines[len(lines)-1])
+			lines = lines[:len(lines)-1]
+			if assert.Len(t, lines, 2) {
+				for _, line := range lines {
+					assert.True(t, strings.HasPrefix(line, "\r\x1b[K"), "line %q", line)
+					assert.True(t, strings.HasSuffix(line, "\n"), "line %q", line)
+					assert.Equal(t, 1, strings.Count(line, "\r"), "line %q", line)
+				}
+				assert.Contains(t, lines[0], "first")
+				assert.Contains(t, lines[1], "second")
+			}
+		})
+	}
+
+	t.Run("should not clear lines by default", func(t *testing.T) {
+		// GIVEN
+		l, out := newTestConsoleLogger(&options{consoleFormat: ConsoleFormatPlain})
+
+		// WHEN
+		l.syncLog(time.Now(), LevelInfo, "message", nil)
+
+		// THEN
+		assert.NotContains(t, out.String(), "\r")
+	})
+}
+
+// countingWriter counts writes, e.g. to check lines are printed with a single write.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestConsoleLoggerSingleWrite(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format ConsoleFormat
+	}{
+		{name: "should print each text line with a single write", format: ConsoleFormatText},
+		{name: "should print each logfmt line with a single write", format: ConsoleFormatLogfmt},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			out := &countingWriter{}
+			l := newConsoleLogger(&options{consoleFormat: tc.format, alignLevels: true})
+			l.out = out
+
+			// WHEN
+			l.syncLog(time.Now(), LevelInfo, "first", nil)
+			l.syncLog(time.Now(), LevelError, "second", map[string]any{"key": "value"})
+
+			// THEN
+			assert.Equal(t, 2, out.writes)
+			assert.Equal(t, 2, strings.Count(out.String(), "\n"))
+		})
+	}
+}
+
+func BenchmarkConsoleLoggerParallel(b *testing.B) {
+	l := newConsoleLogger(&options{})
+	l.out = io.Discard
+	timestamp := time.Now()
+	data := map[string]any{"userId": 42}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.syncLog(timestamp, LevelInfo, "Processing request", data)
+		}
+	})
+}
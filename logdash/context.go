@@ -0,0 +1,51 @@
+package logdash
+
+import (
+	"context"
+	"maps"
+)
+
+// loggerContextKey is the key of the logger stored in a context by [NewContext].
+type loggerContextKey struct{}
+
+// baggageContextKey is the key of the baggage stored in a context by [ContextWithBaggage].
+type baggageContextKey struct{}
+
+// discardLogger is returned by [FromContext] when there is no logger in the context.
+var discardLogger = newLogger(newNoopLogger())
+
+// NewContext returns a copy of the context carrying the logger, e.g. a request-scoped logger created by [Logger.WithFields].
+//
+// The logger can be retrieved deeper in the call stack by [FromContext].
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// FromContext returns the logger stored in the context by [NewContext].
+//
+// When there is no logger in the context, it returns a logger discarding all logs, so it's never nil.
+func FromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*Logger); ok && logger != nil {
+		return logger
+	}
+	return discardLogger
+}
+
+// ContextWithBaggage returns a copy of the context carrying the values as baggage, merged with the baggage
+// of the context, e.g. the tenant of the handled request.
+//
+// Values of keys selected by [WithBaggageFields] are attached to logs with the context.
+func ContextWithBaggage(ctx context.Context, values map[string]string) context.Context {
+	baggage := maps.Clone(baggageFromContext(ctx))
+	if baggage == nil {
+		baggage = make(map[string]string, len(values))
+	}
+	maps.Copy(baggage, values)
+	return context.WithValue(ctx, baggageContextKey{}, baggage)
+}
+
+// baggageFromContext returns the baggage stored in the context by ContextWithBaggage, nil if there is none.
+func baggageFromContext(ctx context.Context) map[string]string {
+	baggage, _ := ctx.Value(baggageContextKey{}).(map[string]string)
+	return baggage
+}
@@ -0,0 +1,66 @@
+package logdash_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerContext(t *testing.T) {
+	t.Run("should retrieve logger stored in context", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(logdash.WithSink(sink))
+		defer ld.Close()
+		requestLogger := ld.Logger.WithFields(map[string]any{"requestId": "r-1"})
+
+		// WHEN
+		ctx := logdash.NewContext(context.Background(), requestLogger)
+		logdash.FromContext(ctx).Info("handled")
+
+		// THEN
+		assert.Same(t, requestLogger, logdash.FromContext(ctx))
+		entries := sink.Entries()
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "handled", entries[0].Message)
+		assert.Equal(t, map[string]any{"requestId": "r-1"}, entries[0].Fields)
+	})
+
+	t.Run("should return logger discarding logs when there is no logger in context", func(t *testing.T) {
+		// GIVEN
+		ctx := context.Background()
+
+		// WHEN
+		logger := logdash.FromContext(ctx)
+
+		// THEN
+		assert.NotNil(t, logger)
+		output := captureStdout(t, func() {
+			logger.Info("discarded")
+			logger.WithFields(map[string]any{"key": "value"}).ErrorF("%s", "discarded")
+		})
+		assert.Empty(t, output)
+	})
+
+	t.Run("should keep fields of parent and child loggers", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithFields(map[string]any{"service": "api", "requestId": "none"}),
+		)
+		defer ld.Close()
+
+		// WHEN
+		ld.Logger.WithFields(map[string]any{"requestId": "r-1"}).Info("child")
+		ld.Logger.Info("parent")
+
+		// THEN
+		entries := sink.Entries()
+		assert.Len(t, entries, 2)
+		assert.Equal(t, map[string]any{"service": "api", "requestId": "r-1"}, entries[0].Fields)
+		assert.Equal(t, map[string]any{"service": "api", "requestId": "none"}, entries[1].Fields)
+	})
+}
@@ -0,0 +1,188 @@
+package logdash
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// deferredLogger implements syncLogger interface for a logger which is not available yet.
+	//
+	// It keeps the most recent logs in a bounded ring buffer until the logger is activated.
+	// Then it replays buffered logs and forwards all following logs to the activated logger.
+	deferredLogger struct {
+		target atomic.Pointer[syncLogger]
+
+		// mu guards the buffer and activation
+		mu sync.Mutex
+		// size is the maximum number of buffered logs, the buffer grows up to it as logs are kept
+		size   int
+		buffer []bufferedLog
+		// next is the index of the next buffered log in the ring buffer
+		next int
+		// full is true when the ring buffer wrapped around
+		full bool
+		// closed is true when the logger is shut down or closed before it was activated
+		closed bool
+	}
+
+	// bufferedLog is a single log kept by deferredLogger.
+	bufferedLog struct {
+		timestamp time.Time
+		level     Level
+		message   string
+		data      map[string]any
+	}
+
+	// deferredMetrics implements metricsBackend interface for metrics which are not available yet.
+	//
+	// Metrics are discarded until activated.
+	deferredMetrics struct {
+		target atomic.Pointer[metricsBackend]
+	}
+)
+
+// newDeferredLogger creates a new deferredLogger instance buffering up to bufferSize logs.
+//
+// The buffer is allocated as logs are kept, so it takes no memory until the first log.
+func newDeferredLogger(bufferSize int) *deferredLogger {
+	return &deferredLogger{
+		size: max(bufferSize, 0),
+	}
+}
+
+// syncLog implements the syncLogger interface.
+func (l *deferredLogger) syncLog(timestamp time.Time, level Level, message string, data map[string]any) {
+	if target := l.target.Load(); target != nil {
+		(*target).syncLog(timestamp, level, message, data)
+		return
+	}
+
+	l.mu.Lock()
+	// the logger may be activated while waiting for the lock
+	if target := l.target.Load(); target != nil {
+		l.mu.Unlock()
+		(*target).syncLog(timestamp, level, message, data)
+		return
+	}
+	defer l.mu.Unlock()
+
+	if l.size == 0 || l.closed {
+		return
+	}
+	entry := bufferedLog{timestamp: timestamp, level: level, message: message, data: data}
+	if len(l.buffer) < l.size {
+		l.buffer = append(l.buffer, entry)
+	} else {
+		l.buffer[l.next] = entry
+		l.full = true
+	}
+	l.next = (l.next + 1) % l.size
+}
+
+// trySyncLog implements the trySyncLogger interface, buffered logs are never reported as dropped.
+//
+// Returns ErrAlreadyClosed when the logger is shut down or closed before it was activated.
+func (l *deferredLogger) trySyncLog(timestamp time.Time, level Level, message string, data map[string]any) error {
+	if target := l.target.Load(); target != nil {
+		return trySyncLog(*target, timestamp, level, message, data)
+	}
+	l.mu.Lock()
+	closed := l.closed
+	l.mu.Unlock()
+	if closed {
+		return ErrAlreadyClosed
+	}
+	l.syncLog(timestamp, level, message, data)
+	return nil
+}
+
+// activate replays buffered logs to the target and forwards all following logs to it.
+func (l *deferredLogger) activate(target syncLogger) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	start := 0
+	if l.full {
+		start = l.next
+	}
+	for i := range l.buffer {
+		entry := l.buffer[(start+i)%len(l.buffer)]
+		target.syncLog(entry.timestamp, entry.level, entry.message, entry.data)
+	}
+	l.buffer = nil
+
+	l.target.Store(&target)
+}
+
+// flush flushes the activated logger, if any.
+func (l *deferredLogger) flush(ctx context.Context) error {
+	if target := l.target.Load(); target != nil {
+		if f, ok := (*target).(flusher); ok {
+			return f.flush(ctx)
+		}
+	}
+	return nil
+}
+
+// Shutdown shuts down the activated logger, if any, otherwise it discards buffered logs and following logs.
+func (l *deferredLogger) Shutdown(ctx context.Context) error {
+	if target := l.target.Load(); target != nil {
+		return (*target).Shutdown(ctx)
+	}
+	l.discard()
+	return nil
+}
+
+// Close closes the activated logger, if any, otherwise it discards buffered logs and following logs.
+func (l *deferredLogger) Close() error {
+	if target := l.target.Load(); target != nil {
+		return (*target).Close()
+	}
+	l.discard()
+	return nil
+}
+
+// discard drops buffered logs and makes the logger discard following logs, as it won't be activated anymore.
+func (l *deferredLogger) discard() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	l.buffer = nil
+}
+
+// newDeferredMetrics creates a new deferredMetrics instance.
+func newDeferredMetrics() *deferredMetrics {
+	m := &deferredMetrics{}
+	var noop metricsBackend = noopMetrics{}
+	m.target.Store(&noop)
+	return m
+}
+
+// activate forwards all following metrics to the target.
+func (m *deferredMetrics) activate(target metricsBackend) {
+	m.target.Store(&target)
+}
+
+// Set sets a metric to an absolute value.
+func (m *deferredMetrics) Set(name string, value float64) {
+	(*m.target.Load()).Set(name, value)
+}
+
+// Mutate changes a metric by a relative value.
+func (m *deferredMetrics) Mutate(name string, value float64) {
+	(*m.target.Load()).Mutate(name, value)
+}
+
+// Shutdown shuts down the activated metrics.
+func (m *deferredMetrics) Shutdown(ctx context.Context) error {
+	return (*m.target.Load()).Shutdown(ctx)
+}
+
+// Close closes the activated metrics.
+func (m *deferredMetrics) Close() error {
+	return (*m.target.Load()).Close()
+}
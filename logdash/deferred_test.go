@@ -0,0 +1,158 @@
+package logdash_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashSetAPIKey(t *testing.T) {
+	t.Run("should send logs emitted before and after the API key is set", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+		)
+
+		// WHEN
+		beforeLogSent := time.Now()
+		ld.Logger.Info("before")
+		ld.Metrics.Set("before-metric", 1)
+		err := ld.SetAPIKey("test-api-key")
+		assert.NoError(t, err)
+		ld.Logger.Info("after")
+		ld.Metrics.Set("after-metric", 1)
+		err = ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 3)
+		var logs, metrics []requestAndBody
+		for _, r := range requestsCollector.requests {
+			if r.request.URL.Path == "/logs" {
+				logs = append(logs, r)
+			} else {
+				metrics = append(metrics, r)
+			}
+		}
+		assert.Len(t, logs, 2)
+		assertRequestAndBody(t, logs[0], http.MethodPost, "/logs", "test-api-key", map[string]any{
+			"message":   "before",
+			"createdAt": nil,
+		}, beforeLogSent)
+		assertRequestAndBody(t, logs[1], http.MethodPost, "/logs", "test-api-key", map[string]any{
+			"message": "after",
+		}, beforeLogSent)
+		assert.Len(t, metrics, 1)
+		assertRequestAndBody(t, metrics[0], http.MethodPut, "/metrics", "test-api-key", map[string]any{
+			"name": "after-metric",
+		}, beforeLogSent)
+	})
+
+	t.Run("should replay only the most recent logs", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithLocalBuffer(2),
+		)
+
+		// WHEN
+		ld.Logger.Info("first")
+		ld.Logger.Info("second")
+		ld.Logger.Info("third")
+		err := ld.SetAPIKey("test-api-key")
+		assert.NoError(t, err)
+		err = ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		var messages []string
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			messages = append(messages, body["message"].(string))
+		}
+		assert.Equal(t, []string{"second", "third"}, messages)
+	})
+
+	t.Run("should not replay logs when local buffer is disabled", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithLocalBuffer(0),
+		)
+
+		// WHEN
+		ld.Logger.Info("before")
+		err := ld.SetAPIKey("test-api-key")
+		assert.NoError(t, err)
+		err = ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Empty(t, requestsCollector.requests)
+	})
+
+	t.Run("should return error when API key is already set", func(t *testing.T) {
+		ld := logdash.New(
+			logdash.WithHost("http://localhost:8080"),
+			logdash.WithAPIKey("test-api-key"),
+		)
+		defer ld.Close()
+
+		assert.ErrorIs(t, ld.SetAPIKey("another-api-key"), logdash.ErrAPIKeyAlreadySet)
+	})
+
+	t.Run("should return error when API key is empty", func(t *testing.T) {
+		ld := logdash.New(logdash.WithHost("http://localhost:8080"))
+		defer ld.Close()
+
+		assert.ErrorIs(t, ld.SetAPIKey(""), logdash.ErrEmptyAPIKey)
+	})
+
+	t.Run("should return error when instance is already shut down", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New(logdash.WithHost("http://localhost:8080"))
+		ld.Logger.Info("before")
+		assert.NoError(t, ld.Shutdown(context.Background()))
+
+		// WHEN
+		err := ld.SetAPIKey("test-api-key")
+
+		// THEN
+		assert.ErrorIs(t, err, logdash.ErrAlreadyClosed)
+		assert.ErrorIs(t, ld.Logger.TryInfo("after"), logdash.ErrAlreadyClosed)
+	})
+}
@@ -2,18 +2,49 @@ package logdash
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
 )
 
+// wireTimestampFormat is the format of timestamps sent to the server, always in UTC.
+const wireTimestampFormat = time.RFC3339Nano
+
+// formatWireTimestamp formats the timestamp to be sent to the server.
+func formatWireTimestamp(t time.Time) string {
+	return t.UTC().Format(wireTimestampFormat)
+}
+
 // httpClient is a common HTTP client for sending data to the server.
 type httpClient struct {
-	client    *retryablehttp.Client
+	// client is shared with clients of other projects, it's replaced when retries change (see: [httpClient.setRetryConfig])
+	client    *atomic.Pointer[retryablehttp.Client]
 	serverURL string
 	apiKey    string
+	health    *remoteHealth
+	// sendDeadline bounds the total time of sending data, including retries, 0 means no limit
+	sendDeadline time.Duration
+	// maxRequestBytes limits the size of sent data, larger data is not sent, 0 means no limit
+	maxRequestBytes int
+	// onSend is invoked after data is successfully sent, nil means no callback
+	onSend func(endpoint string, payload []byte, status int)
+	// clockSync measures the skew from the server clock, nil means it's not measured
+	clockSync *clockSync
+	// keepAlive exercises the connection in the background, nil when disabled or shared with another client
+	keepAlive *keepAlive
+	// stats of requests by endpoint
+	stats          map[string]*requestStats
+	internalLogger *Logger
 }
 
 type retryLogger struct {
@@ -26,40 +57,271 @@ func (l *retryLogger) Printf(format string, v ...interface{}) {
 
 // newHTTPClient creates a new HTTP client instance.
 func newHTTPClient(o *options, internalLogger *Logger) *httpClient {
+	// default client of the retry client has a pooled transport
+	baseClient := retryablehttp.NewClient().HTTPClient
+	if o.httpClient != nil {
+		// copy the provided client to not modify it
+		client := *o.httpClient
+		baseClient = &client
+		if o.httpTimeout > 0 {
+			baseClient.Timeout = o.httpTimeout
+		}
+	} else {
+		baseClient.Timeout = o.httpTimeout
+	}
+	if o.tlsConfig != nil {
+		applyTLSConfig(baseClient, o.tlsConfig, internalLogger)
+	}
+	if o.dialTimeout > 0 || o.responseHeaderTimeout > 0 {
+		applyTransportTimeouts(baseClient, o.dialTimeout, o.responseHeaderTimeout, internalLogger)
+	}
+	if o.unixSocket != "" {
+		applyUnixSocket(baseClient, o.unixSocket, o.dialTimeout, internalLogger)
+	}
+
+	client := &atomic.Pointer[retryablehttp.Client]{}
+	client.Store(newRetryClient(baseClient, o, internalLogger))
+	c := newProjectHTTPClient(client, o, internalLogger)
+	if o.connectionKeepAlive > 0 {
+		c.keepAlive = startKeepAlive(baseClient, o.host+"/logs", o.apiKey, o.connectionKeepAlive, internalLogger)
+	}
+	return c
+}
+
+// newRetryClient creates a client retrying requests sent with the given HTTP client.
+func newRetryClient(httpClient *http.Client, o *options, internalLogger *Logger) *retryablehttp.Client {
 	retryhttpClient := retryablehttp.NewClient()
+	retryhttpClient.HTTPClient = httpClient
 	retryhttpClient.Logger = &retryLogger{
 		internalLogger: internalLogger,
 	}
 	retryhttpClient.RetryMax = o.httpRetries
 	retryhttpClient.RetryWaitMin = o.httpRetryMin
 	retryhttpClient.RetryWaitMax = o.httpRetryMax
-	retryhttpClient.HTTPClient.Timeout = o.httpTimeout
+	if o.httpRetryJitter > 0 {
+		retryhttpClient.Backoff = jitteredBackoff(o.httpRetryJitter)
+	}
+	if o.responseValidator != nil {
+		retryhttpClient.CheckRetry = validatingRetryPolicy(o.responseValidator)
+	}
+	retryhttpClient.RequestLogHook = traceRequestHook
+	retryhttpClient.ResponseLogHook = traceResponseHook
+	return retryhttpClient
+}
 
+// newProjectHTTPClient creates a new HTTP client for the project of the API key, using the given retry client.
+func newProjectHTTPClient(client *atomic.Pointer[retryablehttp.Client], o *options, internalLogger *Logger) *httpClient {
 	return &httpClient{
-		client:    retryhttpClient,
-		serverURL: o.host,
-		apiKey:    o.apiKey,
+		client:          client,
+		serverURL:       o.host,
+		apiKey:          o.apiKey,
+		health:          newRemoteHealth(o.remoteFailureThreshold, o.remoteRecoveryThreshold, o.onRemoteHealthy, o.onRemoteUnhealthy),
+		sendDeadline:    o.sendDeadline,
+		maxRequestBytes: o.maxRequestBytes,
+		onSend:          o.onSend,
+		clockSync:       o.clockSync,
+		stats: map[string]*requestStats{
+			"/logs":    {},
+			"/metrics": {},
+		},
+		internalLogger: internalLogger,
+	}
+}
+
+// stop stops background work of the client.
+func (c *httpClient) stop() {
+	c.keepAlive.stop()
+}
+
+// setRetryConfig replaces the retry client with one using retries of the options, for all projects.
+//
+// Fields of the retry client are read without synchronization during requests, so the client is
+// replaced rather than modified: requests in flight complete with the previous retries.
+func (c *httpClient) setRetryConfig(o *options) {
+	c.client.Store(newRetryClient(c.client.Load().HTTPClient, o, c.internalLogger))
+}
+
+// forProject returns a client for the project of the API key, sharing the connection pool and retries.
+func (c *httpClient) forProject(o *options) *httpClient {
+	return newProjectHTTPClient(c.client, o, c.internalLogger)
+}
+
+// applyTLSConfig sets the TLS configuration on the transport of the client.
+//
+// The transport is cloned, so transports shared with other clients are not modified.
+// Transports other than [*http.Transport] are left untouched.
+func applyTLSConfig(client *http.Client, tlsConfig *tls.Config, internalLogger *Logger) {
+	httpTransport, err := cloneTransport(client)
+	if err != nil {
+		internalLogger.ErrorF("TLS config ignored: %v", err)
+		return
+	}
+	httpTransport.TLSClientConfig = tlsConfig.Clone()
+	client.Transport = httpTransport
+}
+
+// applyTransportTimeouts sets the timeouts of connecting and waiting for response headers
+// on a copy of the client transport, zero timeouts are not changed.
+func applyTransportTimeouts(client *http.Client, dialTimeout, responseHeaderTimeout time.Duration, internalLogger *Logger) {
+	httpTransport, err := cloneTransport(client)
+	if err != nil {
+		internalLogger.ErrorF("Transport timeouts ignored: %v", err)
+		return
+	}
+	if dialTimeout > 0 {
+		httpTransport.DialContext = (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	if responseHeaderTimeout > 0 {
+		httpTransport.ResponseHeaderTimeout = responseHeaderTimeout
+	}
+	client.Transport = httpTransport
+}
+
+// applyUnixSocket makes a copy of the client transport dial the Unix domain socket at the path,
+// whatever the host of requests is.
+func applyUnixSocket(client *http.Client, path string, dialTimeout time.Duration, internalLogger *Logger) {
+	httpTransport, err := cloneTransport(client)
+	if err != nil {
+		internalLogger.ErrorF("Unix socket ignored: %v", err)
+		return
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	httpTransport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	}
+	client.Transport = httpTransport
+}
+
+// cloneTransport returns a copy of the client transport, so the provided transport is not modified.
+func cloneTransport(client *http.Client) (*http.Transport, error) {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	httpTransport, ok := transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unsupported HTTP transport %T", transport)
+	}
+	return httpTransport.Clone(), nil
+}
+
+// validatingRetryPolicy returns a retry policy which retries successful responses rejected by the validator,
+// like responses with error status. Other responses are handled by the default policy.
+//
+// When retries are exhausted, the error of the validator is returned.
+func validatingRetryPolicy(validator func(status int, body []byte) error) retryablehttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if ctx.Err() != nil || err != nil || resp == nil || resp.StatusCode >= 400 {
+			return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+		}
+
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		// keep the body readable for the caller
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if readErr != nil {
+			return true, fmt.Errorf("failed to read response: %w", readErr)
+		}
+		if validationErr := validator(resp.StatusCode, body); validationErr != nil {
+			return true, fmt.Errorf("invalid response: %w", validationErr)
+		}
+		return false, nil
+	}
+}
+
+// jitteredBackoff returns a backoff which randomizes the default backoff by ±fraction,
+// so retries of many clients are not synchronized.
+// The result is clamped to the [min, max] range.
+func jitteredBackoff(fraction float64) retryablehttp.Backoff {
+	fraction = min(fraction, 1)
+	return func(minWait, maxWait time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		wait := retryablehttp.DefaultBackoff(minWait, maxWait, attemptNum, resp)
+		wait = time.Duration(float64(wait) * (1 + fraction*(2*rand.Float64()-1)))
+		return min(max(wait, minWait), maxWait)
+	}
+}
+
+// sendData sends data to the endpoint, giving up all retries when the context is done
+// or the send deadline passes (see: [WithSendDeadline]).
+//
+// The result is recorded to track the health of the remote.
+func (c *httpClient) sendData(ctx context.Context, endpoint string, method string, data any) error {
+	if c.sendDeadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.sendDeadline)
+		defer cancel()
 	}
+	return c.sendDataNoDeadline(ctx, endpoint, method, data)
 }
 
-// sendData sends data to the server at the specified endpoint.
-func (c *httpClient) sendData(endpoint string, method string, data any) error {
+// sendDataNoDeadline sends data like sendData, but regardless of the send deadline,
+// giving up all retries only when the context is done.
+func (c *httpClient) sendDataNoDeadline(ctx context.Context, endpoint string, method string, data any) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal: %w", err)
 	}
+	// checked locally, so neither the health nor statistics of requests are affected
+	if c.maxRequestBytes > 0 && len(jsonData) > c.maxRequestBytes {
+		return fmt.Errorf("%w: %d bytes exceed the limit of %d bytes", ErrPayloadTooLarge, len(jsonData), c.maxRequestBytes)
+	}
+
+	_, status, err := c.requestJSON(ctx, endpoint, method, jsonData)
+	if status == http.StatusRequestEntityTooLarge {
+		// the server is reachable, only this payload is rejected
+		c.health.record(nil)
+		return fmt.Errorf("%w: %d bytes: %w", ErrPayloadTooLarge, len(jsonData), err)
+	}
+	c.health.record(err)
+	if err == nil && c.onSend != nil {
+		c.onSend(endpoint, bytes.Clone(jsonData), status)
+	}
+	return err
+}
+
+// request sends data to the endpoint and returns the response body.
+//
+// Responses with error status are returned as [*statusError].
+func (c *httpClient) request(ctx context.Context, endpoint string, method string, data any) ([]byte, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	respBody, _, err := c.requestJSON(ctx, endpoint, method, jsonData)
+	return respBody, err
+}
 
-	req, err := retryablehttp.NewRequest(method, c.serverURL+endpoint, bytes.NewBuffer(jsonData))
+// requestJSON sends JSON data to the endpoint and returns the response body and status.
+func (c *httpClient) requestJSON(ctx context.Context, endpoint string, method string, jsonData []byte) ([]byte, int, error) {
+	req, err := retryablehttp.NewRequestWithContext(ctx, method, c.serverURL+endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("project-api-key", c.apiKey)
 
-	resp, err := c.client.Do(req)
+	trace := &requestTrace{stats: c.stats[endpoint]}
+	if trace.stats != nil {
+		req = req.WithContext(withRequestTrace(req.Context(), trace))
+	}
+
+	start := time.Now()
+	resp, err := c.client.Load().Do(req)
+	latency := time.Since(start)
+	if resp != nil {
+		trace.lastStatus = resp.StatusCode
+	}
+	c.internalLogger.VerboseF("Request %s %s completed with status %d in %s", method, endpoint, trace.lastStatus, latency)
+	if trace.stats != nil {
+		trace.stats.record(trace.lastStatus, latency)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to send: %w", err)
+		return nil, trace.lastStatus, fmt.Errorf("failed to send: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -67,8 +329,91 @@ func (c *httpClient) sendData(endpoint string, method string, data any) error {
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("server returned error status: %d, body: %s", resp.StatusCode, string(respBody))
+		return nil, resp.StatusCode, &statusError{status: resp.StatusCode, body: string(respBody)}
 	}
+	c.clockSync.observe(resp.Header.Get("Date"), latency)
+
+	return respBody, resp.StatusCode, nil
+}
 
-	return nil
+// authenticate validates the API key and returns the ID of its project.
+//
+// It returns [ErrUnauthorized] when the server rejects the API key.
+func (c *httpClient) authenticate(ctx context.Context) (string, error) {
+	respBody, err := c.request(ctx, "/auth/api-key", http.MethodPost, map[string]string{"apiKey": c.apiKey})
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && (statusErr.status == http.StatusUnauthorized || statusErr.status == http.StatusForbidden) {
+		return "", fmt.Errorf("%w: %w", ErrUnauthorized, err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var auth struct {
+		ProjectID string `json:"projectId"`
+	}
+	if err := json.Unmarshal(respBody, &auth); err != nil {
+		return "", fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return auth.ProjectID, nil
+}
+
+// statusError is returned when the server responds with error status.
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("server returned error status: %d, body: %s", e.status, e.body)
+}
+
+// isTransientSendError reports whether sending may succeed when retried, i.e. it failed because of the network,
+// a server error or the rate limit, rather than because the payload can't be marshaled or is rejected.
+func isTransientSendError(err error) bool {
+	if errors.Is(err, ErrPayloadTooLarge) {
+		return false
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status >= http.StatusInternalServerError || statusErr.status == http.StatusTooManyRequests
+	}
+	var unsupportedValueErr *json.UnsupportedValueError
+	var unsupportedTypeErr *json.UnsupportedTypeError
+	var marshalerErr *json.MarshalerError
+	return !errors.As(err, &unsupportedValueErr) && !errors.As(err, &unsupportedTypeErr) && !errors.As(err, &marshalerErr)
+}
+
+// isValidHTTPMethod reports whether the method is a standard HTTP method.
+func isValidHTTPMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordDrop registers an item for the endpoint dropped before sending.
+func (c *httpClient) recordDrop(endpoint string) {
+	if s := c.stats[endpoint]; s != nil {
+		s.recordDrop()
+	}
+}
+
+// requestRates returns current rates of the endpoint.
+func (c *httpClient) requestRates(endpoint string) RequestRates {
+	if s := c.stats[endpoint]; s != nil {
+		return s.rates()
+	}
+	return RequestRates{}
+}
+
+// requestStats returns statistics of requests sent to the endpoint.
+func (c *httpClient) requestStats(endpoint string) RequestStats {
+	if s := c.stats[endpoint]; s != nil {
+		return s.snapshot()
+	}
+	return RequestStats{}
 }
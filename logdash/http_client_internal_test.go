@@ -0,0 +1,48 @@
+package logdash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitteredBackoff(t *testing.T) {
+	t.Run("should randomize backoff within range", func(t *testing.T) {
+		// GIVEN
+		const (
+			minWait = 100 * time.Millisecond
+			maxWait = 10 * time.Second
+		)
+		backoff := jitteredBackoff(0.5)
+
+		// WHEN
+		waits := make(map[time.Duration]struct{})
+		for range 100 {
+			wait := backoff(minWait, maxWait, 2, nil)
+			// default backoff for the third attempt is 400ms
+			assert.GreaterOrEqual(t, wait, 200*time.Millisecond)
+			assert.LessOrEqual(t, wait, 600*time.Millisecond)
+			waits[wait] = struct{}{}
+		}
+
+		// THEN
+		assert.Greater(t, len(waits), 50)
+	})
+
+	t.Run("should clamp backoff to min and max", func(t *testing.T) {
+		// GIVEN
+		const (
+			minWait = 100 * time.Millisecond
+			maxWait = 200 * time.Millisecond
+		)
+		backoff := jitteredBackoff(1)
+
+		// WHEN & THEN
+		for attempt := range 10 {
+			wait := backoff(minWait, maxWait, attempt, nil)
+			assert.GreaterOrEqual(t, wait, minWait)
+			assert.LessOrEqual(t, wait, maxWait)
+		}
+	})
+}
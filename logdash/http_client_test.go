@@ -0,0 +1,619 @@
+package logdash_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashHTTPMethods(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		opts                  []logdash.Option
+		expectedLogsMethod    string
+		expectedMetricsMethod string
+	}{
+		{
+			name:                  "should use default methods",
+			expectedLogsMethod:    http.MethodPost,
+			expectedMetricsMethod: http.MethodPut,
+		},
+		{
+			name: "should use configured methods",
+			opts: []logdash.Option{
+				logdash.WithLogsMethod(http.MethodPut),
+				logdash.WithMetricsMethod(http.MethodPost),
+			},
+			expectedLogsMethod:    http.MethodPut,
+			expectedMetricsMethod: http.MethodPost,
+		},
+		{
+			name: "should fallback to default methods when configured methods are invalid",
+			opts: []logdash.Option{
+				logdash.WithLogsMethod("SEND"),
+				logdash.WithMetricsMethod(""),
+			},
+			expectedLogsMethod:    http.MethodPost,
+			expectedMetricsMethod: http.MethodPut,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			// WHEN
+			ld := logdash.New(append([]logdash.Option{
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+			}, tc.opts...)...)
+
+			ld.Logger.Info("Hello, World!")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+
+			ld = logdash.New(append([]logdash.Option{
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+			}, tc.opts...)...)
+
+			ld.Metrics.Set("test-metric", 1)
+			err = ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+
+			// THEN
+			assert.Len(t, requestsCollector.requests, 2)
+			assert.Equal(t, "/logs", requestsCollector.requests[0].request.URL.Path)
+			assert.Equal(t, tc.expectedLogsMethod, requestsCollector.requests[0].request.Method)
+			assert.Equal(t, "/metrics", requestsCollector.requests[1].request.URL.Path)
+			assert.Equal(t, tc.expectedMetricsMethod, requestsCollector.requests[1].request.Method)
+		})
+	}
+}
+
+func TestLogdashSendDeadline(t *testing.T) {
+	t.Run("should abandon entry within the deadline instead of exhausting retries", func(t *testing.T) {
+		// GIVEN
+		var requests atomic.Int64
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			requests.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer httpServer.Close()
+
+		// all retries would take at least 10 * 100ms
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetries(10),
+			logdash.WithHTTPRetryMin(100*time.Millisecond),
+			logdash.WithHTTPRetryMax(100*time.Millisecond),
+			logdash.WithSendDeadline(150*time.Millisecond),
+		)
+
+		// WHEN
+		start := time.Now()
+		ld.Logger.Info("Hello, deadline!")
+		err := ld.Shutdown(context.Background())
+		elapsed := time.Since(start)
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Less(t, elapsed, 500*time.Millisecond)
+		assert.Less(t, requests.Load(), int64(11))
+		stats := ld.Stats()
+		assert.Equal(t, int64(1), stats.Logs.Requests)
+		assert.Equal(t, int64(0), stats.Logs.Status2xx)
+	})
+}
+
+func TestLogdashResponseHeaderTimeout(t *testing.T) {
+	t.Run("should give up on server delaying response headers beyond the limit", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			select {
+			case <-time.After(300 * time.Millisecond):
+			case <-r.Context().Done():
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPTimeout(5*time.Second),
+			logdash.WithDialTimeout(time.Second),
+			logdash.WithResponseHeaderTimeout(50*time.Millisecond),
+		)
+
+		// WHEN
+		start := time.Now()
+		ld.Logger.Info("Hello, slow server!")
+		err := ld.Shutdown(context.Background())
+		elapsed := time.Since(start)
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Less(t, elapsed, 250*time.Millisecond)
+		stats := ld.Stats()
+		assert.Equal(t, int64(1), stats.Logs.Requests)
+		assert.Equal(t, int64(1), stats.Logs.Failures)
+	})
+}
+
+func TestLogdashSetHTTPRetryConfig(t *testing.T) {
+	t.Run("should use changed retries for subsequent logs and metrics", func(t *testing.T) {
+		// GIVEN
+		var logRequests, metricRequests atomic.Int64
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			if r.URL.Path == "/logs" {
+				logRequests.Add(1)
+			} else {
+				metricRequests.Add(1)
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetries(0),
+		)
+		ld.Logger.Info("Hello, no retries!")
+		assert.Eventually(t, func() bool {
+			return logRequests.Load() == 1
+		}, time.Second, 10*time.Millisecond)
+
+		// WHEN
+		ld.SetHTTPRetryConfig(2, time.Millisecond, time.Millisecond)
+		ld.Logger.Info("Hello, retries!")
+		ld.Metrics.Set("users", 1)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), logRequests.Load())
+		assert.Equal(t, int64(3), metricRequests.Load())
+	})
+}
+
+func TestLogdashOnSend(t *testing.T) {
+	t.Run("should invoke callback after log and metric are sent", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		type sent struct {
+			endpoint string
+			payload  map[string]any
+			status   int
+		}
+		var (
+			mu    sync.Mutex
+			sends []sent
+		)
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithOnSend(func(endpoint string, payload []byte, status int) {
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(payload, &body))
+				mu.Lock()
+				defer mu.Unlock()
+				sends = append(sends, sent{endpoint: endpoint, payload: body, status: status})
+			}),
+		)
+
+		// WHEN
+		ld.Logger.Info("Hello, World!")
+		ld.Metrics.Set("test-metric", 1)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Len(t, sends, 2)
+		for _, s := range sends {
+			assert.Equal(t, http.StatusOK, s.status)
+			switch s.endpoint {
+			case "/logs":
+				assert.Equal(t, "Hello, World!", s.payload["message"])
+			case "/metrics":
+				assert.Equal(t, "test-metric", s.payload["name"])
+			default:
+				t.Errorf("unexpected endpoint %s", s.endpoint)
+			}
+		}
+	})
+
+	t.Run("should not invoke callback when sending fails", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer httpServer.Close()
+
+		var calls atomic.Int32
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithOnSend(func(string, []byte, int) {
+				calls.Add(1)
+			}),
+		)
+
+		// WHEN
+		ld.Logger.Info("Hello, World!")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Zero(t, calls.Load())
+	})
+}
+
+func TestLogdashPayloadTooLarge(t *testing.T) {
+	t.Run("should drop too large log without retrying and send following logs", func(t *testing.T) {
+		// GIVEN
+		var largeAttempts atomic.Int32
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			if r.ContentLength > 1024 {
+				largeAttempts.Add(1)
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		var droppedMu sync.Mutex
+		var dropped []string
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetryMin(time.Millisecond),
+			logdash.WithHTTPRetryMax(time.Millisecond),
+			logdash.WithOnDrop(func(entry logdash.LogEntry) {
+				droppedMu.Lock()
+				defer droppedMu.Unlock()
+				dropped = append(dropped, entry.Message)
+			}),
+		)
+
+		// WHEN
+		ld.Logger.Info(strings.Repeat("x", 2048))
+		ld.Logger.Info("small")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), largeAttempts.Load())
+		assert.Len(t, requestsCollector.requests, 1)
+		droppedMu.Lock()
+		defer droppedMu.Unlock()
+		assert.Equal(t, []string{strings.Repeat("x", 2048)}, dropped)
+	})
+
+	t.Run("should drop log and metric exceeding max request bytes without sending", func(t *testing.T) {
+		// GIVEN
+		const maxBytes = 1024
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMaxRequestBytes(maxBytes),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+
+		// WHEN
+		ld.Logger.Info(strings.Repeat("x", 2*maxBytes))
+		ld.Logger.Info("small")
+		ld.Metrics.Set(strings.Repeat("m", 2*maxBytes), 1)
+		ld.Metrics.Set("small", 1)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 2)
+		for _, r := range requestsCollector.requests {
+			assert.LessOrEqual(t, len(r.body), maxBytes)
+		}
+		stats := ld.Stats()
+		assert.Equal(t, int64(1), stats.Logs.Requests)
+		assert.Equal(t, int64(1), stats.Metrics.Requests)
+	})
+}
+
+func TestLogdashConnectionKeepAlive(t *testing.T) {
+	t.Run("should recover after server restart without failed sends", func(t *testing.T) {
+		// GIVEN
+		var heads atomic.Int32
+		requestsCollector := &requestsCollector{}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			if r.Method == http.MethodHead {
+				heads.Add(1)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		})
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		addr := listener.Addr().String()
+		httpServer := &httptest.Server{Listener: listener, Config: &http.Server{Handler: handler}}
+		httpServer.Start()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetries(0),
+			logdash.WithConnectionKeepAlive(10*time.Millisecond),
+		)
+		ld.Logger.Info("before restart")
+		assert.Eventually(t, func() bool { return ld.Stats().Logs.Requests == 1 }, time.Second, time.Millisecond)
+
+		// WHEN
+		httpServer.Close()
+		listener, err = net.Listen("tcp", addr)
+		assert.NoError(t, err)
+		httpServer = &httptest.Server{Listener: listener, Config: &http.Server{Handler: handler}}
+		httpServer.Start()
+		defer httpServer.Close()
+		heads.Store(0)
+		assert.Eventually(t, func() bool { return heads.Load() > 0 }, time.Second, time.Millisecond)
+
+		ld.Logger.Info("after restart")
+		err = ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 2)
+		stats := ld.Stats().Logs
+		assert.Equal(t, int64(2), stats.Status2xx)
+		assert.Zero(t, stats.Failures)
+	})
+
+	t.Run("should exercise the logs endpoint and cancel pending request on shutdown", func(t *testing.T) {
+		// GIVEN
+		type head struct {
+			path   string
+			apiKey string
+		}
+		heads := make(chan head, 1)
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			if r.Method == http.MethodHead {
+				select {
+				case heads <- head{path: r.URL.Path, apiKey: r.Header.Get("project-api-key")}:
+				default:
+				}
+				<-r.Context().Done()
+			}
+		}))
+		defer httpServer.Close()
+
+		const interval = 200 * time.Millisecond
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithConnectionKeepAlive(interval),
+		)
+		received := <-heads
+
+		// WHEN
+		start := time.Now()
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), interval/2)
+		assert.Equal(t, head{path: "/logs", apiKey: "test-api-key"}, received)
+	})
+}
+
+func TestLogdashVerboseMasksAPIKey(t *testing.T) {
+	t.Run("should never print the API key in verbose output", func(t *testing.T) {
+		// GIVEN
+		const apiKey = "secret-api-key-1234"
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			// echo the key like a misbehaving proxy would
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid project-api-key: " + r.Header.Get("project-api-key")))
+		}))
+		defer httpServer.Close()
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey(apiKey),
+				logdash.WithVerbose(),
+				logdash.WithHTTPRetries(1),
+				logdash.WithHTTPRetryMin(time.Millisecond),
+				logdash.WithHTTPRetryMax(time.Millisecond),
+			)
+			project := ld.ForProject("other-secret-key-5678")
+			ld.Logger.Info("Hello")
+			project.Logger.Info("Hello")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.Contains(t, output, "Failed to send log")
+		assert.Contains(t, output, "****")
+		assert.NotContains(t, output, apiKey)
+		assert.NotContains(t, output, "other-secret-key-5678")
+	})
+}
+
+func TestLogdashResponseValidator(t *testing.T) {
+	validator := func(status int, body []byte) error {
+		var envelope struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return err
+		}
+		if !envelope.OK {
+			return errors.New(envelope.Error)
+		}
+		return nil
+	}
+
+	t.Run("should retry response rejected by validator", func(t *testing.T) {
+		// GIVEN
+		var attempts atomic.Int32
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			if attempts.Add(1) == 1 {
+				_, _ = w.Write([]byte(`{"ok":false,"error":"quota exceeded"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}))
+		defer httpServer.Close()
+
+		var sent atomic.Int32
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetries(1),
+			logdash.WithHTTPRetryMin(time.Millisecond),
+			logdash.WithHTTPRetryMax(time.Millisecond),
+			logdash.WithResponseValidator(validator),
+			logdash.WithOnSend(func(string, []byte, int) {
+				sent.Add(1)
+			}),
+		)
+
+		// WHEN
+		ld.Logger.Info("Hello, World!")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), attempts.Load())
+		assert.Equal(t, int32(1), sent.Load())
+	})
+
+	t.Run("should report send as failed when retries are exhausted", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"ok":false,"error":"quota exceeded"}`))
+		}))
+		defer httpServer.Close()
+
+		unhealthy := make(chan error, 1)
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetries(0),
+			logdash.WithResponseValidator(validator),
+			logdash.WithRemoteFailureThreshold(1),
+			logdash.WithOnRemoteUnhealthy(func(err error) {
+				unhealthy <- err
+			}),
+		)
+
+		// WHEN
+		ld.Logger.Info("Hello, World!")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		select {
+		case err := <-unhealthy:
+			assert.ErrorContains(t, err, "quota exceeded")
+		case <-time.After(time.Second):
+			t.Error("send was not reported as failed")
+		}
+	})
+}
+
+func TestLogdashUnixSocket(t *testing.T) {
+	t.Run("should send logs and metrics over unix socket", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		socketPath := filepath.Join(t.TempDir(), "logdash.sock")
+		listener, err := net.Listen("unix", socketPath)
+		assert.NoError(t, err)
+		httpServer := &httptest.Server{Listener: listener, Config: &http.Server{Handler: http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			})}}
+		httpServer.Start()
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithUnixSocket(socketPath),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+
+		// WHEN
+		ld.Logger.Info("Hello, socket!")
+		ld.Metrics.Set("users", 1)
+		err = ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		paths := make(map[string]string)
+		for _, r := range requestsCollector.requests {
+			assert.Equal(t, "test-api-key", r.request.Header.Get("project-api-key"))
+			paths[r.request.URL.Path] = string(r.body)
+		}
+		assert.Len(t, paths, 2)
+		assert.Contains(t, paths["/logs"], "Hello, socket!")
+		assert.Contains(t, paths["/metrics"], "users")
+	})
+}
@@ -2,10 +2,14 @@ package logdash
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"maps"
+	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 // httpLogger implements syncLogger interface for HTTP output.
@@ -13,65 +17,327 @@ type httpLogger struct {
 	client         *httpClient
 	internalLogger *Logger
 	sequenceNumber atomic.Int64
-	processor      *asyncProcessor[logEntry]
+	processor      *asyncProcessor[*LogEntry]
+	method         string
+	// middlewares modify entries before sending, in registration order
+	middlewares []func(*LogEntry)
+	// lazyFields are computed for every log about to be sent, in registration order
+	lazyFields []lazyField
+	// wireLog writes sent JSON lines to the wire log writer, nil means no wire log
+	wireLog *asyncProcessor[[]byte]
+	// payloadBuilder builds the payload sent instead of the entry, nil means the entry is sent
+	payloadBuilder func(timestamp time.Time, level Level, message string, fields map[string]any) any
+	// poolEntries recycles entries after they're sent (see: [WithLogEntryPool])
+	poolEntries bool
+	// audit delivers logs until they're sent, nil when not in the audit mode
+	audit *auditDelivery
+	// fullSequence disables wrapping of sequence numbers at 2^32
+	fullSequence bool
+	// deadLetters receives logs which failed to be sent, nil means no dead-letter queue
+	deadLetters chan LogEntry
+	// fieldSizeLimit is the size of a serialized field above which it's reported, 0 means no limit
+	fieldSizeLimit int
+	// truncateFields truncates fields above fieldSizeLimit
+	truncateFields bool
 }
 
-// logEntry represents a single log entry to be sent to the server.
-type logEntry struct {
-	CreatedAt      string `json:"createdAt"`
-	Level          string `json:"level"`
-	Message        string `json:"message"`
-	SequenceNumber int64  `json:"sequenceNumber"`
+// truncatedFieldSuffix marks values of fields truncated by the field size limit.
+const truncatedFieldSuffix = "...(truncated)"
+
+// logEntryPool holds entries recycled after they're sent, shared by all loggers pooling entries.
+var logEntryPool = sync.Pool{
+	New: func() any {
+		return &LogEntry{}
+	},
+}
+
+// lazyField is a field computed only for logs which are sent (see: [WithLazyField]).
+type lazyField struct {
+	key string
+	fn  func() any
+}
+
+// wireLogBufferSize is the number of lines waiting to be written to the wire log, following lines are dropped.
+const wireLogBufferSize = 1024
+
+// LogEntry represents a single log entry to be sent to the server.
+//
+// Entries can be modified before sending by middlewares (see: [WithLogEntryMiddleware]).
+type LogEntry struct {
+	// CreatedAt is the timestamp of the log in UTC, formatted as RFC 3339 with nanoseconds.
+	CreatedAt string `json:"createdAt"`
+	// Level is the name of the log level, e.g. "info".
+	Level string `json:"level"`
+	// Message is the final message, including prefix and suffix.
+	Message string `json:"message"`
+	// SequenceNumber orders logs with the same timestamp.
+	SequenceNumber int64 `json:"sequenceNumber"`
+	// Data is the structured data of the log, including fields.
+	Data map[string]any `json:"data,omitempty"`
+
+	// payload is sent instead of the entry when set (see: [WithLogPayloadBuilder])
+	payload any
 }
 
 // newHTTPLogger creates a new HTTPLogger instance.
-func newHTTPLogger(o *options, internalLogger *Logger, bufferSize int) *httpLogger {
+func newHTTPLogger(o *options, client *httpClient, internalLogger *Logger) *httpLogger {
 	logger := &httpLogger{
-		client:         newHTTPClient(o, internalLogger),
+		client:         client,
 		internalLogger: internalLogger,
+		method:         o.logsMethod,
+		middlewares:    o.logEntryMiddlewares,
+		lazyFields:     o.lazyFields,
+		payloadBuilder: o.logPayloadBuilder,
+		poolEntries:    o.logEntryPool,
+		fullSequence:   o.auditMode,
+		fieldSizeLimit: o.fieldSizeLimit,
+		truncateFields: o.truncateFields,
+	}
+	if o.auditMode {
+		logger.audit = newAuditDelivery(o, internalLogger)
 	}
 
 	// Create async processor for logs
 	logger.processor = newAsyncProcessor(
-		bufferSize,
-		func(entry logEntry) error {
-			return logger.client.sendData("/logs", http.MethodPost, entry)
+		o.bufferSize,
+		o.asyncWorkers,
+		func(entry *LogEntry) error {
+			var payload any = entry
+			if entry.payload != nil {
+				payload = entry.payload
+			}
+			if logger.wireLog != nil {
+				jsonData, err := json.Marshal(payload)
+				if err != nil {
+					return fmt.Errorf("failed to marshal: %w", err)
+				}
+				// the line is written by its own worker, so a slow writer doesn't delay sending
+				logger.wireLog.send(append(jsonData, '\n'))
+				payload = json.RawMessage(jsonData)
+			}
+			if logger.audit != nil {
+				return logger.audit.deliver(logger.client, logger.method, payload)
+			}
+			return logger.client.sendData(context.Background(), "/logs", logger.method, payload)
 		},
-		func(entry logEntry, err error) {
-			if err == errChannelOverflow {
+		func(entry *LogEntry, err error) {
+			if err == ErrOverflow {
+				logger.client.recordDrop("/logs")
 				logger.internalLogger.Error("Log dropped due to channel overflow")
+			} else if errors.Is(err, ErrPayloadTooLarge) {
+				logger.client.recordDrop("/logs")
+				logger.internalLogger.Error(fmt.Sprintf("Log dropped: %v", err))
+				if o.onDrop != nil {
+					o.onDrop(*entry)
+				}
 			} else {
 				logger.internalLogger.Error(fmt.Sprintf("Failed to send log: %v", err))
+				logger.deadLetter(entry)
 			}
 		},
 	)
+	if o.onDrop != nil {
+		logger.processor.dropHandler = func(entry *LogEntry) {
+			o.onDrop(*entry)
+		}
+	}
+	if logger.poolEntries {
+		logger.processor.releaseHandler = releaseLogEntry
+	}
+	if o.onBufferHigh != nil || o.onBufferLow != nil {
+		logger.processor.watermarks = &bufferWatermarks{
+			high:   o.bufferHighWatermark,
+			low:    o.bufferLowWatermark,
+			onHigh: o.onBufferHigh,
+			onLow:  o.onBufferLow,
+		}
+	}
+	if o.logFlushInterval > 0 {
+		logger.processor.startFlushTicker(o.logFlushInterval, func() {
+			logger.internalLogger.WarnF("Buffered logs are waiting for longer than the flush interval of %v", o.logFlushInterval)
+		})
+	}
+
+	if o.wireLogWriter != nil {
+		logger.wireLog = newAsyncProcessor(
+			wireLogBufferSize,
+			1,
+			func(line []byte) error {
+				_, err := o.wireLogWriter.Write(line)
+				return err
+			},
+			func(line []byte, err error) {
+				logger.internalLogger.Error(fmt.Sprintf("Failed to write wire log: %v", err))
+			},
+		)
+		logger.wireLog.SetOverflowPolicy(OverflowPolicyDrop)
+	}
 
 	return logger
 }
 
 // syncLog implements the syncLogger interface.
-func (l *httpLogger) syncLog(timestamp time.Time, level logLevel, message string) {
-	entry := logEntry{
-		CreatedAt:      timestamp.UTC().Format(time.RFC3339Nano),
+func (l *httpLogger) syncLog(timestamp time.Time, level Level, message string, data map[string]any) {
+	_ = l.trySyncLog(timestamp, level, message, data)
+}
+
+// trySyncLog implements the trySyncLogger interface.
+func (l *httpLogger) trySyncLog(timestamp time.Time, level Level, message string, data map[string]any) error {
+	if len(l.lazyFields) > 0 {
+		data = l.withLazyFields(data)
+	}
+	if l.fieldSizeLimit > 0 {
+		data = l.limitFieldSizes(data)
+	}
+	entry := l.newEntry()
+	*entry = LogEntry{
+		CreatedAt:      formatWireTimestamp(timestamp),
 		Level:          string(level),
 		Message:        message,
-		SequenceNumber: l.sequenceNumber.Add(1) % (1 << 32),
+		SequenceNumber: l.nextSequenceNumber(),
+		Data:           data,
+	}
+	for _, middleware := range l.middlewares {
+		middleware(entry)
+	}
+	if l.payloadBuilder != nil {
+		entry.payload = l.payloadBuilder(timestamp, Level(entry.Level), entry.Message, entry.Data)
+	}
+
+	return l.processor.send(entry)
+}
+
+// deadLetter passes a copy of the entry, which failed to be sent, to the dead-letter queue unless it's full.
+func (l *httpLogger) deadLetter(entry *LogEntry) {
+	if l.deadLetters == nil {
+		return
 	}
+	// the entry may be recycled after it's released, so it's copied
+	deadLetter := *entry
+	deadLetter.payload = nil
+	select {
+	case l.deadLetters <- deadLetter:
+	default:
+		l.internalLogger.Error("Failed log discarded, dead-letter queue is full")
+	}
+}
+
+// nextSequenceNumber returns the sequence number of the next log, wrapping at 2^32 unless it's full-range.
+func (l *httpLogger) nextSequenceNumber() int64 {
+	n := l.sequenceNumber.Add(1)
+	if l.fullSequence {
+		return n
+	}
+	return n % (1 << 32)
+}
+
+// newEntry returns an entry to be filled, recycled when entries are pooled.
+func (l *httpLogger) newEntry() *LogEntry {
+	if l.poolEntries {
+		return logEntryPool.Get().(*LogEntry)
+	}
+	return &LogEntry{}
+}
+
+// releaseLogEntry returns the entry, which is already sent or dropped, to the pool.
+func releaseLogEntry(entry *LogEntry) {
+	// references are cleared, so the pool doesn't keep data of logs alive
+	*entry = LogEntry{}
+	logEntryPool.Put(entry)
+}
 
-	l.processor.send(entry)
+// withLazyFields returns a copy of the data with lazy fields computed, data of the log takes precedence.
+func (l *httpLogger) withLazyFields(data map[string]any) map[string]any {
+	// the data is shared with other outputs, so it's not modified
+	withLazy := make(map[string]any, len(data)+len(l.lazyFields))
+	for _, field := range l.lazyFields {
+		if _, ok := data[field.key]; !ok {
+			withLazy[field.key] = field.fn()
+		}
+	}
+	maps.Copy(withLazy, data)
+	return withLazy
+}
+
+// limitFieldSizes reports fields of the data, which are larger than the limit when serialized,
+// and returns a copy of the data with them truncated if enabled.
+func (l *httpLogger) limitFieldSizes(data map[string]any) map[string]any {
+	limited, copied := data, false
+	for key, value := range data {
+		serialized, err := json.Marshal(value)
+		if err != nil || len(serialized) <= l.fieldSizeLimit {
+			continue
+		}
+		l.internalLogger.WarnF("Field %s of the log has %d bytes, exceeding the limit of %d bytes",
+			key, len(serialized), l.fieldSizeLimit)
+		if !l.truncateFields {
+			continue
+		}
+		// the data is shared with other outputs, so it's not modified
+		if !copied {
+			limited, copied = maps.Clone(data), true
+		}
+		limited[key] = truncateUTF8(serialized, l.fieldSizeLimit) + truncatedFieldSuffix
+	}
+	return limited
+}
+
+// truncateUTF8 returns at most size bytes of the text, which is longer, without splitting a multi-byte character.
+func truncateUTF8(text []byte, size int) string {
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	return string(text[:size])
 }
 
 // Close stops the background worker and closes the logger.
 func (l *httpLogger) Close() error {
-	return l.processor.Close()
+	if l.audit != nil {
+		// pending logs are spilled instead of being sent
+		l.audit.cancel()
+	}
+	err := l.processor.Close()
+	if l.audit != nil && err == nil {
+		// wait until pending logs are spilled, so the spill isn't written after Close returns
+		<-l.processor.stoppedChan
+	}
+	if l.wireLog != nil {
+		l.wireLog.Close()
+	}
+	return err
+}
+
+// flush waits until logs logged so far are sent to the server.
+func (l *httpLogger) flush(ctx context.Context) error {
+	return l.processor.flush(ctx)
 }
 
 // Shutdown stops the background worker and closes the logger.
 func (l *httpLogger) Shutdown(ctx context.Context) error {
-	return l.processor.Shutdown(ctx)
+	if l.audit != nil {
+		// logs not delivered when the context is done are spilled
+		stop := context.AfterFunc(ctx, l.audit.cancel)
+		defer stop()
+	}
+	err := l.processor.Shutdown(ctx)
+	if l.audit != nil && ctx.Err() != nil && !errors.Is(err, ErrAlreadyClosed) {
+		// wait until pending logs are spilled
+		<-l.processor.stoppedChan
+	}
+	if l.wireLog != nil {
+		// lines of logs sent until the processor stopped are enqueued,
+		// lines of logs sent after the context is done are rejected
+		err = errors.Join(err, l.wireLog.Shutdown(ctx))
+	}
+	return err
 }
 
 // SetOverflowPolicy sets the overflow policy for the logger
 func (l *httpLogger) SetOverflowPolicy(policy OverflowPolicy) {
 	l.processor.SetOverflowPolicy(policy)
 }
+
+// resizeBuffer changes the size of the buffer of logs waiting to be sent.
+func (l *httpLogger) resizeBuffer(size int) error {
+	return l.processor.resize(size)
+}
@@ -0,0 +1,86 @@
+package logdash
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPLoggerShutdown(t *testing.T) {
+	t.Run("should shut down wire log when logs aren't sent before context is done", func(t *testing.T) {
+		// GIVEN
+		release := make(chan struct{})
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			<-release
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+		defer close(release)
+
+		o := &options{
+			host:          httpServer.URL,
+			apiKey:        "test-api-key",
+			logsMethod:    http.MethodPost,
+			bufferSize:    DefaultBufferSize,
+			asyncWorkers:  1,
+			wireLogWriter: &bytes.Buffer{},
+		}
+		internalLogger := newLogger(newNoopLogger())
+		logger := newHTTPLogger(o, newHTTPClient(o, internalLogger), internalLogger)
+		logger.syncLog(time.Now(), LevelInfo, "stalled", nil)
+
+		// WHEN
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := logger.Shutdown(ctx)
+
+		// THEN
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorIs(t, logger.wireLog.send([]byte("late\n")), ErrAlreadyClosed)
+	})
+}
+
+func BenchmarkHTTPLoggerEntryPool(b *testing.B) {
+	benchmarks := []struct {
+		name        string
+		poolEntries bool
+	}{
+		{name: "unpooled"},
+		{name: "pooled", poolEntries: true},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			o := &options{
+				host:         "http://localhost",
+				apiKey:       "test-api-key",
+				logsMethod:   http.MethodPost,
+				bufferSize:   DefaultBufferSize,
+				asyncWorkers: 1,
+				logEntryPool: bm.poolEntries,
+			}
+			internalLogger := newLogger(newNoopLogger())
+			logger := newHTTPLogger(o, newHTTPClient(o, internalLogger), internalLogger)
+			// marshaling stands for sending, so the benchmark isn't dominated by HTTP
+			logger.processor.processFunc = func(entry *LogEntry) error {
+				_, err := json.Marshal(entry)
+				return err
+			}
+			timestamp := time.Now()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				logger.syncLog(timestamp, LevelInfo, "Processing request", nil)
+			}
+			_ = logger.Shutdown(context.Background())
+		})
+	}
+}
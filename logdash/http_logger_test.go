@@ -0,0 +1,856 @@
+package logdash_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashAsyncWorkers(t *testing.T) {
+	t.Run("should drain logs concurrently without losing any", func(t *testing.T) {
+		// GIVEN
+		const (
+			logs    = 20
+			workers = 4
+			latency = 50 * time.Millisecond
+		)
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			time.Sleep(latency)
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithAsyncWorkers(workers),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+		)
+
+		// WHEN
+		start := time.Now()
+		for range logs {
+			ld.Logger.Info("Hello, workers!")
+		}
+		err := ld.Shutdown(context.Background())
+		elapsed := time.Since(start)
+
+		// THEN
+		assert.NoError(t, err)
+		// a single worker needs logs * latency
+		assert.Less(t, elapsed, logs*latency/2)
+
+		assert.Len(t, requestsCollector.requests, logs)
+		sequenceNumbers := make(map[float64]struct{}, logs)
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			sequenceNumbers[body["sequenceNumber"].(float64)] = struct{}{}
+		}
+		for i := 1; i <= logs; i++ {
+			assert.Contains(t, sequenceNumbers, float64(i))
+		}
+	})
+}
+
+func TestLogdashLogEntryMiddleware(t *testing.T) {
+	t.Run("should send entries modified by middlewares in order", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		opts := []logdash.Option{
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithFields(map[string]any{"service": "api"}),
+			logdash.WithLogEntryMiddleware(func(entry *logdash.LogEntry) {
+				data := map[string]any{"length": len(entry.Message)}
+				for key, value := range entry.Data {
+					data[key] = value
+				}
+				entry.Data = data
+			}),
+			logdash.WithLogEntryMiddleware(func(entry *logdash.LogEntry) {
+				entry.Message = entry.Message + " (enriched)"
+			}),
+		}
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(opts...)
+			ld.Logger.Info("Hello")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.Contains(t, output, "INFO Hello")
+		assert.NotContains(t, output, "enriched")
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, "Hello (enriched)", body["message"])
+		assert.Equal(t, map[string]any{"service": "api", "length": float64(5)}, body["data"])
+	})
+}
+
+func TestLogdashWireLogFile(t *testing.T) {
+	t.Run("should write the exact sent JSON as NDJSON", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		wireLog := &bytes.Buffer{}
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithWireLogFile(wireLog),
+		)
+
+		// WHEN
+		ld.Logger.WithFields(map[string]any{"user": "john"}).Info("first")
+		ld.Logger.Warn("second")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSuffix(wireLog.String(), "\n"), "\n")
+		assert.Len(t, lines, 2)
+		assert.Len(t, requestsCollector.requests, 2)
+		for i, request := range requestsCollector.requests {
+			assert.Equal(t, string(request.body), lines[i])
+		}
+	})
+}
+
+func TestLogdashLazyField(t *testing.T) {
+	t.Run("should compute lazy field only for sent logs", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		var calls atomic.Int64
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithLogFilter(func(level logdash.Level, message string) bool {
+				return message != "filtered"
+			}),
+			logdash.WithLazyField("pool", func() any {
+				calls.Add(1)
+				return map[string]any{"open": 3}
+			}),
+		)
+
+		// WHEN
+		ld.Logger.Info("filtered")
+		callsAfterFiltered := calls.Load()
+		ld.Logger.Info("sent")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), callsAfterFiltered)
+		assert.Equal(t, int64(1), calls.Load())
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, map[string]any{"pool": map[string]any{"open": float64(3)}}, body["data"])
+	})
+}
+
+func TestLogdashLogFlushInterval(t *testing.T) {
+	t.Run("should warn about buffered log waiting behind busy worker and keep order", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		var received atomic.Int64
+		release := make(chan struct{})
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			if received.Add(1) == 1 {
+				<-release
+			}
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		// WHEN
+		var requestsWhileBusy int64
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithVerbose(),
+				logdash.WithLogFlushInterval(20*time.Millisecond),
+			)
+			ld.Logger.Info("slow")
+			assert.Eventually(t, func() bool { return received.Load() == 1 }, time.Second, time.Millisecond)
+			ld.Logger.Info("buffered")
+			time.Sleep(100 * time.Millisecond)
+			requestsWhileBusy = received.Load()
+			close(release)
+			assert.NoError(t, ld.Shutdown(context.Background()))
+		})
+
+		// THEN
+		assert.Equal(t, int64(1), requestsWhileBusy)
+		assert.Equal(t, 1, strings.Count(output, "Buffered logs are waiting for longer than the flush interval of 20ms"))
+		if assert.Len(t, requestsCollector.requests, 2) {
+			for i, message := range []string{"slow", "buffered"} {
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(requestsCollector.requests[i].body, &body))
+				assert.Equal(t, message, body["message"])
+			}
+		}
+	})
+}
+
+func TestLogdashLogPayloadBuilder(t *testing.T) {
+	t.Run("should send payload built by the custom builder", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		fixedTime := time.Date(2024, 5, 17, 10, 30, 45, 0, time.UTC)
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithTimeSource(func() time.Time { return fixedTime }),
+			logdash.WithLogPayloadBuilder(func(timestamp time.Time, level logdash.Level, message string, fields map[string]any) any {
+				return map[string]any{
+					"meta": map[string]any{"level": level, "time": timestamp.Unix()},
+					"body": map[string]any{"text": message, "fields": fields},
+				}
+			}),
+		)
+
+		// WHEN
+		ld.Logger.Infow("Hello, builder!", "user", "john")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, map[string]any{
+			"meta": map[string]any{"level": "info", "time": float64(fixedTime.Unix())},
+			"body": map[string]any{"text": "Hello, builder!", "fields": map[string]any{"user": "john"}},
+		}, body)
+	})
+}
+
+func TestLogdashLogEntryPool(t *testing.T) {
+	t.Run("should send every log intact when entries are recycled concurrently", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithLogEntryPool(),
+			logdash.WithAsyncWorkers(4),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+			logdash.WithLogEntryMiddleware(func(entry *logdash.LogEntry) {
+				entry.Data = map[string]any{"message": entry.Message}
+			}),
+		)
+
+		// WHEN
+		const goroutines, logs = 8, 25
+		var wg sync.WaitGroup
+		for g := range goroutines {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range logs {
+					ld.Logger.InfoF("log %d-%d", g, i)
+				}
+			}()
+		}
+		wg.Wait()
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, goroutines*logs)
+		messages := make(map[string]struct{})
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			message := body["message"].(string)
+			assert.Equal(t, message, body["data"].(map[string]any)["message"])
+			messages[message] = struct{}{}
+		}
+		assert.Len(t, messages, goroutines*logs)
+	})
+}
+
+func TestLogdashAuditMode(t *testing.T) {
+	t.Run("should deliver every log exactly once in order when server is down for a while", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		var down atomic.Bool
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			if down.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		spill := &bytes.Buffer{}
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithAuditMode(spill),
+			logdash.WithHTTPRetryMax(10*time.Millisecond),
+			// undermining options are overridden
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyDrop),
+			logdash.WithAsyncWorkers(4),
+		)
+
+		// WHEN
+		const logs = 15
+		for i := range logs {
+			if i == 5 {
+				down.Store(true)
+				time.AfterFunc(100*time.Millisecond, func() {
+					down.Store(false)
+				})
+			}
+			ld.Logger.InfoF("audit %d", i+1)
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Empty(t, spill.String())
+		assert.Len(t, requestsCollector.requests, logs)
+		for i, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			assert.Equal(t, fmt.Sprintf("audit %d", i+1), body["message"])
+			assert.Equal(t, float64(i+1), body["sequenceNumber"])
+		}
+	})
+
+	t.Run("should spill undelivered logs in order when shutdown context is done", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer httpServer.Close()
+
+		spill := &bytes.Buffer{}
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithAuditMode(spill),
+			logdash.WithHTTPRetryMax(10*time.Millisecond),
+		)
+		const logs = 5
+		for i := range logs {
+			ld.Logger.InfoF("audit %d", i+1)
+		}
+
+		// WHEN
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := ld.Shutdown(ctx)
+
+		// THEN
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		lines := strings.Split(strings.TrimSuffix(spill.String(), "\n"), "\n")
+		assert.Len(t, lines, logs)
+		for i, line := range lines {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal([]byte(line), &body))
+			assert.Equal(t, fmt.Sprintf("audit %d", i+1), body["message"])
+		}
+	})
+	t.Run("should spill pending logs before close returns", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer httpServer.Close()
+
+		spill := &closableSpill{}
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithAuditMode(spill),
+			logdash.WithHTTPRetryMax(10*time.Millisecond),
+		)
+		const logs = 5
+		for i := range logs {
+			ld.Logger.InfoF("audit %d", i+1)
+		}
+
+		// WHEN
+		err := ld.Close()
+		spill.close()
+		time.Sleep(50 * time.Millisecond)
+
+		// THEN
+		assert.NoError(t, err)
+		lines, writesAfterClose := spill.stats()
+		assert.Equal(t, logs, lines)
+		assert.Zero(t, writesAfterClose)
+	})
+}
+
+// closableSpill counts lines written to it and writes after it's closed.
+type closableSpill struct {
+	mu               sync.Mutex
+	closed           bool
+	lines            int
+	writesAfterClose int
+}
+
+func (s *closableSpill) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		s.writesAfterClose++
+		return 0, errors.New("spill closed")
+	}
+	s.lines += bytes.Count(p, []byte("\n"))
+	return len(p), nil
+}
+
+func (s *closableSpill) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+func (s *closableSpill) stats() (lines, writesAfterClose int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lines, s.writesAfterClose
+}
+
+func TestLogdashLogDeadLetterQueue(t *testing.T) {
+	t.Run("should route logs failed after retries to the dead-letter queue", func(t *testing.T) {
+		// GIVEN
+		var requests atomic.Int64
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			requests.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetries(2),
+			logdash.WithHTTPRetryMin(time.Millisecond),
+			logdash.WithHTTPRetryMax(time.Millisecond),
+			logdash.WithLogDeadLetterQueue(10),
+			logdash.WithFields(map[string]any{"service": "api"}),
+		)
+
+		// WHEN
+		ld.Logger.Info("first")
+		ld.Logger.Error("second")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), requests.Load())
+		deadLetters := ld.DeadLetters()
+		if assert.Len(t, deadLetters, 2) {
+			first, second := <-deadLetters, <-deadLetters
+			assert.Equal(t, "first", first.Message)
+			assert.Equal(t, "info", first.Level)
+			assert.Equal(t, map[string]any{"service": "api"}, first.Data)
+			assert.Equal(t, "second", second.Message)
+			assert.Equal(t, "error", second.Level)
+		}
+	})
+
+	t.Run("should discard failed logs when the dead-letter queue is full", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithLogDeadLetterQueue(2),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+		)
+
+		// WHEN
+		for i := range 5 {
+			ld.Logger.InfoF("log %d", i)
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		deadLetters := ld.DeadLetters()
+		if assert.Len(t, deadLetters, 2) {
+			assert.Equal(t, "log 0", (<-deadLetters).Message)
+			assert.Equal(t, "log 1", (<-deadLetters).Message)
+		}
+	})
+
+	t.Run("should not enable the dead-letter queue by default", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New()
+		defer ld.Close()
+
+		// WHEN
+		deadLetters := ld.DeadLetters()
+
+		// THEN
+		assert.Nil(t, deadLetters)
+	})
+}
+
+func TestLogdashFieldTypes(t *testing.T) {
+	testCases := []struct {
+		name         string
+		log          func(ld *logdash.Logdash)
+		expectedData string
+	}{
+		{
+			name: "should send numbers and booleans of keys and values as JSON types",
+			log: func(ld *logdash.Logdash) {
+				ld.Logger.Infow("msg", "count", 42, "ok", true, "ratio", 0.5, "name", "john")
+			},
+			expectedData: `{"count":42,"name":"john","ok":true,"ratio":0.5}`,
+		},
+		{
+			name: "should send numbers and booleans of fields as JSON types",
+			log: func(ld *logdash.Logdash) {
+				ld.Logger.WithFields(map[string]any{"count": int64(42), "ok": false}).Info("msg")
+			},
+			expectedData: `{"count":42,"ok":false}`,
+		},
+		{
+			name: "should send numbers and booleans of slog attributes as JSON types",
+			log: func(ld *logdash.Logdash) {
+				logger := slog.New(logdash.NewSlogTextHandler(ld.Logger, slog.HandlerOptions{Level: slog.LevelInfo}, logdash.WithSlogStructuredAttrs()))
+				logger.With("retries", uint64(3)).Info("msg", "count", 42, "ok", true, slog.Group("http", "status", 200))
+			},
+			expectedData: `{"attrs":{"count":42,"http":{"status":200},"ok":true},"fields":{"retries":3}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+			)
+
+			// WHEN
+			tc.log(ld)
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			if assert.Len(t, requestsCollector.requests, 1) {
+				var body map[string]json.RawMessage
+				assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+				assert.JSONEq(t, tc.expectedData, string(body["data"]))
+			}
+		})
+	}
+}
+
+func TestLogdashResizeLogBuffer(t *testing.T) {
+	t.Run("should not lose logs when buffer grows under load", func(t *testing.T) {
+		// GIVEN
+		const logs = 500
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			time.Sleep(time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+			logdash.WithBufferSize(4),
+			logdash.WithAsyncWorkers(2),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+		)
+
+		// WHEN
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range logs {
+				ld.Logger.Infow("Hello, resize!", "i", i)
+			}
+		}()
+		for _, size := range []int{8, 16, 64, 256} {
+			time.Sleep(10 * time.Millisecond)
+			assert.NoError(t, ld.ResizeLogBuffer(size))
+		}
+		wg.Wait()
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, logs)
+		indices := make(map[float64]struct{}, logs)
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			indices[body["data"].(map[string]any)["i"].(float64)] = struct{}{}
+		}
+		assert.Len(t, indices, logs)
+	})
+
+	t.Run("should reject shrinking below number of buffered logs", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		received := make(chan struct{}, 1)
+		release := make(chan struct{})
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+			<-release
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+			logdash.WithBufferSize(10),
+		)
+		// the first log is being sent, others wait in the buffer
+		ld.Logger.Info("first")
+		<-received
+		for range 5 {
+			ld.Logger.Info("buffered")
+		}
+
+		// WHEN
+		shrinkErr := ld.ResizeLogBuffer(2)
+		resizeErr := ld.ResizeLogBuffer(5)
+		close(release)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.ErrorIs(t, shrinkErr, logdash.ErrBufferTooSmall)
+		assert.NoError(t, resizeErr)
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 6)
+	})
+
+	t.Run("should keep order of audit logs when resizing during a stalled send", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		received := make(chan struct{}, 1)
+		release := make(chan struct{})
+		var inFlight, maxInFlight atomic.Int32
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			if n > maxInFlight.Load() {
+				maxInFlight.Store(n)
+			}
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+			<-release
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+			logdash.WithAuditMode(&bytes.Buffer{}),
+			logdash.WithBufferSize(4),
+		)
+		// the first log is being sent, others wait in the buffer
+		ld.Logger.Info("audit 1")
+		<-received
+		for i := 2; i <= 4; i++ {
+			ld.Logger.InfoF("audit %d", i)
+		}
+
+		// WHEN
+		resizeErr := ld.ResizeLogBuffer(16)
+		for i := 5; i <= 8; i++ {
+			ld.Logger.InfoF("audit %d", i)
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, resizeErr)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), maxInFlight.Load())
+		if assert.Len(t, requestsCollector.requests, 8) {
+			for i, r := range requestsCollector.requests {
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(r.body, &body))
+				assert.Equal(t, fmt.Sprintf("audit %d", i+1), body["message"])
+			}
+		}
+	})
+}
+
+func TestLogdashFieldSizeLimit(t *testing.T) {
+	const limit = 100
+	largeBody := strings.Repeat("x", 1000)
+
+	testCases := []struct {
+		name         string
+		truncate     bool
+		expectedBody string
+	}{
+		{
+			name:         "should warn about oversized field and send it as is",
+			expectedBody: largeBody,
+		},
+		{
+			name:         "should warn about oversized field and truncate it",
+			truncate:     true,
+			expectedBody: `"` + strings.Repeat("x", limit-1) + "...(truncated)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			sink := logdash.NewMemorySink()
+
+			// WHEN
+			output := captureStdout(t, func() {
+				ld := logdash.New(
+					logdash.WithHost(httpServer.URL),
+					logdash.WithAPIKey("test-api-key"),
+					logdash.WithVerbose(),
+					logdash.WithSink(sink),
+					logdash.WithFieldSizeLimit(limit, tc.truncate),
+				)
+				ld.Logger.Infow("Response received", "body", largeBody, "status", 200)
+				err := ld.Shutdown(context.Background())
+				assert.NoError(t, err)
+			})
+
+			// THEN
+			assert.Contains(t, output, "Field body of the log has 1002 bytes, exceeding the limit of 100 bytes")
+			assert.NotContains(t, output, "Field status")
+			if assert.Len(t, requestsCollector.requests, 1) {
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+				assert.Equal(t, map[string]any{"body": tc.expectedBody, "status": float64(200)}, body["data"])
+			}
+			// the data is shared with other outputs, so it's not modified
+			entries := sink.Entries()
+			if assert.Len(t, entries, 1) {
+				assert.Equal(t, largeBody, entries[0].Fields["body"])
+			}
+		})
+	}
+}
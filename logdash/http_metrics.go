@@ -2,13 +2,16 @@ package logdash
 
 import (
 	"context"
-	"net/http"
+	"errors"
+	"maps"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type (
-	// httpMetrics implements Metrics interface for HTTP output.
+	// httpMetrics implements metricsBackend interface for HTTP output.
 	httpMetrics struct {
 		client         *httpClient
 		internalLogger *Logger
@@ -23,9 +26,88 @@ type (
 
 		// informs about stopping the dispatcher (and all pipeline downstream)
 		stoppedChan chan struct{}
+		// closed when stopping starts, so pending metrics are sent without rate limiting
+		stoppingChan chan struct{}
+		// sendCtx is canceled when the shutdown context is done, so in-flight requests don't outlive the shutdown
+		sendCtx     context.Context
+		cancelSends context.CancelFunc
+
+		// shutdownRetryInterval between attempts to send a metric during shutdown, 0 means no retries
+		shutdownRetryInterval time.Duration
+		// retrySends is set when shutdown starts, so failed sends are retried until sendCtx is canceled
+		retrySends atomic.Bool
+
+		// rateLimiter limits the rate of requests, nil means no limit
+		rateLimiter *rateLimiter
 
 		accumulatorsWg sync.WaitGroup
 
+		// idle accumulators ask the dispatcher to be evicted
+		evictionChan chan accumulatorEviction
+		// idleTimeout after which an accumulator without pending metric is evicted, 0 means never
+		idleTimeout time.Duration
+
+		// coalesceWindow after which accumulated metric is queued for sending, 0 means never
+		coalesceWindow time.Duration
+
+		// flushThreshold of accumulated relative changes at which they are queued for sending, 0 means no threshold
+		flushThreshold float64
+		// flushCount of accumulated relative changes at which they are queued for sending, 0 means no limit
+		flushCount int
+
+		// rounding rounds accumulated values before sending, nil means no rounding
+		rounding func(float64) float64
+
+		// dedup suppresses setting metrics to their last set value
+		dedup bool
+		// dedupWindow after which the last set value is sent again, 0 means never
+		dedupWindow time.Duration
+
+		// snapshotInterval at which accumulated metric is sent, 0 means metrics are sent as soon as possible
+		snapshotInterval time.Duration
+
+		// atomicBatchWindow for which a set is held to be merged with following changes, 0 means sets aren't held
+		atomicBatchWindow time.Duration
+
+		// absoluteValues are the last known values of metrics which were set, to be resent
+		// when the remote becomes healthy, nil means no resending
+		absoluteValues   map[string]float64
+		absoluteValuesMu sync.Mutex
+
+		// immediate sends every operation as its own request, without accumulation
+		immediate bool
+		// sentByDispatcher sends immediate metrics by the dispatcher itself, without the sending loop,
+		// to save a goroutine (see: [WithMinimalMode])
+		sentByDispatcher bool
+
+		// confirmed are values of metrics acknowledged by the server, nil means sets are not suppressed,
+		// it's accessed only by the goroutine sending metrics, which sends operations of a metric in order
+		confirmed map[string]float64
+
+		// overflowPolicy defines what happens when the dispatch channel is full
+		overflowPolicy OverflowPolicy
+
+		// method is the HTTP method used to send metrics
+		method string
+
+		// now returns the current time used as the timestamp of metrics
+		now func() time.Time
+
+		// maxNames limits the number of distinct metric names, 0 means no limit
+		maxNames int
+		// maxAccumulators limits the number of accumulators, metrics of other names are sent without accumulation,
+		// 0 means no limit
+		maxAccumulators int
+
+		// namePrefix is prepended to names of metrics, once they're dispatched
+		namePrefix string
+
+		// units declared for metrics, nil means no units
+		units *metricUnits
+
+		// operations of metrics in the strict mode, nil means lenient mode
+		operations *metricOperations
+
 		stopping bool
 	}
 
@@ -35,6 +117,23 @@ type (
 		Name      string  `json:"name"`
 		Value     float64 `json:"value"`
 		Operation string  `json:"operation"`
+		Unit      string  `json:"unit,omitempty"`
+	}
+
+	// metricOperations tracks operations used on metric names (see: [WithStrictMetricTypes]).
+	//
+	// It's shared by the metrics front, which declares types of registered metrics, and the backend which checks them.
+	metricOperations struct {
+		mu    sync.Mutex
+		first map[string]string
+		// mixed contains names which were already reported as mixing operations
+		mixed map[string]struct{}
+	}
+
+	// accumulatorEviction is a request of an idle accumulator to be stopped.
+	accumulatorEviction struct {
+		name string
+		c    <-chan metricEntry
 	}
 )
 
@@ -43,18 +142,61 @@ const (
 	metricOperationMutate = "change"
 )
 
+// maxPendingWindows is the maximum number of closed coalesce windows of a metric waiting for sending,
+// e.g. while the server is unreachable, following windows are merged into the newest one.
+const maxPendingWindows = 64
+
+// knownMetricOperations are operations understood by the server, other operations are rejected before sending.
+var knownMetricOperations = map[string]struct{}{
+	metricOperationSet:    {},
+	metricOperationMutate: {},
+}
+
 // newHTTPMetrics creates a new HTTPMetrics instance.
-func newHTTPMetrics(o *options, internalLogger *Logger) *httpMetrics {
+func newHTTPMetrics(o *options, client *httpClient, internalLogger *Logger) *httpMetrics {
+	sendCtx, cancelSends := context.WithCancel(context.Background())
 	metrics := &httpMetrics{
-		client:                 newHTTPClient(o, internalLogger),
+		client:                 client,
 		internalLogger:         internalLogger,
 		sendingAccumulatedChan: make(chan metricEntry),
 		stoppedChan:            make(chan struct{}),
-		dispatchChan:           make(chan metricEntry),
+		stoppingChan:           make(chan struct{}),
+		sendCtx:                sendCtx,
+		cancelSends:            cancelSends,
+		shutdownRetryInterval:  o.metricShutdownRetry,
+		namePrefix:             o.metricPrefix,
+		atomicBatchWindow:      o.metricAtomicBatch,
+		rateLimiter:            newRateLimiter(o.metricsRateLimit),
+		dispatchChan:           make(chan metricEntry, o.metricsBufferSize),
+		overflowPolicy:         o.metricsOverflowPolicy,
+		evictionChan:           make(chan accumulatorEviction),
+		idleTimeout:            o.metricIdleTimeout,
+		now:                    o.timeSource,
+		coalesceWindow:         o.metricCoalesceWindow,
+		snapshotInterval:       o.metricSnapshotInterval,
+		flushThreshold:         o.metricFlushThreshold,
+		flushCount:             o.metricFlushCount,
+		dedup:                  o.metricDedup,
+		rounding:               o.metricRounding,
+		dedupWindow:            o.metricDedupWindow,
+		method:                 o.metricsMethod,
+		maxNames:               o.maxMetricNames,
+		maxAccumulators:        o.maxMetricAccumulators,
+		immediate:              o.immediateMetrics,
+		sentByDispatcher:       o.immediateMetrics && o.metricsByDispatcher,
+	}
+	if o.responseCaching {
+		metrics.confirmed = make(map[string]float64)
+	}
+	if o.metricResend {
+		metrics.absoluteValues = make(map[string]float64)
+		client.health.addOnHealthy(metrics.resendAbsoluteValues)
 	}
 
-	metrics.sendingLoopWg.Add(1)
-	go metrics.sendingLoop()
+	if !metrics.sentByDispatcher {
+		metrics.sendingLoopWg.Add(1)
+		go metrics.sendingLoop()
+	}
 	go metrics.dispatch()
 
 	return metrics
@@ -64,13 +206,47 @@ func (m *httpMetrics) dispatch() {
 	defer close(m.stoppedChan)
 
 	accumulators := make(map[string]chan metricEntry)
-	for entry := range m.dispatchChan {
-		if _, ok := accumulators[entry.Name]; !ok {
-			accumulators[entry.Name] = make(chan metricEntry)
-			m.accumulatorsWg.Add(1)
-			go m.accumulate(entry.Name, accumulators[entry.Name])
+LOOP:
+	for {
+		select {
+		case entry, ok := <-m.dispatchChan:
+			if !ok {
+				break LOOP
+			}
+			if m.sentByDispatcher {
+				m.send(entry)
+				continue
+			}
+			if m.immediate {
+				m.sendingAccumulatedChan <- entry
+				continue
+			}
+			if _, ok := accumulators[entry.Name]; !ok {
+				if m.maxNames > 0 && len(accumulators) >= m.maxNames {
+					m.internalLogger.ErrorF("Metric %s dropped: limit of %d metric names reached", entry.Name, m.maxNames)
+					continue
+				}
+				if m.maxAccumulators > 0 && len(accumulators) >= m.maxAccumulators {
+					// the metric has no accumulator, so nothing of it is pending and the order is kept;
+					// the sending loop doesn't wait for the dispatcher, so this doesn't deadlock
+					m.sendingAccumulatedChan <- entry
+					continue
+				}
+				accumulators[entry.Name] = make(chan metricEntry)
+				m.accumulatorsWg.Add(1)
+				go m.accumulate(entry.Name, accumulators[entry.Name])
+			}
+			accumulators[entry.Name] <- entry
+
+		case eviction := <-m.evictionChan:
+			// the accumulator is idle, so it's safe to stop it,
+			// it will be recreated when the metric name appears again
+			if c, ok := accumulators[eviction.name]; ok && c == eviction.c {
+				m.internalLogger.VerboseF("Evicting idle accumulator of metric %s", eviction.name)
+				close(c)
+				delete(accumulators, eviction.name)
+			}
 		}
-		accumulators[entry.Name] <- entry
 	}
 
 	// close all accumulators
@@ -85,15 +261,42 @@ func (m *httpMetrics) dispatch() {
 	close(m.sendingAccumulatedChan)
 	// wait for the sending loop to finish: all metrics are sent
 	m.sendingLoopWg.Wait()
+	m.cancelSends()
 }
 
 func (m *httpMetrics) sendingLoop() {
 	defer m.sendingLoopWg.Done()
 
 	for entry := range m.sendingAccumulatedChan {
-		if err := m.client.sendData("/metrics", http.MethodPut, entry); err != nil {
-			m.internalLogger.ErrorF("Failed to send metric: %v", err)
+		m.send(entry)
+	}
+}
+
+// send sends the metric to the server, respecting the rate limit.
+func (m *httpMetrics) send(entry metricEntry) {
+	if m.isConfirmed(entry) {
+		m.internalLogger.VerboseF("Metric %s not sent: value %v already confirmed", entry.Name, entry.Value)
+		return
+	}
+	m.rateLimiter.wait(m.stoppingChan)
+	err := m.client.sendData(m.sendCtx, "/metrics", m.method, entry)
+	for err != nil && isTransientSendError(err) && m.retrySends.Load() {
+		m.internalLogger.VerboseF("Retrying to send metric %s: %v", entry.Name, err)
+		select {
+		case <-m.sendCtx.Done():
+		case <-time.After(m.shutdownRetryInterval):
+		}
+		if m.sendCtx.Err() != nil {
+			break
 		}
+		err = m.client.sendData(m.sendCtx, "/metrics", m.method, entry)
+	}
+	m.confirm(entry, err)
+	if errors.Is(err, ErrPayloadTooLarge) {
+		m.client.recordDrop("/metrics")
+		m.internalLogger.ErrorF("Metric %s dropped: %v", entry.Name, err)
+	} else if err != nil {
+		m.internalLogger.ErrorF("Failed to send metric: %v", err)
 	}
 }
 
@@ -108,16 +311,155 @@ func (m *httpMetrics) accumulate(name string, c <-chan metricEntry) {
 		// non-nil value enables sending accumulated metric
 		outputChan       chan<- metricEntry
 		accumulatedEntry metricEntry
+		// there is accumulated metric in accumulatedEntry
+		accumulating bool
+		// coalesced is the number of relative changes accumulated in accumulatedEntry
+		coalesced int
+		// closed coalesce windows waiting for sending, oldest first
+		windows []metricEntry
+
+		// fires when the coalesce window of the accumulated metric is over
+		windowTimer *time.Timer
+		// ticks when the accumulated metric should be sent in the snapshot mode
+		snapshotTicker *time.Ticker
+
+		// fires when the held set should be sent in the atomic batch mode
+		holdTimer *time.Timer
+		// the accumulated metric starts with a set, which is held to be merged with following changes
+		holding bool
+
+		// fires when there is no pending metric and nothing received for m.idleTimeout
+		idleTimer *time.Timer
+		// set to m.evictionChan when the accumulator is idle
+		// non-nil value enables requesting eviction
+		evictionChan chan<- accumulatorEviction
+		// eviction is requested, the dispatcher will close the input channel
+		evicting bool
+
+		// lastSet is the last value the metric was set to, valid when hasLastSet,
+		// used to suppress duplicates in the dedup mode
+		lastSet     float64
+		lastSetTime time.Time
+		hasLastSet  bool
+
+		// carry is the remainder of rounding sent changes, added to the following change
+		carry float64
 	)
-	accumulatedEntry.Name = name
-	accumulatedEntry.Operation = metricOperationMutate
+	// round returns the entry with the rounded value and the remainder to be carried,
+	// the value of a set is absolute, so nothing is carried
+	round := func(entry metricEntry) (metricEntry, float64) {
+		if m.rounding == nil {
+			return entry, 0
+		}
+		if entry.Operation == metricOperationSet {
+			entry.Value = m.rounding(entry.Value)
+			return entry, 0
+		}
+		total := entry.Value + carry
+		entry.Value = m.rounding(total)
+		return entry, total - entry.Value
+	}
+	resetAccumulated := func() {
+		accumulatedEntry = metricEntry{Name: name, Operation: metricOperationMutate}
+		accumulating = false
+		coalesced = 0
+		holding = false
+	}
+	resetAccumulated()
+	// closeWindow queues the accumulated metric for sending,
+	// so the following metrics are accumulated separately
+	closeWindow := func() {
+		if len(windows) >= maxPendingWindows {
+			// sending is stalled, so the newest window absorbs the metric rather than windows piling up
+			windows[len(windows)-1] = mergeWindows(windows[len(windows)-1], accumulatedEntry)
+		} else {
+			windows = append(windows, accumulatedEntry)
+		}
+		resetAccumulated()
+	}
+
+	if m.idleTimeout > 0 {
+		idleTimer = time.NewTimer(m.idleTimeout)
+		defer idleTimer.Stop()
+	}
+	if m.snapshotInterval > 0 {
+		snapshotTicker = time.NewTicker(m.snapshotInterval)
+		defer snapshotTicker.Stop()
+	} else if m.coalesceWindow > 0 {
+		windowTimer = time.NewTimer(m.coalesceWindow)
+		windowTimer.Stop()
+		defer windowTimer.Stop()
+	}
+	// in the snapshot mode, sets are merged with following changes until the tick anyway
+	if m.atomicBatchWindow > 0 && snapshotTicker == nil {
+		holdTimer = time.NewTimer(m.atomicBatchWindow)
+		holdTimer.Stop()
+		defer holdTimer.Stop()
+	}
 
 LOOP:
 	for {
+		// idle timer is enabled only when there is no pending metric
+		var idleChan <-chan time.Time
+		if idleTimer != nil && outputChan == nil && !accumulating && evictionChan == nil && !evicting {
+			idleChan = idleTimer.C
+		}
+		// window timer is enabled only when there is accumulated metric
+		var windowChan <-chan time.Time
+		if windowTimer != nil && accumulating {
+			windowChan = windowTimer.C
+		}
+		var snapshotChan <-chan time.Time
+		if snapshotTicker != nil {
+			snapshotChan = snapshotTicker.C
+		}
+		// hold timer is enabled only when the accumulated metric is held
+		var holdChan <-chan time.Time
+		if holding {
+			holdChan = holdTimer.C
+		}
+		// the oldest window is sent first
+		nextEntry := accumulatedEntry
+		if len(windows) > 0 {
+			nextEntry = windows[0]
+		}
+		nextEntry, nextCarry := round(nextEntry)
+		// closed windows are sent while the accumulated metric is held
+		sendChan := outputChan
+		if holding && len(windows) == 0 {
+			sendChan = nil
+		}
+
 		select {
+		case <-idleChan:
+			evictionChan = m.evictionChan
+
+		case evictionChan <- accumulatorEviction{name: name, c: c}:
+			evictionChan = nil
+			evicting = true
+
+		case <-windowChan:
+			closeWindow()
+
+		case <-snapshotChan:
+			if accumulating {
+				closeWindow()
+				outputChan = m.sendingAccumulatedChan
+			}
+
+		case <-holdChan:
+			holding = false
+
 		case entry, ok := <-c:
 			// input channel is closed
 			if !ok {
+				// the held metric is sent without waiting for the hold to end
+				holding = false
+				// in the snapshot mode, the accumulated metric is sent without waiting for the tick
+				if snapshotTicker != nil && accumulating {
+					closeWindow()
+					outputChan = m.sendingAccumulatedChan
+				}
 				// there is no accumulated metric, we can stop the accumulator
 				if outputChan == nil {
 					break LOOP
@@ -128,58 +470,263 @@ LOOP:
 				// don't try to send nor accumulate zero value
 				continue
 			}
-			// try send immediately only if there is no accumulated metric
-			if outputChan == nil {
+			// metric received, the accumulator is not idle anymore
+			evictionChan = nil
+			if idleTimer != nil {
+				idleTimer.Reset(m.idleTimeout)
+			}
+			if m.dedup {
+				if entry.Operation == metricOperationSet && hasLastSet && entry.Value == lastSet &&
+					(m.dedupWindow == 0 || m.now().Sub(lastSetTime) < m.dedupWindow) {
+					continue
+				}
+				// the value after a change is unknown, so the following set is always sent
+				lastSet, lastSetTime, hasLastSet = entry.Value, m.now(), entry.Operation == metricOperationSet
+			}
+			// in the atomic batch mode, a set is held, so it's sent along with following changes,
+			// rather than the server seeing the set before the changes land
+			if holdTimer != nil && entry.Operation == metricOperationSet && !holding {
+				// previously accumulated metric is sent on its own, before the set
+				if accumulating {
+					closeWindow()
+				}
+				holding = true
+				holdTimer.Reset(m.atomicBatchWindow)
+			}
+			// try send immediately only if there is no accumulated metric,
+			// in the snapshot mode, metrics are sent only on the tick
+			if outputChan == nil && snapshotTicker == nil && !holding {
+				rounded, remainder := round(entry)
 				select {
-				case m.sendingAccumulatedChan <- entry:
+				case m.sendingAccumulatedChan <- rounded:
+					carry = remainder
 					continue
 				default:
 				}
 			}
+			// start the coalesce window with the first accumulated metric
+			if windowTimer != nil && !accumulating {
+				windowTimer.Reset(m.coalesceWindow)
+			}
 			// accumulate metric
+			accumulating = true
 			accumulatedEntry.Timestamp = entry.Timestamp
+			accumulatedEntry.Unit = entry.Unit
 			switch entry.Operation {
 			case metricOperationSet:
 				accumulatedEntry.Value = entry.Value
 				accumulatedEntry.Operation = metricOperationSet
 			case metricOperationMutate:
 				accumulatedEntry.Value += entry.Value
+				coalesced++
+			}
+			// large or many accumulated changes are queued ahead of the following changes
+			if m.reachedFlushThreshold(accumulatedEntry) || (m.flushCount > 0 && coalesced >= m.flushCount) {
+				closeWindow()
+				outputChan = m.sendingAccumulatedChan
+				continue
 			}
 			// enable sending accumulated metric
-			if outputChan == nil {
+			if outputChan == nil && snapshotTicker == nil {
 				outputChan = m.sendingAccumulatedChan
 			}
 
-		case outputChan <- accumulatedEntry:
-			m.internalLogger.VerboseF("Accumulated metrics sent: %#v", accumulatedEntry)
+		case sendChan <- nextEntry:
+			carry = nextCarry
+			m.internalLogger.VerboseF("Accumulated metrics sent: %#v", nextEntry)
+			if len(windows) > 0 {
+				windows = windows[1:]
+			} else {
+				resetAccumulated()
+				if windowTimer != nil {
+					windowTimer.Stop()
+				}
+			}
+			// keep sending while there is pending metric,
+			// in the snapshot mode, the accumulated metric waits for the next tick
+			if len(windows) > 0 || (accumulating && snapshotTicker == nil) {
+				continue
+			}
 			outputChan = nil
-			accumulatedEntry.Value = 0
-			accumulatedEntry.Operation = metricOperationMutate
 			if c == nil {
 				break LOOP
 			}
+			if idleTimer != nil {
+				idleTimer.Reset(m.idleTimeout)
+			}
 
 		}
 	}
 }
 
+// mergeWindows returns a single metric with the same effect as the earlier metric followed by the later one.
+func mergeWindows(earlier, later metricEntry) metricEntry {
+	if later.Operation == metricOperationSet {
+		// the set overrides whatever was before it
+		return later
+	}
+	merged := later
+	merged.Operation = earlier.Operation
+	merged.Value = earlier.Value + later.Value
+	return merged
+}
+
+// isConfirmed reports whether the metric is set to the value already acknowledged by the server.
+func (m *httpMetrics) isConfirmed(entry metricEntry) bool {
+	if m.confirmed == nil || entry.Operation != metricOperationSet {
+		return false
+	}
+	value, ok := m.confirmed[entry.Name]
+	return ok && value == entry.Value
+}
+
+// confirm updates the acknowledged value of the metric after it's sent with the error.
+//
+// After a failure, the value on the server is unknown, so the following set is sent.
+func (m *httpMetrics) confirm(entry metricEntry, err error) {
+	if m.confirmed == nil {
+		return
+	}
+	value, ok := m.confirmed[entry.Name]
+	switch {
+	case err != nil:
+		delete(m.confirmed, entry.Name)
+	case entry.Operation == metricOperationSet:
+		m.confirmed[entry.Name] = entry.Value
+	case ok:
+		m.confirmed[entry.Name] = value + entry.Value
+	}
+}
+
+// reachedFlushThreshold reports whether accumulated relative changes reached the flush threshold.
+func (m *httpMetrics) reachedFlushThreshold(entry metricEntry) bool {
+	return m.flushThreshold > 0 && entry.Operation == metricOperationMutate && math.Abs(entry.Value) >= m.flushThreshold
+}
+
 func (m *httpMetrics) sendOperation(name string, value float64, operation string) {
+	if _, ok := knownMetricOperations[operation]; !ok {
+		m.client.recordDrop("/metrics")
+		m.internalLogger.ErrorF("Metric %s rejected: unknown operation %q", name, operation)
+		return
+	}
+	if first, mixed := m.operations.check(name, operation); mixed {
+		m.internalLogger.WarnF("Metric %s uses %q operation, but it was first used with %q operation", name, operation, first)
+	}
+	m.trackAbsoluteValue(name, value, operation)
+	m.sendEntry(name, value, operation)
+}
+
+// trackAbsoluteValue updates the last known value of the metric, if it was ever set.
+//
+// Metrics which were only mutated are not tracked, as their absolute value is not known.
+func (m *httpMetrics) trackAbsoluteValue(name string, value float64, operation string) {
+	if m.absoluteValues == nil {
+		return
+	}
+
+	m.absoluteValuesMu.Lock()
+	defer m.absoluteValuesMu.Unlock()
+
+	if operation == metricOperationSet {
+		m.absoluteValues[name] = value
+	} else if current, ok := m.absoluteValues[name]; ok {
+		m.absoluteValues[name] = current + value
+	}
+}
+
+// resendAbsoluteValues sets metrics with known absolute values again,
+// so values which failed to be sent while the remote was unhealthy are corrected.
+func (m *httpMetrics) resendAbsoluteValues() {
+	m.absoluteValuesMu.Lock()
+	values := maps.Clone(m.absoluteValues)
+	m.absoluteValuesMu.Unlock()
+
+	m.internalLogger.VerboseF("Resending %d metrics after the remote became healthy", len(values))
+	for name, value := range values {
+		m.sendEntry(name, value, metricOperationSet)
+	}
+}
+
+// sendEntry dispatches the operation to the accumulator of the metric.
+//
+// The name is prefixed here, so accumulators are keyed by names sent to the server,
+// while units, types and absolute values are tracked by names as given.
+func (m *httpMetrics) sendEntry(name string, value float64, operation string) {
 	entry := metricEntry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
-		Name:      name,
+		Timestamp: formatWireTimestamp(m.now()),
+		Name:      m.namePrefix + name,
 		Value:     value,
 		Operation: operation,
+		Unit:      m.units.unit(name),
 	}
 
-	m.dispatchChanMu.Lock()
-	defer m.dispatchChanMu.Unlock()
+	// read lock is enough, because the channel is closed only under write lock,
+	// so concurrent senders don't wait for each other
+	m.dispatchChanMu.RLock()
+	defer m.dispatchChanMu.RUnlock()
 
 	if m.stopping {
 		m.internalLogger.VerboseF("Failed to send metric: %v", ErrAlreadyClosed)
 		return
 	}
 
-	m.dispatchChan <- entry
+	select {
+	case m.dispatchChan <- entry:
+		// Metric sent to channel
+	default:
+		// Channel is full
+		if m.overflowPolicy == OverflowPolicyDrop {
+			m.client.recordDrop("/metrics")
+			m.internalLogger.ErrorF("Metric %s dropped due to channel overflow", name)
+			return
+		}
+		// Block until there's space in the channel
+		m.dispatchChan <- entry
+	}
+}
+
+// newMetricOperations creates a new metricOperations instance.
+func newMetricOperations() *metricOperations {
+	return &metricOperations{
+		first: make(map[string]string),
+		mixed: make(map[string]struct{}),
+	}
+}
+
+// declare sets the expected operation of the metric, replacing the recorded one.
+func (o *metricOperations) declare(name, operation string) {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.first[name] = operation
+}
+
+// check records the operation of the metric and reports whether the metric mixes operations.
+// The first operation of the metric is returned, mixing is reported only once per name.
+func (o *metricOperations) check(name, operation string) (first string, mixed bool) {
+	if o == nil {
+		return "", false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	first, ok := o.first[name]
+	if !ok {
+		o.first[name] = operation
+		return operation, false
+	}
+	if first == operation {
+		return first, false
+	}
+	if _, reported := o.mixed[name]; reported {
+		return first, false
+	}
+	o.mixed[name] = struct{}{}
+	return first, true
 }
 
 // Set sets a metric to an absolute value.
@@ -193,7 +740,9 @@ func (m *httpMetrics) Mutate(name string, value float64) {
 }
 
 // stopDispatcher stops the dispatcher and starts closing accumulators.
-func (m *httpMetrics) stopDispatcher() (err error) {
+//
+// With retrySends, failed sends of pending metrics are retried until sendCtx is canceled.
+func (m *httpMetrics) stopDispatcher(retrySends bool) (err error) {
 	m.dispatchChanMu.Lock()
 	defer m.dispatchChanMu.Unlock()
 
@@ -202,6 +751,8 @@ func (m *httpMetrics) stopDispatcher() (err error) {
 	}
 
 	m.stopping = true
+	m.retrySends.Store(retrySends)
+	close(m.stoppingChan)
 	close(m.dispatchChan)
 
 	return nil
@@ -211,7 +762,7 @@ func (m *httpMetrics) stopDispatcher() (err error) {
 //
 // Close doesn't wait for pending metrics to be sent.
 func (m *httpMetrics) Close() error {
-	return m.stopDispatcher()
+	return m.stopDispatcher(false)
 }
 
 // Shutdown stops the background worker and closes the metrics.
@@ -219,9 +770,12 @@ func (m *httpMetrics) Close() error {
 // Shutdown waits for all pending metrics to be sent.
 func (m *httpMetrics) Shutdown(ctx context.Context) error {
 	m.internalLogger.VerboseF("Shutting down metrics")
-	if err := m.stopDispatcher(); err != nil {
+	if err := m.stopDispatcher(m.shutdownRetryInterval > 0); err != nil {
 		return err
 	}
+	// metrics not sent when the context is done are given up, including the in-flight request
+	stop := context.AfterFunc(ctx, m.cancelSends)
+	defer stop()
 
 	// wait for the process goroutine to finish
 	select {
@@ -0,0 +1,107 @@
+package logdash
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPMetricsOperationValidation(t *testing.T) {
+	t.Run("should reject unknown operation before sending", func(t *testing.T) {
+		// GIVEN
+		var requests atomic.Int64
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			requests.Add(1)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		o := &options{
+			host:          httpServer.URL,
+			apiKey:        "test-api-key",
+			metricsMethod: http.MethodPut,
+			timeSource:    time.Now,
+
+			metricsBufferSize:     DefaultBufferSize,
+			metricsOverflowPolicy: OverflowPolicyBlock,
+		}
+		sink := NewMemorySink()
+		internalLogger := newLogger(&sinkLogger{sink: sink})
+		metrics := newHTTPMetrics(o, newHTTPClient(o, internalLogger), internalLogger)
+
+		// WHEN
+		metrics.sendOperation("test-metric", 1, "delete")
+		metrics.sendOperation("test-metric", 2, metricOperationSet)
+		err := metrics.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), requests.Load())
+		var errors []string
+		for _, entry := range sink.Entries() {
+			if entry.Level == LevelError {
+				errors = append(errors, entry.Message)
+			}
+		}
+		assert.Equal(t, []string{`Metric test-metric rejected: unknown operation "delete"`}, errors)
+	})
+}
+
+func TestHTTPMetricsPendingWindows(t *testing.T) {
+	t.Run("should merge windows above the limit while sending is stalled", func(t *testing.T) {
+		// GIVEN
+		release := make(chan struct{})
+		var mu sync.Mutex
+		var values []float64
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			<-release
+			var entry metricEntry
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&entry))
+			mu.Lock()
+			values = append(values, entry.Value)
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		o := &options{
+			host:          httpServer.URL,
+			apiKey:        "test-api-key",
+			metricsMethod: http.MethodPut,
+			timeSource:    time.Now,
+			// every change closes its own window
+			metricFlushCount: 1,
+
+			metricsBufferSize:     DefaultBufferSize,
+			metricsOverflowPolicy: OverflowPolicyBlock,
+		}
+		internalLogger := newLogger(&sinkLogger{sink: NewMemorySink()})
+		metrics := newHTTPMetrics(o, newHTTPClient(o, internalLogger), internalLogger)
+
+		// WHEN
+		const changes = 3 * maxPendingWindows
+		for range changes {
+			metrics.sendOperation("test-metric", 1, metricOperationMutate)
+		}
+		close(release)
+		err := metrics.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(values), maxPendingWindows+2)
+		total := 0.0
+		for _, value := range values {
+			total += value
+		}
+		assert.Equal(t, float64(changes), total)
+	})
+}
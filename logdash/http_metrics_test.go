@@ -0,0 +1,1454 @@
+package logdash_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashMetricsMaxNames(t *testing.T) {
+	t.Run("should keep goroutine count bounded when limit of metric names is reached", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		const maxNames = 10
+		goroutinesBefore := runtime.NumGoroutine()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMaxMetricNames(maxNames),
+		)
+
+		// WHEN
+		for i := range 1000 {
+			ld.Metrics.Set(fmt.Sprintf("metric-%d", i), float64(i))
+		}
+		goroutinesAfter := runtime.NumGoroutine()
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		// accumulators, background workers and HTTP connections
+		assert.Less(t, goroutinesAfter-goroutinesBefore, maxNames+20)
+
+		names := make(map[string]struct{})
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			names[body["name"].(string)] = struct{}{}
+		}
+		assert.Len(t, names, maxNames)
+		for i := range maxNames {
+			assert.Contains(t, names, fmt.Sprintf("metric-%d", i))
+		}
+	})
+}
+
+func TestLogdashMaxConcurrentMetricAccumulators(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []logdash.Option
+	}{
+		{
+			name: "should send final value of every name exactly once when limit is saturated",
+		},
+		{
+			name: "should send final value of every name exactly once when accumulators are evicted",
+			opts: []logdash.Option{logdash.WithMetricAccumulatorIdleTimeout(time.Millisecond)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			const (
+				maxAccumulators = 8
+				goroutines      = 10
+				namesPerRoutine = 30
+				updates         = 3
+			)
+			goroutinesBefore := runtime.NumGoroutine()
+
+			ld := logdash.New(append([]logdash.Option{
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithMaxConcurrentMetricAccumulators(maxAccumulators),
+				logdash.WithMetricsOverflowPolicy(logdash.OverflowPolicyBlock),
+			}, tc.opts...)...)
+
+			// WHEN
+			var wg sync.WaitGroup
+			var maxGoroutines atomic.Int64
+			for g := range goroutines {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range namesPerRoutine {
+						for u := range updates {
+							ld.Metrics.Set(fmt.Sprintf("metric-%d-%d", g, i), float64(u+1))
+						}
+						current := int64(runtime.NumGoroutine())
+						for {
+							seen := maxGoroutines.Load()
+							if current <= seen || maxGoroutines.CompareAndSwap(seen, current) {
+								break
+							}
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			// accumulators, logging goroutines, background workers and HTTP connections
+			assert.Less(t, maxGoroutines.Load()-int64(goroutinesBefore), int64(maxAccumulators+goroutines+20))
+
+			finalValues := make(map[string]int)
+			lastValues := make(map[string]float64)
+			for _, r := range requestsCollector.requests {
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(r.body, &body))
+				name := body["name"].(string)
+				lastValues[name] = body["value"].(float64)
+				if body["value"] == float64(updates) {
+					finalValues[name]++
+				}
+			}
+			assert.Len(t, finalValues, goroutines*namesPerRoutine)
+			for name, count := range finalValues {
+				assert.Equal(t, 1, count, name)
+				assert.Equal(t, float64(updates), lastValues[name], name)
+			}
+		})
+	}
+}
+
+func TestLogdashMetricsAccumulatorIdleTimeout(t *testing.T) {
+	t.Run("should stop idle accumulators and recreate them when metric appears again", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		const names = 50
+		goroutinesBefore := runtime.NumGoroutine()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricAccumulatorIdleTimeout(20*time.Millisecond),
+			// unbuffered dispatch starts the accumulator before Set returns
+			logdash.WithMetricsBufferSize(0),
+		)
+
+		// WHEN
+		for i := range names {
+			ld.Metrics.Set(fmt.Sprintf("metric-%d", i), float64(i))
+		}
+		assert.GreaterOrEqual(t, runtime.NumGoroutine()-goroutinesBefore, names)
+
+		// THEN
+		// only background workers and HTTP connections are left
+		assert.Eventually(t, func() bool {
+			return runtime.NumGoroutine()-goroutinesBefore < 10
+		}, time.Second, 5*time.Millisecond)
+
+		// WHEN
+		ld.Metrics.Set("metric-0", 100)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, names+1)
+		var lastBody map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[names].body, &lastBody))
+		assert.Equal(t, "metric-0", lastBody["name"])
+		assert.Equal(t, float64(100), lastBody["value"])
+	})
+}
+
+func TestLogdashMetricsNonBlockingDispatch(t *testing.T) {
+	t.Run("should return promptly from Mutate when sending loop is stalled", func(t *testing.T) {
+		// GIVEN
+		release := make(chan struct{})
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			<-release
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+		)
+
+		// WHEN
+		start := time.Now()
+		for i := range 1000 {
+			ld.Metrics.Mutate(fmt.Sprintf("metric-%d", i%10), 1)
+		}
+		elapsed := time.Since(start)
+		close(release)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Less(t, elapsed, 500*time.Millisecond)
+	})
+}
+
+func TestLogdashMetricCoalesceWindow(t *testing.T) {
+	t.Run("should send accumulated metric once per window when server is stalled", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		release := make(chan struct{})
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			<-release
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricCoalesceWindow(20*time.Millisecond),
+		)
+
+		// WHEN
+		const mutations = 40
+		for range mutations {
+			ld.Metrics.Mutate("test-metric", 1)
+			time.Sleep(5 * time.Millisecond)
+		}
+		close(release)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		// the first metric is sent immediately, following ones are coalesced per window
+		assert.Greater(t, len(requestsCollector.requests), 3)
+		var total float64
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			assert.Equal(t, "change", body["operation"])
+			assert.Less(t, body["value"].(float64), float64(mutations-1))
+			total += body["value"].(float64)
+		}
+		assert.Equal(t, float64(mutations), total)
+	})
+}
+
+func TestLogdashMetricFlushThreshold(t *testing.T) {
+	t.Run("should queue accumulated changes reaching threshold when server is stalled", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		received := make(chan struct{}, 1)
+		release := make(chan struct{})
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+			<-release
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricFlushThreshold(10),
+		)
+		ld.Metrics.Mutate("test-metric", 1)
+		<-received
+
+		// WHEN
+		for range 25 {
+			ld.Metrics.Mutate("test-metric", 1)
+		}
+		close(release)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		var values []float64
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			values = append(values, body["value"].(float64))
+		}
+		assert.Equal(t, []float64{1, 10, 10, 5}, values)
+	})
+
+	t.Run("should send changes reaching threshold without waiting for snapshot tick", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricSnapshotInterval(time.Hour),
+			logdash.WithMetricFlushThreshold(100),
+		)
+		defer ld.Shutdown(context.Background())
+
+		// WHEN
+		ld.Metrics.Mutate("test-metric", 60)
+		ld.Metrics.Mutate("test-metric", -200)
+
+		// THEN
+		assert.Eventually(t, func() bool {
+			requestsCollector.mu.Lock()
+			defer requestsCollector.mu.Unlock()
+			return len(requestsCollector.requests) == 1
+		}, time.Second, 10*time.Millisecond)
+		requestsCollector.mu.Lock()
+		defer requestsCollector.mu.Unlock()
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, float64(-140), body["value"])
+	})
+}
+
+func TestLogdashMetricFlushAfterCount(t *testing.T) {
+	t.Run("should queue accumulated changes reaching count when server is stalled", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		received := make(chan struct{}, 1)
+		release := make(chan struct{})
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+			<-release
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricFlushAfterCount(100),
+		)
+		ld.Metrics.Mutate("test-metric", 1)
+		<-received
+
+		// WHEN
+		for range 250 {
+			ld.Metrics.Mutate("test-metric", 1)
+		}
+		close(release)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		var values []float64
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			values = append(values, body["value"].(float64))
+		}
+		// changes still dispatched when the server recovers may be sent in smaller values
+		if assert.GreaterOrEqual(t, len(values), 4) {
+			assert.Equal(t, 1.0, values[0])
+			var sum float64
+			for _, value := range values[1:] {
+				assert.LessOrEqual(t, value, 100.0)
+				sum += value
+			}
+			assert.Equal(t, 250.0, sum)
+		}
+	})
+}
+
+func TestLogdashResponseCaching(t *testing.T) {
+	type step struct {
+		mutate bool
+		value  float64
+	}
+
+	testCases := []struct {
+		name string
+		// statuses of consecutive responses, following responses are OK
+		statuses       []int
+		steps          []step
+		expectedValues []float64
+	}{
+		{
+			name:           "should suppress set value only after it's confirmed",
+			statuses:       []int{http.StatusInternalServerError},
+			steps:          []step{{value: 5}, {value: 5}, {value: 5}, {value: 5}},
+			expectedValues: []float64{5, 5},
+		},
+		{
+			name:           "should send set value when it differs from confirmed one",
+			steps:          []step{{value: 5}, {value: 6}, {value: 5}},
+			expectedValues: []float64{5, 6, 5},
+		},
+		{
+			name:           "should take confirmed changes into account",
+			steps:          []step{{value: 5}, {mutate: true, value: 1}, {value: 6}, {value: 5}},
+			expectedValues: []float64{5, 1, 5},
+		},
+		{
+			name:           "should send set value following failed change",
+			statuses:       []int{http.StatusOK, http.StatusInternalServerError},
+			steps:          []step{{value: 5}, {mutate: true, value: 1}, {value: 5}},
+			expectedValues: []float64{5, 1, 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+			var attempts atomic.Int64
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				status := http.StatusOK
+				if attempt := int(attempts.Add(1)); attempt <= len(tc.statuses) {
+					status = tc.statuses[attempt-1]
+				}
+				w.WriteHeader(status)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithResponseCaching(),
+			)
+
+			// WHEN
+			for _, step := range tc.steps {
+				if step.mutate {
+					ld.Metrics.Mutate("errors", step.value)
+				} else {
+					ld.Metrics.Set("errors", step.value)
+				}
+				// let the value be sent, so it isn't accumulated with the following ones
+				time.Sleep(10 * time.Millisecond)
+			}
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			var values []float64
+			for _, r := range requestsCollector.requests {
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(r.body, &body))
+				values = append(values, body["value"].(float64))
+			}
+			assert.Equal(t, tc.expectedValues, values)
+		})
+	}
+}
+
+func TestLogdashMetricDedup(t *testing.T) {
+	type step struct {
+		mutate bool
+		value  float64
+		// advance of the time source before the step
+		advance time.Duration
+	}
+
+	testCases := []struct {
+		name           string
+		opts           []logdash.Option
+		steps          []step
+		expectedValues []float64
+	}{
+		{
+			name:           "should send repeatedly set value only once",
+			steps:          []step{{value: 0}, {value: 0}, {value: 0}, {value: 0}, {value: 0}},
+			expectedValues: []float64{0},
+		},
+		{
+			name:           "should send set value when it changes",
+			steps:          []step{{value: 0}, {value: 1}, {value: 1}, {value: 0}},
+			expectedValues: []float64{0, 1, 0},
+		},
+		{
+			name:           "should send set value following a change",
+			steps:          []step{{value: 0}, {mutate: true, value: 1}, {value: 0}},
+			expectedValues: []float64{0, 1, 0},
+		},
+		{
+			name:           "should suppress unchanged value within window",
+			opts:           []logdash.Option{logdash.WithMetricDedupWindow(time.Minute)},
+			steps:          []step{{value: 0}, {value: 0, advance: 30 * time.Second}},
+			expectedValues: []float64{0},
+		},
+		{
+			name:           "should resend unchanged value after window",
+			opts:           []logdash.Option{logdash.WithMetricDedupWindow(time.Minute)},
+			steps:          []step{{value: 0}, {value: 0}, {value: 0, advance: 2 * time.Minute}},
+			expectedValues: []float64{0, 0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			start := time.Now()
+			var elapsed atomic.Int64
+			ld := logdash.New(append([]logdash.Option{
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithMetricDedup(),
+				logdash.WithTimeSource(func() time.Time { return start.Add(time.Duration(elapsed.Load())) }),
+			}, tc.opts...)...)
+
+			// WHEN
+			for _, step := range tc.steps {
+				elapsed.Add(int64(step.advance))
+				if step.mutate {
+					ld.Metrics.Mutate("errors", step.value)
+				} else {
+					ld.Metrics.Set("errors", step.value)
+				}
+				// let the value be sent, so it isn't accumulated with the following ones
+				time.Sleep(10 * time.Millisecond)
+			}
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			var values []float64
+			for _, r := range requestsCollector.requests {
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(r.body, &body))
+				values = append(values, body["value"].(float64))
+			}
+			assert.Equal(t, tc.expectedValues, values)
+		})
+	}
+}
+
+func TestLogdashMetricRounding(t *testing.T) {
+	t.Run("should send integer changes totaling exactly the sum of fractional changes", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithIntegerMetrics(),
+		)
+
+		// WHEN
+		for range 1000 {
+			ld.Metrics.Mutate("distance", 0.1)
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		var total float64
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			value := body["value"].(float64)
+			assert.Equal(t, math.Round(value), value)
+			total += value
+		}
+		assert.Equal(t, float64(100), total)
+	})
+
+	t.Run("should round set value with custom rounding", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricRounding(func(v float64) float64 {
+				return math.Round(v*100) / 100
+			}),
+		)
+
+		// WHEN
+		ld.Metrics.Set("ratio", 0.123456)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, 0.12, body["value"])
+	})
+}
+
+func TestLogdashMetricsRateLimit(t *testing.T) {
+	t.Run("should space out requests to the configured rate", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		const perSecond = 20
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricsRateLimit(perSecond),
+		)
+
+		// WHEN
+		const metrics = 10
+		for i := range metrics {
+			ld.Metrics.Set(fmt.Sprintf("metric-%d", i), 1)
+		}
+		time.Sleep(300 * time.Millisecond)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		requests := requestsCollector.requests
+		assert.Len(t, requests, metrics)
+		// only requests sent before shutdown are limited: 6 within 300ms at 20 per second
+		for i := 1; i < 6; i++ {
+			interval := requests[i].timeReceived.Sub(requests[i-1].timeReceived)
+			assert.Greater(t, interval, time.Second/perSecond*8/10)
+		}
+	})
+
+	t.Run("should flush pending metrics on shutdown without waiting for the limit", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricsRateLimit(1),
+		)
+
+		// WHEN
+		const metrics = 5
+		for i := range metrics {
+			ld.Metrics.Set(fmt.Sprintf("metric-%d", i), 1)
+		}
+		start := time.Now()
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Len(t, requestsCollector.requests, metrics)
+	})
+}
+
+func TestLogdashMetricSnapshotInterval(t *testing.T) {
+	t.Run("should send metric once per interval despite many updates", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		const interval = 50 * time.Millisecond
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricSnapshotInterval(interval),
+		)
+
+		// WHEN
+		const updates = 200
+		start := time.Now()
+		for i := range updates {
+			ld.Metrics.Set("connections", float64(i+1))
+			time.Sleep(time.Millisecond)
+		}
+		elapsed := time.Since(start)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		requests := requestsCollector.requests
+		// one snapshot per tick and the pending one on shutdown
+		assert.LessOrEqual(t, len(requests), int(elapsed/interval)+1)
+		assert.Greater(t, len(requests), 1)
+		for i := 1; i < len(requests)-1; i++ {
+			assert.Greater(t, requests[i].timeReceived.Sub(requests[i-1].timeReceived), interval*8/10)
+		}
+		var last map[string]any
+		assert.NoError(t, json.Unmarshal(requests[len(requests)-1].body, &last))
+		assert.Equal(t, "set", last["operation"])
+		assert.Equal(t, float64(updates), last["value"])
+	})
+
+	t.Run("should sum up changes within interval", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricSnapshotInterval(time.Hour),
+		)
+
+		// WHEN
+		for range 10 {
+			ld.Metrics.Mutate("requests", 1)
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, "change", body["operation"])
+		assert.Equal(t, float64(10), body["value"])
+	})
+}
+
+func TestLogdashMetricUnits(t *testing.T) {
+	t.Run("should send declared unit through accumulation", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		release := make(chan struct{})
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			<-release
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+		)
+		ld.ExtendedMetrics().DeclareUnit("payload", "bytes")
+
+		// WHEN
+		for range 10 {
+			ld.Metrics.Mutate("payload", 100)
+		}
+		ld.ExtendedMetrics().SetWithUnit("latency", 12, "ms")
+		close(release)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Greater(t, len(requestsCollector.requests), 2)
+		var total float64
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			switch body["name"] {
+			case "payload":
+				assert.Equal(t, "bytes", body["unit"])
+				total += body["value"].(float64)
+			case "latency":
+				assert.Equal(t, "ms", body["unit"])
+			}
+		}
+		assert.Equal(t, float64(1000), total)
+	})
+
+	t.Run("should keep the first unit and warn about conflicting unit", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithVerbose(),
+			)
+			ld.ExtendedMetrics().DeclareUnit("latency", "ms")
+			ld.ExtendedMetrics().SetWithUnit("latency", 1.5, "s")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.Contains(t, output, `Unit "s" of metric latency conflicts with declared unit "ms"`)
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, "ms", body["unit"])
+	})
+}
+
+func TestLogdashStrictMetricTypes(t *testing.T) {
+	t.Run("should warn once when set metric is mutated", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithStrictMetricTypes(),
+				logdash.WithVerbose(),
+			)
+			ld.Metrics.Set("connections", 10)
+			ld.Metrics.Mutate("connections", 1)
+			ld.Metrics.Mutate("connections", 1)
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.Equal(t, 1, strings.Count(output, `Metric connections uses "change" operation, but it was first used with "set" operation`))
+	})
+
+	t.Run("should not warn by default", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithVerbose(),
+			)
+			ld.Metrics.Set("connections", 10)
+			ld.Metrics.Mutate("connections", 1)
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.NotContains(t, output, "operation, but it was first used")
+	})
+}
+
+func TestLogdashMetricRegister(t *testing.T) {
+	t.Run("should set initial values and apply metadata to following operations", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		specs := []logdash.MetricSpec{
+			{Name: "requests", Type: logdash.MetricTypeCounter, Unit: "count"},
+			{Name: "connections", Type: logdash.MetricTypeGauge, Initial: 5},
+			{Name: "latency", Type: logdash.MetricTypeGauge, Unit: "ms", Initial: 1},
+		}
+
+		// WHEN
+		var snapshot map[string]float64
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithStrictMetricTypes(),
+				logdash.WithVerbose(),
+				logdash.WithMetricsBufferSize(0),
+			)
+			ld.ExtendedMetrics().Register(specs)
+			ld.Metrics.Mutate("requests", 2)
+			ld.ExtendedMetrics().Register(specs)
+			ld.Metrics.Set("latency", 12)
+			snapshot = ld.ExtendedMetrics().Snapshot()
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.NotContains(t, output, "operation, but it was first used")
+		assert.Equal(t, map[string]float64{"requests": 2, "connections": 5, "latency": 12}, snapshot)
+
+		first := make(map[string]map[string]any)
+		last := make(map[string]map[string]any)
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			name := body["name"].(string)
+			if _, ok := first[name]; !ok {
+				first[name] = body
+			}
+			last[name] = body
+		}
+		assert.Len(t, first, 3)
+		assert.Equal(t, "set", first["connections"]["operation"])
+		assert.Equal(t, float64(5), first["connections"]["value"])
+		assert.Nil(t, first["connections"]["unit"])
+		assert.Equal(t, "count", last["requests"]["unit"])
+		assert.Equal(t, "ms", last["latency"]["unit"])
+		assert.Equal(t, float64(12), last["latency"]["value"])
+	})
+}
+
+func TestLogdashImmediateMetrics(t *testing.T) {
+	testCases := []struct {
+		name             string
+		opts             []logdash.Option
+		expectedRequests func(mutations int, requests int) bool
+	}{
+		{
+			name: "should send every mutation as its own request",
+			opts: []logdash.Option{logdash.WithImmediateMetrics()},
+			expectedRequests: func(mutations int, requests int) bool {
+				return requests == mutations
+			},
+		},
+		{
+			name: "should accumulate mutations by default",
+			expectedRequests: func(mutations int, requests int) bool {
+				return requests < mutations
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+			release := make(chan struct{})
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				<-release
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			ld := logdash.New(append([]logdash.Option{
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithMetricsBufferSize(100),
+			}, tc.opts...)...)
+
+			// WHEN
+			const mutations = 20
+			for range mutations {
+				ld.Metrics.Mutate("requests", 1)
+			}
+			close(release)
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			requests := requestsCollector.requests
+			assert.True(t, tc.expectedRequests(mutations, len(requests)), "unexpected number of requests: %d", len(requests))
+			var total float64
+			for _, r := range requests {
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(r.body, &body))
+				total += body["value"].(float64)
+			}
+			assert.Equal(t, float64(mutations), total)
+		})
+	}
+}
+
+func TestLogdashMetricResend(t *testing.T) {
+	t.Run("should resend set metrics but not changes when remote recovers", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		var failing atomic.Bool
+		failing.Store(true)
+		var failures atomic.Int64
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			if failing.Load() {
+				failures.Add(1)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricResend(),
+			logdash.WithRemoteRecoveryThreshold(1),
+		)
+
+		// WHEN
+		ld.Metrics.Set("connections", 5)
+		ld.Metrics.Mutate("requests", 1)
+		ld.Metrics.Set("queue", 1)
+		assert.Eventually(t, func() bool {
+			return failures.Load() == int64(logdash.DefaultRemoteFailureThreshold)
+		}, time.Second, time.Millisecond)
+		failing.Store(false)
+		ld.Metrics.Mutate("requests", 2)
+		assert.Eventually(t, func() bool {
+			requestsCollector.mu.Lock()
+			defer requestsCollector.mu.Unlock()
+			return len(requestsCollector.requests) == 3
+		}, time.Second, time.Millisecond)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		sent := make(map[string][]map[string]any)
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			sent[body["name"].(string)] = append(sent[body["name"].(string)], body)
+		}
+		assert.Len(t, sent["requests"], 1)
+		assert.Equal(t, float64(2), sent["requests"][0]["value"])
+		assert.Len(t, sent["connections"], 1)
+		assert.Equal(t, "set", sent["connections"][0]["operation"])
+		assert.Equal(t, float64(5), sent["connections"][0]["value"])
+		assert.Len(t, sent["queue"], 1)
+		assert.Equal(t, float64(1), sent["queue"][0]["value"])
+	})
+}
+
+func TestLogdashMetricsShutdownContext(t *testing.T) {
+	t.Run("should cancel in-flight metric request when shutdown context is done", func(t *testing.T) {
+		// GIVEN
+		received := make(chan struct{}, 1)
+		canceled := make(chan time.Time, 1)
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			// the server notices the client went away only after the body is read
+			_, _ = io.Copy(io.Discard, r.Body)
+			received <- struct{}{}
+			select {
+			case <-r.Context().Done():
+				canceled <- time.Now()
+			case <-time.After(5 * time.Second):
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPTimeout(10*time.Second),
+		)
+		ld.Metrics.Set("users", 1)
+		<-received
+
+		// WHEN
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := ld.Shutdown(ctx)
+		elapsed := time.Since(start)
+
+		// THEN
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, elapsed, time.Second)
+		select {
+		case canceledAt := <-canceled:
+			assert.Less(t, canceledAt.Sub(start), time.Second)
+		case <-time.After(time.Second):
+			assert.Fail(t, "in-flight metric request not canceled")
+		}
+	})
+}
+
+func TestLogdashMetricSendRetryOnShutdown(t *testing.T) {
+	testCases := []struct {
+		name             string
+		opts             []logdash.Option
+		expectedRequests int
+		expectedValue    bool
+	}{
+		{
+			name:             "should give up failed send during shutdown by default",
+			expectedRequests: 1,
+		},
+		{
+			name:             "should retry failed send during shutdown until final value is delivered",
+			opts:             []logdash.Option{logdash.WithMetricSendRetryOnShutdown(10 * time.Millisecond)},
+			expectedRequests: 2,
+			expectedValue:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+			var attempts atomic.Int32
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				requestsCollector.add(t, r)
+				if attempts.Add(1) == 1 {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer httpServer.Close()
+
+			ld := logdash.New(append([]logdash.Option{
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				// the value is sent only on shutdown
+				logdash.WithMetricSnapshotInterval(time.Hour),
+			}, tc.opts...)...)
+			ld.Metrics.Set("users", 42)
+
+			// WHEN
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			err := ld.Shutdown(ctx)
+
+			// THEN
+			assert.NoError(t, err)
+			assert.Len(t, requestsCollector.requests, tc.expectedRequests)
+			if tc.expectedValue {
+				var body map[string]any
+				last := requestsCollector.requests[len(requestsCollector.requests)-1]
+				assert.NoError(t, json.Unmarshal(last.body, &body))
+				assert.Equal(t, "users", body["name"])
+				assert.Equal(t, 42.0, body["value"])
+			}
+		})
+	}
+
+	t.Run("should stop retrying when shutdown context is done", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricSnapshotInterval(time.Hour),
+			logdash.WithMetricSendRetryOnShutdown(10*time.Millisecond),
+		)
+		ld.Metrics.Set("users", 42)
+
+		// WHEN
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := ld.Shutdown(ctx)
+
+		// THEN
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("should give up permanent failure during shutdown without deadline", func(t *testing.T) {
+		// GIVEN
+		var requests atomic.Int32
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			requests.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricSnapshotInterval(time.Hour),
+			logdash.WithMetricSendRetryOnShutdown(10*time.Millisecond),
+		)
+		ld.Metrics.Set("users", 42)
+
+		// WHEN
+		done := make(chan error, 1)
+		go func() { done <- ld.Shutdown(context.Background()) }()
+
+		// THEN
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Shutdown kept retrying permanent failure")
+		}
+		assert.Equal(t, int32(1), requests.Load())
+	})
+}
+
+func TestLogdashMetricPrefix(t *testing.T) {
+	t.Run("should send metrics with prefixed names exactly once", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		var snapshot map[string]float64
+		captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithMetricPrefix("checkout."),
+				// metrics pass through the verbose wrapper
+				logdash.WithVerbose(),
+			)
+
+			// WHEN
+			ld.Metrics.Set("orders", 1)
+			ld.ExtendedMetrics().SetWithUnit("latency", 5, "ms")
+			snapshot = ld.ExtendedMetrics().Snapshot()
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		bodies := make(map[string]map[string]any)
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			bodies[body["name"].(string)] = body
+		}
+		assert.Len(t, bodies, 2)
+		if assert.Contains(t, bodies, "checkout.orders") {
+			assert.Equal(t, 1.0, bodies["checkout.orders"]["value"])
+		}
+		if assert.Contains(t, bodies, "checkout.latency") {
+			assert.Equal(t, "ms", bodies["checkout.latency"]["unit"])
+		}
+		assert.Equal(t, map[string]float64{"orders": 1, "latency": 5}, snapshot)
+	})
+}
+
+func TestLogdashMetricsAtomicBatch(t *testing.T) {
+	type sentMetric struct {
+		operation string
+		value     float64
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []logdash.Option
+		validate func(t *testing.T, sent []sentMetric)
+	}{
+		{
+			name: "should send reset before changes by default",
+			validate: func(t *testing.T, sent []sentMetric) {
+				assert.Greater(t, len(sent), 1)
+				assert.Equal(t, sentMetric{operation: "set", value: 0}, sent[0])
+			},
+		},
+		{
+			name: "should send set merged with following changes as single coherent update",
+			opts: []logdash.Option{logdash.WithMetricsAtomicBatch(100 * time.Millisecond)},
+			validate: func(t *testing.T, sent []sentMetric) {
+				assert.Equal(t, []sentMetric{{operation: "set", value: 10}}, sent)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			ld := logdash.New(append([]logdash.Option{
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+			}, tc.opts...)...)
+
+			// WHEN
+			ld.Metrics.Set("users", 0)
+			// changes follow the reset shortly, after the sending loop picked up the reset
+			time.Sleep(10 * time.Millisecond)
+			for range 10 {
+				ld.Metrics.Mutate("users", 1)
+			}
+			time.Sleep(200 * time.Millisecond)
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			var sent []sentMetric
+			total := 0.0
+			for _, r := range requestsCollector.requests {
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(r.body, &body))
+				metric := sentMetric{operation: body["operation"].(string), value: body["value"].(float64)}
+				sent = append(sent, metric)
+				if metric.operation == "set" {
+					total = metric.value
+				} else {
+					total += metric.value
+				}
+			}
+			assert.Equal(t, 10.0, total)
+			tc.validate(t, sent)
+		})
+	}
+
+	t.Run("should send held set on shutdown without waiting for window", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMetricsAtomicBatch(time.Hour),
+		)
+
+		// WHEN
+		ld.Metrics.Set("users", 5)
+		ld.Metrics.Mutate("users", 2)
+		start := time.Now()
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+		if assert.Len(t, requestsCollector.requests, 1) {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+			assert.Equal(t, "set", body["operation"])
+			assert.Equal(t, 7.0, body["value"])
+		}
+	})
+}
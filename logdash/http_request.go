@@ -0,0 +1,54 @@
+package logdash
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPRequest logs a standardized access log of the handled HTTP request at the http level, e.g.:
+//
+//	start := time.Now()
+//	next.ServeHTTP(rw, req)
+//	ld.Logger.HTTPRequest(req, rw.status, time.Since(start))
+//
+// The message is e.g. "GET /users 200 12ms" and the structured data contains
+// method, path, status, durationMs, remoteAddr and userAgent.
+// The query, the body and headers are not logged, as they may contain secrets.
+// Selected headers can be logged with [WithHTTPRequestHeaders].
+func (l *Logger) HTTPRequest(req *http.Request, status int, duration time.Duration) {
+	if !l.enabled(LevelHTTP) {
+		return
+	}
+
+	data := map[string]any{
+		"method":     req.Method,
+		"path":       req.URL.Path,
+		"status":     status,
+		"durationMs": float64(duration) / float64(time.Millisecond),
+		"remoteAddr": req.RemoteAddr,
+		"userAgent":  req.UserAgent(),
+	}
+	if headers := l.requestHeaders(req); len(headers) > 0 {
+		data["headers"] = headers
+	}
+
+	message := fmt.Sprintf("%s %s %d %s", req.Method, req.URL.Path, status, duration.Round(time.Millisecond))
+	l.logWithData(l.now(), LevelHTTP, message, l.withSource(LevelHTTP, data))
+}
+
+// requestHeaders returns values of headers selected with WithHTTPRequestHeaders which are present in the request.
+func (l *Logger) requestHeaders(req *http.Request) map[string]string {
+	var headers map[string]string
+	for _, name := range l.httpHeaders {
+		value := req.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	return headers
+}
@@ -0,0 +1,65 @@
+package logdash_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerHTTPRequest(t *testing.T) {
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/users?token=secret", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("User-Agent", "test-agent")
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("X-Request-Id", "request-1")
+		return req
+	}
+
+	t.Run("should log standardized fields without headers", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(logdash.WithSink(sink))
+		defer ld.Shutdown(context.Background())
+
+		// WHEN
+		ld.Logger.HTTPRequest(newRequest(), http.StatusCreated, 12*time.Millisecond)
+
+		// THEN
+		entries := sink.Entries()
+		assert.Len(t, entries, 1)
+		assert.Equal(t, logdash.LevelHTTP, entries[0].Level)
+		assert.Equal(t, "POST /users 201 12ms", entries[0].Message)
+		assert.Equal(t, map[string]any{
+			"method":     "POST",
+			"path":       "/users",
+			"status":     http.StatusCreated,
+			"durationMs": float64(12),
+			"remoteAddr": "192.0.2.1:1234",
+			"userAgent":  "test-agent",
+		}, entries[0].Fields)
+	})
+
+	t.Run("should log selected headers", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithHTTPRequestHeaders("x-request-id", "X-Missing"),
+		)
+		defer ld.Shutdown(context.Background())
+
+		// WHEN
+		ld.Logger.HTTPRequest(newRequest(), http.StatusOK, time.Millisecond)
+
+		// THEN
+		entries := sink.Entries()
+		assert.Len(t, entries, 1)
+		assert.Equal(t, map[string]string{"X-Request-Id": "request-1"}, entries[0].Fields["headers"])
+	})
+}
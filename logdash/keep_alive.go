@@ -0,0 +1,92 @@
+package logdash
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// keepAlive periodically exercises the connection to the server, so stale connections are replaced
+// before logs or metrics are sent over them, e.g. after the server restarted during an idle period.
+type keepAlive struct {
+	client         *http.Client
+	url            string
+	apiKey         string
+	interval       time.Duration
+	internalLogger *Logger
+
+	// ctx is canceled by stop, which also cancels the pending ping
+	ctx      context.Context
+	cancel   context.CancelFunc
+	doneChan chan struct{}
+}
+
+// startKeepAlive starts exercising the connection to the url every interval.
+func startKeepAlive(client *http.Client, url string, apiKey string, interval time.Duration, internalLogger *Logger) *keepAlive {
+	ctx, cancel := context.WithCancel(context.Background())
+	k := &keepAlive{
+		client:         client,
+		url:            url,
+		apiKey:         apiKey,
+		interval:       interval,
+		internalLogger: internalLogger,
+		ctx:            ctx,
+		cancel:         cancel,
+		doneChan:       make(chan struct{}),
+	}
+	go k.loop()
+	return k
+}
+
+func (k *keepAlive) loop() {
+	defer close(k.doneChan)
+
+	ticker := time.NewTicker(k.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-k.ctx.Done():
+			return
+		case <-ticker.C:
+			k.ping()
+		}
+	}
+}
+
+// ping sends a HEAD request to the logs endpoint of the server, any response means the connection is healthy.
+// On failure, idle connections are dropped, so the next request starts with a fresh connection.
+func (k *keepAlive) ping() {
+	ctx, cancel := context.WithTimeout(k.ctx, k.interval)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, k.url, nil)
+	if err != nil {
+		k.internalLogger.VerboseF("Keep-alive request failed: %v", err)
+		return
+	}
+	req.Header.Set("project-api-key", k.apiKey)
+	resp, err := k.client.Do(req)
+	if err != nil {
+		if k.ctx.Err() != nil {
+			// stopped, the connection is not used anymore
+			return
+		}
+		k.internalLogger.VerboseF("Keep-alive failed, dropping idle connections: %v", err)
+		k.client.CloseIdleConnections()
+		return
+	}
+	// Allow reuse connection
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// stop stops exercising the connection, cancels the pending ping and waits for it to finish.
+func (k *keepAlive) stop() {
+	if k == nil {
+		return
+	}
+	k.cancel()
+	<-k.doneChan
+}
@@ -0,0 +1,56 @@
+package logdash
+
+import (
+	"context"
+	"time"
+)
+
+// thresholdLogger implements syncLogger interface, passing only logs at least as severe as minLevel
+// to the wrapped syncLogger.
+type thresholdLogger struct {
+	syncLogger
+	minLevel Level
+}
+
+// withMinLevel wraps the logger to discard logs below the level, the empty level keeps all logs.
+func withMinLevel(logger syncLogger, level Level) syncLogger {
+	if level == "" {
+		return logger
+	}
+	return &thresholdLogger{syncLogger: logger, minLevel: level}
+}
+
+// unwrap implements the wrapperLogger interface.
+func (l *thresholdLogger) unwrap() syncLogger {
+	return l.syncLogger
+}
+
+// wrap implements the wrapperLogger interface.
+func (l *thresholdLogger) wrap(logger syncLogger) syncLogger {
+	return &thresholdLogger{syncLogger: logger, minLevel: l.minLevel}
+}
+
+// syncLog implements the syncLogger interface.
+func (l *thresholdLogger) syncLog(timestamp time.Time, level Level, message string, data map[string]any) {
+	// severities are resolved on every log, as levels may be registered later
+	if level.severity() < l.minLevel.severity() {
+		return
+	}
+	l.syncLogger.syncLog(timestamp, level, message, data)
+}
+
+// trySyncLog implements the trySyncLogger interface.
+func (l *thresholdLogger) trySyncLog(timestamp time.Time, level Level, message string, data map[string]any) error {
+	if level.severity() < l.minLevel.severity() {
+		return nil
+	}
+	return trySyncLog(l.syncLogger, timestamp, level, message, data)
+}
+
+// flush implements the flusher interface, when the wrapped logger does.
+func (l *thresholdLogger) flush(ctx context.Context) error {
+	if f, ok := l.syncLogger.(flusher); ok {
+		return f.flush(ctx)
+	}
+	return nil
+}
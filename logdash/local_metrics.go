@@ -0,0 +1,170 @@
+package logdash
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// localMetrics implements Metrics interface, tracking values of metrics locally
+// and forwarding all operations to the wrapped backend.
+type localMetrics struct {
+	backend metricsBackend
+
+	mu     sync.RWMutex
+	values map[string]float64
+	// maxNames limits the number of tracked metric names, 0 means no limit
+	maxNames int
+
+	// now returns the current time used to measure durations
+	now func() time.Time
+
+	// units declared for metrics, shared with the backend
+	units *metricUnits
+	// operations of metrics in the strict mode, shared with the backend, nil means lenient mode
+	operations *metricOperations
+	// registered contains names of metrics registered with Register
+	registered map[string]struct{}
+	// mirror mirrors operations to statsd, nil means no mirroring
+	mirror *statsdMirror
+	// aggregator aggregates observations into summary statistics, nil means observations are set as they are
+	aggregator *observeAggregator
+}
+
+// newLocalMetrics creates a new localMetrics instance wrapping the backend.
+func newLocalMetrics(backend metricsBackend) *localMetrics {
+	return &localMetrics{
+		backend:    backend,
+		values:     make(map[string]float64),
+		now:        time.Now,
+		units:      newMetricUnits(newLogger(newNoopLogger())),
+		registered: make(map[string]struct{}),
+	}
+}
+
+// Set sets a metric to an absolute value.
+func (m *localMetrics) Set(name string, value float64) {
+	m.set(name, value)
+	m.mirror.gauge(name, value)
+}
+
+// set sets a metric to an absolute value, without mirroring.
+func (m *localMetrics) set(name string, value float64) {
+	m.mu.Lock()
+	if m.tracks(name) {
+		m.values[name] = value
+	}
+	m.mu.Unlock()
+
+	m.backend.Set(name, value)
+}
+
+// tracks reports whether the value of the metric is tracked, i.e. it's tracked already or the limit isn't reached,
+// it must be called with the lock held.
+func (m *localMetrics) tracks(name string) bool {
+	if _, ok := m.values[name]; ok {
+		return true
+	}
+	return m.maxNames <= 0 || len(m.values) < m.maxNames
+}
+
+// SetWithUnit sets a metric to an absolute value, declaring its unit.
+func (m *localMetrics) SetWithUnit(name string, value float64, unit string) {
+	m.DeclareUnit(name, unit)
+	m.Set(name, value)
+}
+
+// DeclareUnit declares the unit of a metric, the first declared unit is kept.
+func (m *localMetrics) DeclareUnit(name string, unit string) {
+	m.units.declare(name, unit)
+}
+
+// Register declares metrics and sets them to their initial values, skipping already registered metrics.
+func (m *localMetrics) Register(specs []MetricSpec) {
+	for _, spec := range specs {
+		m.mu.Lock()
+		_, registered := m.registered[spec.Name]
+		m.registered[spec.Name] = struct{}{}
+		m.mu.Unlock()
+		if registered {
+			continue
+		}
+
+		m.DeclareUnit(spec.Name, spec.Unit)
+		m.Set(spec.Name, spec.Initial)
+		// declared after the initial value, so it's not reported as mixing operations
+		m.operations.declare(spec.Name, spec.Type.operation())
+	}
+}
+
+// Mutate changes a metric by a relative value.
+func (m *localMetrics) Mutate(name string, value float64) {
+	m.mu.Lock()
+	if m.tracks(name) {
+		m.values[name] += value
+	}
+	m.mu.Unlock()
+
+	m.backend.Mutate(name, value)
+	m.mirror.count(name, value)
+}
+
+// Timing starts measuring a duration and returns the function which stops it
+// and sets the metric to the measured duration in milliseconds.
+//
+// Only the first call of the returned function sets the metric, following calls do nothing.
+func (m *localMetrics) Timing(name string) func() {
+	start := m.now()
+	var stopped atomic.Bool
+	return func() {
+		if stopped.Swap(true) {
+			return
+		}
+		milliseconds := float64(m.now().Sub(start)) / float64(time.Millisecond)
+		m.set(name, milliseconds)
+		m.mirror.timing(name, milliseconds)
+	}
+}
+
+// Observe records a single observation of the metric, aggregated into summary statistics if enabled,
+// otherwise the metric is set to the observed value.
+//
+// The raw observation is mirrored as a histogram, so statsd aggregates observations on its own.
+func (m *localMetrics) Observe(name string, value float64) {
+	m.mirror.histogram(name, value)
+	if m.aggregator != nil {
+		m.aggregator.observe(name, value)
+		return
+	}
+	m.set(name, value)
+}
+
+// Snapshot returns the current values of all metrics.
+func (m *localMetrics) Snapshot() map[string]float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return maps.Clone(m.values)
+}
+
+// PrometheusHandler returns a handler exposing the snapshot in the Prometheus text format.
+func (m *localMetrics) PrometheusHandler() http.Handler {
+	return newPrometheusHandler(m.Snapshot)
+}
+
+// Shutdown sends pending summary statistics, shuts down the wrapped backend and the mirror.
+func (m *localMetrics) Shutdown(ctx context.Context) error {
+	m.aggregator.close()
+	defer m.mirror.close()
+	return m.backend.Shutdown(ctx)
+}
+
+// Close closes the wrapped backend and the mirror, pending summary statistics are discarded.
+func (m *localMetrics) Close() error {
+	m.aggregator.stop()
+	defer m.mirror.close()
+	return m.backend.Close()
+}
@@ -0,0 +1,215 @@
+package logdash_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashMetricsSnapshot(t *testing.T) {
+	t.Run("should track metric values locally", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New()
+		defer ld.Close()
+
+		// WHEN
+		ld.Metrics.Set("users", 10)
+		ld.Metrics.Mutate("users", 5)
+		ld.Metrics.Mutate("requests", 1)
+		ld.Metrics.Mutate("requests", 1)
+		snapshot := ld.ExtendedMetrics().Snapshot()
+		ld.Metrics.Set("users", 0)
+
+		// THEN
+		assert.Equal(t, map[string]float64{"users": 15, "requests": 2}, snapshot)
+		assert.Equal(t, map[string]float64{"users": 0, "requests": 2}, ld.ExtendedMetrics().Snapshot())
+	})
+
+	t.Run("should not track metric names beyond the limit", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New(logdash.WithMaxMetricNames(2))
+		defer ld.Close()
+
+		// WHEN
+		ld.Metrics.Set("users", 10)
+		ld.Metrics.Mutate("requests", 1)
+		ld.Metrics.Set("errors", 1)
+		ld.Metrics.Mutate("latency", 5)
+		ld.Metrics.Mutate("users", 5)
+
+		// THEN
+		assert.Equal(t, map[string]float64{"users": 15, "requests": 1}, ld.ExtendedMetrics().Snapshot())
+	})
+}
+
+func TestLogdashMetricsPrometheusHandler(t *testing.T) {
+	t.Run("should expose metrics in Prometheus text format", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New()
+		defer ld.Close()
+
+		ld.Metrics.Set("active_users", 42)
+		ld.Metrics.Mutate("http.requests-total", 1.5)
+		ld.Metrics.Set("5xx", -1)
+
+		// WHEN
+		recorder := httptest.NewRecorder()
+		ld.ExtendedMetrics().PrometheusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		// THEN
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "# TYPE _5xx gauge\n"+
+			"_5xx -1\n"+
+			"# TYPE active_users gauge\n"+
+			"active_users 42\n"+
+			"# TYPE http_requests_total gauge\n"+
+			"http_requests_total 1.5\n", recorder.Body.String())
+	})
+
+	t.Run("should suffix names colliding after conversion", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New()
+		defer ld.Close()
+
+		ld.Metrics.Set("a.b", 1)
+		ld.Metrics.Set("a_b", 2)
+		ld.Metrics.Set("a-b", 3)
+		ld.Metrics.Set("a_b_2", 4)
+
+		// WHEN
+		recorder := httptest.NewRecorder()
+		ld.ExtendedMetrics().PrometheusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		// THEN
+		assert.Equal(t, "# TYPE a_b_3 gauge\n"+
+			"a_b_3 3\n"+
+			"# TYPE a_b_4 gauge\n"+
+			"a_b_4 1\n"+
+			"# TYPE a_b gauge\n"+
+			"a_b 2\n"+
+			"# TYPE a_b_2 gauge\n"+
+			"a_b_2 4\n", recorder.Body.String())
+	})
+}
+
+func TestLogdashMetricsTiming(t *testing.T) {
+	t.Run("should set the measured duration in milliseconds only once", func(t *testing.T) {
+		// GIVEN
+		now := time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)
+		ld := logdash.New(logdash.WithTimeSource(func() time.Time { return now }))
+		defer ld.Close()
+
+		// WHEN
+		stop := ld.ExtendedMetrics().Timing("db.query")
+		now = now.Add(1500 * time.Microsecond)
+		stop()
+		now = now.Add(time.Second)
+		stop()
+
+		// THEN
+		assert.Equal(t, map[string]float64{"db.query": 1.5}, ld.ExtendedMetrics().Snapshot())
+	})
+
+	t.Run("should send the measured duration to the server", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+		)
+
+		// WHEN
+		func() {
+			defer ld.ExtendedMetrics().Timing("db.query")()
+			time.Sleep(20 * time.Millisecond)
+		}()
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, "set", body["operation"])
+		assert.GreaterOrEqual(t, body["value"].(float64), float64(20))
+		assert.Less(t, body["value"].(float64), float64(1000))
+	})
+}
+
+func TestLogdashMetricsObserve(t *testing.T) {
+	t.Run("should set the observed value by default", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New()
+		defer ld.Close()
+
+		// WHEN
+		ld.ExtendedMetrics().Observe("latency", 12)
+		ld.ExtendedMetrics().Observe("latency", 7)
+
+		// THEN
+		assert.Equal(t, map[string]float64{"latency": 7}, ld.ExtendedMetrics().Snapshot())
+	})
+
+	t.Run("should send summary statistics of observations within tolerance", func(t *testing.T) {
+		// GIVEN
+		const observations = 10000
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			// statistics are sent on shutdown
+			logdash.WithObserveAggregation(time.Hour),
+		)
+
+		// WHEN
+		// uniform distribution of 1..10000 in random order
+		for _, i := range rand.Perm(observations) {
+			ld.ExtendedMetrics().Observe("latency", float64(i+1))
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		sent := make(map[string]float64)
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			assert.Equal(t, "set", body["operation"])
+			sent[body["name"].(string)] = body["value"].(float64)
+		}
+		assert.Len(t, sent, 7)
+		assert.NotContains(t, sent, "latency")
+		assert.Equal(t, float64(observations), sent["latency.count"])
+		assert.Equal(t, 1.0, sent["latency.min"])
+		assert.Equal(t, float64(observations), sent["latency.max"])
+		assert.Equal(t, 5000.5, sent["latency.mean"])
+		// about 4 standard errors of a sample of 1024 observations
+		assert.InDelta(t, 5000, sent["latency.p50"], 600)
+		assert.InDelta(t, 9500, sent["latency.p95"], 300)
+		assert.InDelta(t, 9900, sent["latency.p99"], 150)
+	})
+}
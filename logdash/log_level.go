@@ -1,21 +1,176 @@
 package logdash
 
-// logLevel represents the severity level of a log message.
-type logLevel string
+import (
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gookit/color"
+)
+
+// Level represents the severity level of a log message.
+type Level string
 
 const (
-	// logLevelError represents error messages.
-	logLevelError logLevel = "error"
-	// logLevelWarn represents warning messages.
-	logLevelWarn logLevel = "warning"
-	// logLevelInfo represents informational messages.
-	logLevelInfo logLevel = "info"
-	// logLevelHTTP represents HTTP-related messages.
-	logLevelHTTP logLevel = "http"
-	// logLevelVerbose represents verbose level messages.
-	logLevelVerbose logLevel = "verbose"
-	// logLevelDebug represents debug level messages.
-	logLevelDebug logLevel = "debug"
-	// logLevelSilly represents the lowest priority log level.
-	logLevelSilly logLevel = "silly"
+	// LevelError represents error messages.
+	LevelError Level = "error"
+	// LevelWarn represents warning messages.
+	LevelWarn Level = "warning"
+	// LevelInfo represents informational messages.
+	LevelInfo Level = "info"
+	// LevelHTTP represents HTTP-related messages.
+	LevelHTTP Level = "http"
+	// LevelVerbose represents verbose level messages.
+	LevelVerbose Level = "verbose"
+	// LevelDebug represents debug level messages.
+	LevelDebug Level = "debug"
+	// LevelSilly represents the lowest priority log level.
+	LevelSilly Level = "silly"
 )
+
+type (
+	// LevelOption configures a level registered by [RegisterLevel].
+	LevelOption func(*levelSpec)
+
+	// levelSpec describes how a level is filtered and printed to the console.
+	levelSpec struct {
+		// severity is the rank of the level used for filtering, higher is more severe
+		severity int64
+		// color of the level name printed to the console
+		color color.RGBColor
+		// shortName is the three-letter level name printed to the console
+		shortName string
+	}
+
+	// levelRegistry holds registered levels and aliases, it's never modified once published.
+	levelRegistry struct {
+		levels map[Level]levelSpec
+		// aliases maps lowercase external level names to levels
+		aliases map[string]Level
+	}
+)
+
+var (
+	// levelsMu serializes updates of the registry, which can be extended by RegisterLevel at any time.
+	levelsMu sync.Mutex
+	// registeredLevels is replaced with an updated copy on every update, so logging reads it without locking.
+	registeredLevels atomic.Pointer[levelRegistry]
+)
+
+func init() {
+	registeredLevels.Store(&levelRegistry{
+		levels: map[Level]levelSpec{
+			LevelError:   {severity: 70, color: color.RGB(231, 0, 11), shortName: "ERR"},  // Red
+			LevelWarn:    {severity: 60, color: color.RGB(254, 154, 0), shortName: "WRN"}, // Orange
+			LevelInfo:    {severity: 50, color: color.RGB(21, 93, 252), shortName: "INF"}, // Blue
+			LevelHTTP:    {severity: 40, color: color.RGB(0, 166, 166), shortName: "HTP"}, // Teal
+			LevelVerbose: {severity: 30, color: color.RGB(0, 166, 0), shortName: "VRB"},   // Green
+			LevelDebug:   {severity: 20, color: color.RGB(0, 166, 62), shortName: "DBG"},  // Light Green
+			LevelSilly:   {severity: 10, color: color.RGB(80, 80, 80), shortName: "SLY"},  // Gray
+		},
+		aliases: map[string]Level{},
+	})
+}
+
+// updateRegistry publishes a copy of the registry modified by the function.
+func updateRegistry(update func(r *levelRegistry)) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+
+	current := registeredLevels.Load()
+	updated := &levelRegistry{
+		levels:  maps.Clone(current.levels),
+		aliases: maps.Clone(current.aliases),
+	}
+	update(updated)
+	registeredLevels.Store(updated)
+}
+
+// WithLevelColor sets the color of the level name printed to the console.
+//
+// Registered levels are printed in gray by default.
+func WithLevelColor(c color.RGBColor) LevelOption {
+	return func(s *levelSpec) {
+		s.color = c
+	}
+}
+
+// WithLevelShortName sets the level name printed to the console when short level names are enabled.
+//
+// By default, the first three letters of the level name are used.
+func WithLevelShortName(name string) LevelOption {
+	return func(s *levelSpec) {
+		s.shortName = name
+	}
+}
+
+// RegisterLevel registers a custom level, so it can be used for logging (see: [Logger.LogLevel]).
+//
+// The severity decides whether the level passes filtering (see: [WithMinLevel]),
+// on the scale of built-in levels: silly is 10, debug 20, verbose 30, http 40, info 50, warning 60 and error 70.
+// The name is sent to the server as-is. Registering an already registered level replaces it.
+func RegisterLevel(name string, severity int, opts ...LevelOption) Level {
+	spec := levelSpec{
+		severity:  int64(severity),
+		color:     color.RGB(80, 80, 80),
+		shortName: strings.ToUpper(name[:min(3, len(name))]),
+	}
+	for _, opt := range opts {
+		opt(&spec)
+	}
+
+	updateRegistry(func(r *levelRegistry) {
+		r.levels[Level(name)] = spec
+	})
+	return Level(name)
+}
+
+// RegisterLevelAlias registers an external level name, e.g. of syslog or another logging library,
+// so logs named with it are logged at the level (see: [Logger.LogNamed]).
+//
+// Names are matched case-insensitively. Registering an already registered alias replaces it.
+func RegisterLevelAlias(external string, level Level) {
+	updateRegistry(func(r *levelRegistry) {
+		r.aliases[strings.ToLower(external)] = level
+	})
+}
+
+// lookupLevel resolves the level name, either an alias or a level name, case-insensitively.
+func lookupLevel(name string) (Level, bool) {
+	r := registeredLevels.Load()
+	if _, ok := r.levels[Level(name)]; ok {
+		return Level(name), true
+	}
+	lower := strings.ToLower(name)
+	if level, ok := r.aliases[lower]; ok {
+		return level, true
+	}
+	if _, ok := r.levels[Level(lower)]; ok {
+		return Level(lower), true
+	}
+	return "", false
+}
+
+// spec returns the description of the level.
+//
+// Unknown levels have the severity of [LevelInfo] and no color.
+func (l Level) spec() levelSpec {
+	if spec, ok := registeredLevels.Load().levels[l]; ok {
+		return spec
+	}
+	return levelSpec{severity: 50}
+}
+
+// registered reports whether the level is a built-in level or registered by [RegisterLevel].
+func (l Level) registered() bool {
+	_, ok := registeredLevels.Load().levels[l]
+	return ok
+}
+
+// severity returns the rank of the level used for filtering, higher is more severe.
+//
+// Unknown levels have the severity of [LevelInfo].
+func (l Level) severity() int64 {
+	return l.spec().severity
+}
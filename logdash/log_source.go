@@ -0,0 +1,48 @@
+package logdash
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// maxSourceDepth limits the number of frames searched for the call site of a log.
+const maxSourceDepth = 32
+
+// sdkFunctionPrefix is the prefix of names of functions of the SDK, which are skipped when looking for the call site.
+var sdkFunctionPrefix = reflect.TypeOf(Logger{}).PkgPath() + "."
+
+// withSource returns data with the source of the log call attached, if enabled for the level (see: [WithSource]).
+//
+// Data of the log takes precedence over the source.
+func (l *Logger) withSource(level Level, data map[string]any) map[string]any {
+	if l.sourceLevel == nil || (*l.sourceLevel != "" && level.severity() < l.sourceLevel.severity()) {
+		return data
+	}
+	file, function, ok := callSite()
+	if !ok {
+		return data
+	}
+	return mergeFields(map[string]any{"source": file, "sourceFunction": function}, data)
+}
+
+// callSite returns the file:line and the function of the first caller outside of the SDK.
+//
+// Frames are searched rather than skipped by a fixed number, because logging methods call each other,
+// e.g. [Logger.Log] calls [Logger.Info], so the depth of the call site differs between them.
+func callSite() (file, function string, ok bool) {
+	var pcs [maxSourceDepth]uintptr
+	// skip runtime.Callers and callSite
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, sdkFunctionPrefix) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line), frame.Function, frame.PC != 0
+		}
+		if !more {
+			return "", "", false
+		}
+	}
+}
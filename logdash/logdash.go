@@ -2,8 +2,19 @@ package logdash
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/gookit/color"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -21,109 +32,1469 @@ type (
 		// If no API key is provided, the Logdash will not send any metrics to the server.
 		Metrics Metrics
 
+		// extendedMetrics is the metrics object created for Metrics, returned by ExtendedMetrics.
+		extendedMetrics *localMetrics
+
 		// internalLogger is the logger used to log messages to the console.
 		internalLogger *Logger
+		// secrets are masked in messages of the internal logger, shared with instances created by ForProject.
+		secrets *secretMasker
+
+		// shutdownOrder is the order of flushing logs and metrics on shutdown.
+		shutdownOrder ShutdownOrder
+		// shutdownHooks run in order after logs and metrics are flushed, they aren't inherited by ForProject.
+		shutdownHooks []func(ctx context.Context) error
+
+		// metricUnits are units declared for metrics, shared by Metrics and the backend sending them.
+		metricUnits *metricUnits
+		// metricOperations are operations of metrics in the strict mode, nil means lenient mode.
+		metricOperations *metricOperations
+
+		// options are kept to set up sending to the server when the API key is set later.
+		options options
+
+		// mu guards setting the API key later.
+		mu sync.Mutex
+
+		// client is the HTTP client shared by the logger and metrics.
+		//
+		// It's nil if no API key is provided.
+		client *httpClient
+
+		// deferredLogger and deferredMetrics wait for the API key to be set later.
+		//
+		// They're nil if the API key is provided to [New].
+		deferredLogger  *deferredLogger
+		deferredMetrics *deferredMetrics
+
+		// httpLogger sends logs to the server, guarded by mu, it's nil if no API key is set.
+		httpLogger *httpLogger
+
+		// readinessGate holds logs sent to the server until the application is ready.
+		readinessGate *readinessGate
+
+		// deadLetters receives logs which failed to be sent, nil means no dead-letter queue.
+		deadLetters chan LogEntry
+
+		// projects are instances created by [Logdash.ForProject], guarded by mu.
+		projects []*Logdash
+
+		// closed is true once the instance is shut down or closed, guarded by mu.
+		closed bool
+
+		// projectMu guards the project resolved lazily by [Logdash.DashboardURL], it's not held during the request.
+		projectMu sync.Mutex
+		projectID string
+		// projectResolving is true while the project is being resolved, so concurrent calls don't wait for it
+		projectResolving bool
+		// projectFailedAt is the time of the last failed resolution, zero if none failed
+		projectFailedAt time.Time
+	}
+
+	// Option is a function that configures a Logdash instance.
+	Option func(*options)
+
+	// options contains all the configuration options for Logdash.
+	options struct {
+		host               string
+		apiKey             string
+		verbose            bool
+		alignLevels        bool
+		shortLevels        bool
+		minLevel           Level
+		timeSource         func() time.Time
+		logFilters         []func(level Level, message string) bool
+		sinks              []Sink
+		exitFunc           func(code int)
+		fields             map[string]any
+		deploymentMetadata map[string]any
+		messagePrefix      string
+		messageSuffix      string
+		messageTemplate    string
+		templateFallback   string
+		bufferSize         int
+		localBufferSize    int
+		readinessHeld      bool
+		readinessQueueSize int
+		overflowPolicy     OverflowPolicy
+		asyncWorkers       int
+		httpTimeout        time.Duration
+		httpRetries        int
+		httpRetryMin       time.Duration
+		httpRetryMax       time.Duration
+		httpRetryJitter    float64
+		sendDeadline       time.Duration
+		maxRequestBytes    int
+		httpClient         *http.Client
+		tlsConfig          *tls.Config
+		logsMethod         string
+		metricsMethod      string
+		maxMetricNames     int
+		shutdownOrder      ShutdownOrder
+		startupProbe       time.Duration
+
+		metricsBufferSize      int
+		metricsOverflowPolicy  OverflowPolicy
+		metricIdleTimeout      time.Duration
+		metricCoalesceWindow   time.Duration
+		metricsRateLimit       float64
+		metricSnapshotInterval time.Duration
+		strictMetricTypes      bool
+		immediateMetrics       bool
+		metricsByDispatcher    bool
+		metricResend           bool
+		metricFlushThreshold   float64
+		metricFlushCount       int
+		metricShutdownRetry    time.Duration
+		metricPrefix           string
+		metricAtomicBatch      time.Duration
+		observeAggregation     time.Duration
+		metricDedup            bool
+		responseCaching        bool
+		metricDedupWindow      time.Duration
+		maxMetricAccumulators  int
+		metricRounding         func(float64) float64
+		statsdMirrorAddr       string
+
+		remoteFailureThreshold  int
+		remoteRecoveryThreshold int
+		onRemoteHealthy         func()
+		onRemoteUnhealthy       func(error)
+		onSend                  func(endpoint string, payload []byte, status int)
+		logEntryMiddlewares     []func(entry *LogEntry)
+		consoleFormat           ConsoleFormat
+		connectionKeepAlive     time.Duration
+		responseValidator       func(status int, body []byte) error
+		outputShutdownTimeout   time.Duration
+		onDrop                  func(entry LogEntry)
+		httpRequestHeaders      []string
+		consoleMinLevel         Level
+		remoteMinLevel          Level
+		wireLogWriter           io.Writer
+		clockOffset             time.Duration
+		autoClockSync           bool
+		clockSync               *clockSync
+		dialTimeout             time.Duration
+		unixSocket              string
+		responseHeaderTimeout   time.Duration
+		lazyFields              []lazyField
+		fieldSizeLimit          int
+		truncateFields          bool
+		consoleStdout           io.Writer
+		consoleStderr           io.Writer
+		consoleErrorLevel       Level
+		logFlushInterval        time.Duration
+		bufferHighWatermark     float64
+		bufferLowWatermark      float64
+		onBufferHigh            func()
+		onBufferLow             func()
+		logPayloadBuilder       func(timestamp time.Time, level Level, message string, fields map[string]any) any
+		shutdownHooks           []func(ctx context.Context) error
+		logEntryPool            bool
+		auditMode               bool
+		auditSpill              io.Writer
+		consoleLevelColors      map[Level]color.RGBColor
+		consoleTimestampColor   *color.RGBColor
+		consoleLineClear        bool
+		silentWhenUnconfigured  bool
+		deadLetterQueueSize     int
+		sampler                 *keyedSampler
+		traceSampled            func(ctx context.Context) (sampled bool, ok bool)
+		baggageKeys             []string
+		baggageSource           func(ctx context.Context, key string) (value string, ok bool)
+		runtimeStatsOnError     bool
+		source                  bool
+		sourceMinLevel          Level
+	}
+
+	// OverflowPolicy defines how to handle log overflow.
+	OverflowPolicy int
+
+	// ShutdownOrder defines the order in which logs and metrics are flushed by [Logdash.Shutdown].
+	ShutdownOrder int
+
+	// ConsoleFormat defines how logs are printed to the console (see: [WithConsoleFormat]).
+	ConsoleFormat int
+)
+
+const (
+	// OverflowPolicyDrop drops new logs when the internal buffer is full.
+	//
+	// This is the default behavior.
+	OverflowPolicyDrop OverflowPolicy = iota
+
+	// OverflowPolicyBlock blocks when the internal buffer is full.
+	//
+	// This is useful when you want to preserve logs even when the internal buffer is full.
+	OverflowPolicyBlock
+)
+
+const (
+	// ShutdownConcurrent flushes logs and metrics at the same time.
+	//
+	// This is the default behavior.
+	ShutdownConcurrent ShutdownOrder = iota
+
+	// ShutdownLogsFirst flushes metrics only after all logs are flushed.
+	ShutdownLogsFirst
+
+	// ShutdownMetricsFirst flushes logs only after all metrics are flushed.
+	//
+	// This is useful when the last logs summarize final metric values.
+	ShutdownMetricsFirst
+)
+
+const (
+	// ConsoleFormatText prints colored lines with timestamp, level and message, followed by data as JSON.
+	//
+	// This is the default format.
+	ConsoleFormatText ConsoleFormat = iota
+
+	// ConsoleFormatLogfmt prints logfmt lines without colors, e.g. time=... level=info msg="user logged in" user=john.
+	//
+	// Data is printed as separate keys sorted by name, composite values are encoded as JSON.
+	// Data keys colliding with time, level, msg or each other are prefixed with "data.", so keys are unique.
+	// This format is parsed natively by many tools, e.g. Grafana Loki.
+	ConsoleFormatLogfmt
+
+	// ConsoleFormatPlain prints the same lines as [ConsoleFormatText] without colors,
+	// e.g. for log files or terminals without color support.
+	ConsoleFormatPlain
+)
+
+var (
+	// DefaultBufferSize is the default size of the buffer for the async queue.
+	DefaultBufferSize = 128
+
+	// ErrAPIKeyAlreadySet is returned by [Logdash.SetAPIKey] when the API key is already set.
+	ErrAPIKeyAlreadySet = errors.New("API key already set")
+
+	// ErrEmptyAPIKey is returned by [Logdash.SetAPIKey] when the API key is empty.
+	ErrEmptyAPIKey = errors.New("empty API key")
+
+	// ErrNoAPIKey is returned by [Logdash.Ping] when the API key is not set.
+	ErrNoAPIKey = errors.New("API key not set")
+
+	// ErrUnauthorized is returned by [Logdash.Ping] when the server rejects the API key.
+	ErrUnauthorized = errors.New("API key rejected")
+
+	// ErrPayloadTooLarge is reported when the server rejects a log or metric as too large,
+	// or when it exceeds the limit of [WithMaxRequestBytes].
+	// Such items are dropped, because sending them again would fail as well.
+	// Logs and metrics are sent one per request, so there is no batch to split and retry in smaller parts,
+	// dropped logs are passed to the callback of [WithOnDrop] instead.
+	ErrPayloadTooLarge = errors.New("payload too large")
+
+	// ErrInvalidHost is returned by [NewWithError] when the host is not a valid http or https URL (see: [WithHost]).
+	ErrInvalidHost = errors.New("invalid host")
+
+	// ErrOverflow is returned when a log is dropped, because the buffer is full
+	// and the overflow policy is set to drop (see: [Logger.TryInfo]).
+	ErrOverflow = errors.New("buffer overflow")
+
+	// ErrBufferTooSmall is returned by [Logdash.ResizeLogBuffer] when the new size can't hold already buffered logs.
+	ErrBufferTooSmall = errors.New("buffer too small")
+
+	// DefaultRemoteFailureThreshold is the default number of consecutive failed sends
+	// after which the remote is considered unhealthy.
+	DefaultRemoteFailureThreshold = 3
+
+	// DefaultRemoteRecoveryThreshold is the default number of consecutive successful sends
+	// after which the unhealthy remote is considered healthy again.
+	DefaultRemoteRecoveryThreshold = 2
+)
+
+// WithHost sets the host for the Logdash server.
+//
+// The scheme is optional and defaults to https, e.g. "api.logdash.io" is the same as "https://api.logdash.io".
+// Use "http://" explicitly for local or self-hosted servers without TLS. Trailing slashes are ignored.
+func WithHost(host string) Option {
+	return func(o *options) {
+		o.host = host
+	}
+}
+
+// WithAPIKey sets the API key for the Logdash server.
+func WithAPIKey(apiKey string) Option {
+	return func(o *options) {
+		o.apiKey = apiKey
+	}
+}
+
+// WithVerbose enables verbose logging.
+//
+// This is useful for debugging, showing internal logs and changes in the metrics.
+func WithVerbose() Option {
+	return func(o *options) {
+		o.verbose = true
+	}
+}
+
+// WithLevelAlignment pads level names in the console output,
+// so messages of all levels start in the same column.
+func WithLevelAlignment() Option {
+	return func(o *options) {
+		o.alignLevels = true
+	}
+}
+
+// WithShortLevels prints three-letter level names (e.g. INF, WRN, ERR) in the console output.
+func WithShortLevels() Option {
+	return func(o *options) {
+		o.shortLevels = true
+	}
+}
+
+// WithConsoleFormat sets the format of logs printed to the console.
+//
+// By default, logs are printed as colored text (see: [ConsoleFormatText]).
+// Level alignment and short levels apply only to the text format.
+func WithConsoleFormat(format ConsoleFormat) Option {
+	return func(o *options) {
+		o.consoleFormat = format
+	}
+}
+
+// minimalBufferSize is the size of buffers in the minimal mode (see: [WithMinimalMode]).
+const minimalBufferSize = 16
+
+// WithMinimalMode is a preset minimizing the number of goroutines and the memory footprint,
+// e.g. for embedded devices or tiny sidecars. It combines:
+//   - buffers of 16 logs and metrics (see: [WithBufferSize], [WithMetricsBufferSize], [WithLocalBuffer]),
+//   - a single worker sending logs (see: [WithAsyncWorkers]),
+//   - metrics sent one by one by a single goroutine, without a goroutine per metric (see: [WithImmediateMetrics]),
+//   - no HTTP retries (see: [WithHTTPRetries]),
+//   - the console without colors (see: [ConsoleFormatPlain]).
+//
+// The tradeoffs are that logs are dropped sooner during bursts or server outages, as the buffer is small
+// and the overflow policy drops them by default, that logging a metric waits when its buffer is full,
+// as metrics aren't accumulated, so every operation is a request, and that a failed request is given up
+// without retrying. Logs and metrics are still sent by separate goroutines, so a slow request
+// for one doesn't delay the other.
+//
+// Options following the preset override it, e.g. to keep colors: WithMinimalMode(), WithConsoleFormat(ConsoleFormatText).
+func WithMinimalMode() Option {
+	return func(o *options) {
+		o.bufferSize = minimalBufferSize
+		o.metricsBufferSize = minimalBufferSize
+		o.localBufferSize = minimalBufferSize
+		o.asyncWorkers = 1
+		o.immediateMetrics = true
+		o.metricsByDispatcher = true
+		o.httpRetries = 0
+		o.consoleFormat = ConsoleFormatPlain
+	}
+}
+
+// WithSilentWhenUnconfigured disables printing logs to the console when no API key is provided,
+// e.g. in libraries using Logdash internally, which shouldn't print to the stdout of the host application.
+//
+// Logs are still buffered until the API key is set (see: [Logdash.SetAPIKey]) and passed to sinks (see: [WithSink]),
+// but the console stays silent for the lifetime of the instance. With the API key, logs are printed as usual.
+// By default, logs are always printed to the console.
+func WithSilentWhenUnconfigured() Option {
+	return func(o *options) {
+		o.silentWhenUnconfigured = true
+	}
+}
+
+// WithConsoleLineClear clears the current terminal line before printing each log, e.g. in CLI tools drawing
+// progress bars with carriage returns, so logs don't mix with a partially drawn line.
+//
+// Each log is printed as "\r\x1b[K" followed by the line and a newline, so the progress bar can be redrawn
+// below the log. The sequence is printed in every console format, so it's meant for terminals only.
+// By default, lines are printed as they are.
+func WithConsoleLineClear() Option {
+	return func(o *options) {
+		o.consoleLineClear = true
+	}
+}
+
+// WithConsoleStreams prints logs at least as severe as errorThreshold to stderr and other logs to stdout,
+// following the Unix convention, e.g.:
+//
+//	logdash.WithConsoleStreams(os.Stdout, os.Stderr, logdash.LevelWarn)
+//
+// Each log is printed as a whole line to one of the streams, so lines don't interleave.
+// A nil stdout defaults to [os.Stdout]. A nil stderr prints all logs to stdout.
+// By default, all logs are printed to [os.Stdout].
+func WithConsoleStreams(stdout, stderr io.Writer, errorThreshold Level) Option {
+	return func(o *options) {
+		o.consoleStdout = stdout
+		o.consoleStderr = stderr
+		o.consoleErrorLevel = errorThreshold
+	}
+}
+
+// WithConsoleLevelColor sets the color of the level name printed to the console,
+// e.g. when the default color is hard to read on the terminal theme.
+//
+// It applies to built-in and registered levels (see: [RegisterLevel]), colors of other levels are unchanged.
+func WithConsoleLevelColor(level Level, c color.RGBColor) Option {
+	return func(o *options) {
+		if o.consoleLevelColors == nil {
+			o.consoleLevelColors = make(map[Level]color.RGBColor)
+		}
+		o.consoleLevelColors[level] = c
+	}
+}
+
+// WithColorScheme sets colors of level names printed to the console at once (see: [WithConsoleLevelColor]).
+//
+// Levels missing in the scheme keep their colors.
+func WithColorScheme(scheme map[Level]color.RGBColor) Option {
+	return func(o *options) {
+		for level, c := range scheme {
+			WithConsoleLevelColor(level, c)(o)
+		}
+	}
+}
+
+// WithTimestampColor sets the color of timestamps printed to the console, gray by default.
+func WithTimestampColor(c color.RGBColor) Option {
+	return func(o *options) {
+		o.consoleTimestampColor = &c
+	}
+}
+
+// WithMinLevel sets the minimum level of logs, less severe logs are discarded.
+//
+// Levels from the least severe are: [LevelSilly], [LevelDebug], [LevelVerbose], [LevelHTTP],
+// [LevelInfo], [LevelWarn] and [LevelError]. By default, all logs are kept.
+// Unknown levels are reported through the internal logger (see: [WithVerbose]) and ignored.
+// The level can be changed at runtime with [Logdash.SetMinLevel].
+func WithMinLevel(level Level) Option {
+	return func(o *options) {
+		o.minLevel = level
+	}
+}
+
+// WithConsoleMinLevel sets the minimum level of logs printed to the console, less severe logs are not printed,
+// e.g. [LevelInfo] to keep the console quiet while sending all logs to the server.
+//
+// It applies in addition to [WithMinLevel], which discards logs before they reach any output.
+// By default, all logs are printed.
+func WithConsoleMinLevel(level Level) Option {
+	return func(o *options) {
+		o.consoleMinLevel = level
+	}
+}
+
+// WithRemoteMinLevel sets the minimum level of logs sent to the server, less severe logs are not sent,
+// e.g. [LevelInfo] to print debug logs to the console during development without using the server quota.
+//
+// It applies in addition to [WithMinLevel], which discards logs before they reach any output.
+// By default, all logs are sent.
+func WithRemoteMinLevel(level Level) Option {
+	return func(o *options) {
+		o.remoteMinLevel = level
+	}
+}
+
+// WithLogFilter adds the filter deciding whether the log is kept, returning false drops the log
+// before it reaches the console or the server.
+//
+// The filter gets the level and the final message, including prefix and suffix (see: [WithMessagePrefix]).
+// Multiple filters can be added, the log is kept only if all of them keep it.
+//
+// Filters run synchronously on every log call, so they should be fast and non-blocking,
+// e.g. a substring check rather than a regular expression compiled on every call.
+// Filters must be safe for concurrent use.
+func WithLogFilter(filter func(level Level, message string) bool) Option {
+	return func(o *options) {
+		if filter != nil {
+			o.logFilters = append(o.logFilters, filter)
+		}
+	}
+}
+
+// WithSink adds the sink receiving logs in addition to the console and the server,
+// e.g. [MemorySink] to assert on logs in tests.
+//
+// The sink receives logs which pass the minimum level and filters (see: [WithMinLevel], [WithLogFilter]),
+// including prefix, suffix and fields. Multiple sinks can be added.
+func WithSink(sink Sink) Option {
+	return func(o *options) {
+		if sink != nil {
+			o.sinks = append(o.sinks, sink)
+		}
+	}
+}
+
+// WithLogEntryMiddleware adds the middleware modifying logs right before they're queued for sending to the server,
+// e.g. to add a field computed per log or to rewrite the message. Console output is not affected.
+//
+// Multiple middlewares can be added, they run in the order of adding.
+// Middlewares run synchronously on every log call, so they should be fast.
+// The data of the entry may be shared with other outputs, so replace it with a copy instead of modifying it.
+func WithLogEntryMiddleware(middleware func(entry *LogEntry)) Option {
+	return func(o *options) {
+		if middleware != nil {
+			o.logEntryMiddlewares = append(o.logEntryMiddlewares, middleware)
+		}
+	}
+}
+
+// WithLazyField attaches the field computed by fn to logs sent to the server, e.g. an expensive snapshot
+// of a connection pool, which is worth computing only for logs which are actually sent.
+//
+// Unlike [WithFields], fn is called only after the log passed the minimum levels, filters and sampling,
+// right before it's enqueued for sending, so it's not called for discarded logs.
+// It's called synchronously by the logging goroutine and must be safe for concurrent use.
+// Lazy fields are not printed to the console. Data of the log takes precedence over lazy fields.
+func WithLazyField(key string, fn func() any) Option {
+	return func(o *options) {
+		o.lazyFields = append(o.lazyFields, lazyField{key: key, fn: fn})
+	}
+}
+
+// WithFieldSizeLimit reports fields of logs sent to the server, which are larger than the limit in bytes
+// when serialized as JSON, e.g. a whole HTTP response body attached by accident, to catch them before they bloat logs.
+//
+// Fields are reported by the verbose output (see: [WithVerbose]). With truncate, they're also replaced
+// by the string of their first bytes serialized as JSON, followed by "...(truncated)".
+// Only top-level fields are checked, right before the log is enqueued for sending, so each field is serialized
+// one more time. Logs printed to the console are not affected. By default, the size of fields is not checked.
+func WithFieldSizeLimit(bytes int, truncate bool) Option {
+	return func(o *options) {
+		o.fieldSizeLimit = bytes
+		o.truncateFields = truncate
+	}
+}
+
+// WithSource attaches the location of the log call to logs of the [Logger] methods, e.g. [Logger.Info],
+// like the AddSource option of [log/slog.HandlerOptions] does for slog logs:
+//   - "source" is the file and the line of the call, e.g. "/app/main.go:42",
+//   - "sourceFunction" is the fully qualified name of the calling function, e.g. "main.handleOrder".
+//
+// Capturing the caller is relatively expensive, so it can be limited to severe logs with [WithSourceMinLevel].
+// Data of the log takes precedence over the source. By default, the source is not attached.
+func WithSource() Option {
+	return func(o *options) {
+		o.source = true
+	}
+}
+
+// WithSourceMinLevel attaches the location of the log call to logs at the level and above, e.g. [LevelError],
+// less severe logs don't capture the caller (see: [WithSource]).
+func WithSourceMinLevel(level Level) Option {
+	return func(o *options) {
+		o.source = true
+		o.sourceMinLevel = level
+	}
+}
+
+// WithRuntimeStatsOnError attaches runtime statistics to logs at the error level and above, e.g. to diagnose leaks:
+//   - "goroutines" is the current number of goroutines,
+//   - "heapAllocBytes" is the number of bytes of allocated heap objects,
+//   - "numGC" is the number of completed GC cycles.
+//
+// Reading memory statistics stops the world, so they're read at most once per second and may lag behind.
+// Fields of the logger and data of the log take precedence over the statistics.
+// By default, runtime statistics are not attached.
+func WithRuntimeStatsOnError() Option {
+	return func(o *options) {
+		o.runtimeStatsOnError = true
+	}
+}
+
+// WithKeyedSampling keeps only a rate (0 to 1) of logs, except logs flagged by the value of the field,
+// e.g. to keep all logs of a trace or a user under investigation while sampling the rest:
+//
+//	logdash.WithKeyedSampling("traceID", 0.1, func(value any) bool {
+//		return value == investigatedTraceID
+//	})
+//
+// The log is kept when its data, including fields (see: [Logger.WithFields]), contains the field
+// and keep returns true for its value. Other logs, including logs without the field, are kept randomly at the rate.
+// Sampled out logs reach neither the console nor the server.
+// By default, all logs are kept.
+func WithKeyedSampling(fieldKey string, rate float64, keep func(value any) bool) Option {
+	return func(o *options) {
+		o.sampler = &keyedSampler{key: fieldKey, rate: rate, keep: keep}
+	}
+}
+
+// WithFollowTraceSampling drops logs below the info level when the trace of their context is sampled out,
+// so the volume of debug logs follows the volume of traces. Logs of sampled traces, logs without a trace
+// and logs at the info level and above are always kept.
+//
+// The SDK doesn't depend on OpenTelemetry, so the sampling decision of the span in the context
+// is reported by the function, ok is false when the context carries no span:
+//
+//	logdash.WithFollowTraceSampling(func(ctx context.Context) (bool, bool) {
+//		spanContext := trace.SpanContextFromContext(ctx)
+//		return spanContext.IsSampled(), spanContext.IsValid()
+//	})
+//
+// It applies to logs with a context, i.e. logged through [SlogTextHandler].
+// By default, logs are kept regardless of the sampling of traces.
+func WithFollowTraceSampling(traceSampled func(ctx context.Context) (sampled bool, ok bool)) Option {
+	return func(o *options) {
+		o.traceSampled = traceSampled
+	}
+}
+
+// WithBaggageFields attaches values of the keys of the baggage in the context as fields of logs with the context,
+// e.g. the tenant or the cohort of a feature flag, without extracting them manually.
+// Keys absent from the baggage are omitted. Data of the log takes precedence over the baggage.
+//
+// The baggage is set by [ContextWithBaggage], or read by the function set by [WithBaggageSource].
+// It applies to logs with a context, i.e. logged through [SlogTextHandler], e.g. by [log/slog.InfoContext].
+// By default, the baggage is not attached.
+func WithBaggageFields(keys ...string) Option {
+	return func(o *options) {
+		o.baggageKeys = append(o.baggageKeys, keys...)
+	}
+}
+
+// WithBaggageSource reads values of keys selected by [WithBaggageFields] absent from the baggage set by
+// [ContextWithBaggage] from other baggage in the context, ok is false when the key is absent.
+//
+// The SDK doesn't depend on OpenTelemetry, so its baggage is read by the function, e.g.:
+//
+//	logdash.WithBaggageSource(func(ctx context.Context, key string) (string, bool) {
+//		member := baggage.FromContext(ctx).Member(key)
+//		return member.Value(), member.Key() != ""
+//	})
+func WithBaggageSource(source func(ctx context.Context, key string) (value string, ok bool)) Option {
+	return func(o *options) {
+		o.baggageSource = source
+	}
+}
+
+// WithFields attaches the fields to the data of every log, e.g. the name of the service.
+//
+// Fields are merged with fields added before. Data of the log takes precedence over the fields.
+func WithFields(fields map[string]any) Option {
+	return func(o *options) {
+		if o.fields == nil {
+			o.fields = make(map[string]any, len(fields))
+		}
+		for key, value := range fields {
+			o.fields[key] = value
+		}
+	}
+}
+
+// WithHTTPRequestHeaders selects request headers logged by [Logger.HTTPRequest], e.g. "X-Request-Id".
+//
+// Headers are logged as-is, so headers carrying secrets, e.g. "Authorization" or "Cookie", shouldn't be selected.
+// By default, no headers are logged.
+func WithHTTPRequestHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.httpRequestHeaders = append(o.httpRequestHeaders, headers...)
+	}
+}
+
+// WithExitFunc sets the function terminating the process after [Logger.Fatal], e.g. to avoid exiting in tests.
+//
+// By default, [os.Exit] is used.
+func WithExitFunc(exit func(code int)) Option {
+	return func(o *options) {
+		o.exitFunc = exit
+	}
+}
+
+// WithTimeSource sets the clock used for timestamps of logs and metrics, e.g. a fixed clock in tests.
+//
+// Timestamps are always sent to the server in UTC (RFC 3339 with nanoseconds),
+// while the console shows them in local time.
+// By default and when now is nil, [time.Now] is used.
+func WithTimeSource(now func() time.Time) Option {
+	return func(o *options) {
+		if now == nil {
+			now = time.Now
+		}
+		o.timeSource = now
+	}
+}
+
+// WithClockOffset shifts timestamps of all logs and metrics by offset, e.g. to compensate
+// a known skew of the host clock when NTP isn't available, or to test time-shifted scenarios.
+//
+// The offset applies to the time source (see: [WithTimeSource]), so timestamps printed to the console shift as well.
+// Measured durations (see: [ExtendedMetrics.Timing]) are not affected.
+// By default, timestamps are not shifted.
+func WithClockOffset(offset time.Duration) Option {
+	return func(o *options) {
+		o.clockOffset = offset
+	}
+}
+
+// WithAutoClockSync corrects timestamps of all logs and metrics by the skew of the host clock from the server clock,
+// measured from the Date header of successful responses, like [WithClockOffset] without knowing the skew upfront.
+//
+// The skew is a rolling estimate, so timestamps follow the server clock gradually. The Date header has
+// a resolution of a second, so smaller skews are not corrected. Logs and metrics recorded before the first
+// response are not corrected. The correction adds to the clock offset (see: [WithClockOffset]).
+// By default, timestamps are not corrected.
+func WithAutoClockSync() Option {
+	return func(o *options) {
+		o.autoClockSync = true
+	}
+}
+
+// WithMessagePrefix adds the prefix to every log message, e.g. to tag the environment.
+//
+// The prefix is separated from the message by a space.
+func WithMessagePrefix(prefix string) Option {
+	return func(o *options) {
+		o.messagePrefix = prefix
+	}
+}
+
+// WithMessageSuffix adds the suffix to every log message, e.g. to tag the environment.
+//
+// The suffix is separated from the message by a space.
+func WithMessageSuffix(suffix string) Option {
+	return func(o *options) {
+		o.messageSuffix = suffix
+	}
+}
+
+// WithMessageTemplate renders every log message with the template, e.g. "{component} | {message}",
+// so messages are structured consistently in the console and on the server.
+//
+// The {message} placeholder is the message of the log, including the prefix and suffix (see: [WithMessagePrefix]).
+// Other placeholders are filled with fields of the log, including fields of the logger (see: [WithFields]).
+// Placeholders of missing fields are rendered empty, unless a fallback is set (see: [WithMessageTemplateFallback]).
+// Fields are still sent as structured data. Log filters (see: [WithLogFilter]) see the message before it's templated.
+// By default, messages are not templated.
+func WithMessageTemplate(tmpl string) Option {
+	return func(o *options) {
+		o.messageTemplate = tmpl
+	}
+}
+
+// WithMessageTemplateFallback sets the text rendered in place of placeholders of missing fields,
+// e.g. "-", in the message template (see: [WithMessageTemplate]). By default, they're rendered empty.
+func WithMessageTemplateFallback(fallback string) Option {
+	return func(o *options) {
+		o.templateFallback = fallback
+	}
+}
+
+// WithBufferSize sets the size of the buffer for the async queue.
+func WithBufferSize(size int) Option {
+	return func(o *options) {
+		o.bufferSize = size
+	}
+}
+
+// WithOverflowPolicy sets how to handle log overflow.
+//
+// It's the same as [WithLogOverflowPolicy].
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return WithLogOverflowPolicy(policy)
+}
+
+// WithBufferWatermarks sets callbacks invoked when the log buffer fills up to the high watermark
+// and when it drains down to the low watermark again, as fractions of the buffer size (see: [WithBufferSize]),
+// e.g. to alert before logs start to be dropped:
+//
+//	logdash.WithBufferWatermarks(0.8, 0.2, alert, resolve)
+//
+// Each callback is invoked once per crossing: after onHigh, onHigh is not invoked again until onLow was invoked.
+// Callbacks are invoked synchronously by the logging goroutine or the worker, so they must be fast and non-blocking.
+// Either callback may be nil.
+func WithBufferWatermarks(high, low float64, onHigh, onLow func()) Option {
+	return func(o *options) {
+		o.bufferHighWatermark = high
+		o.bufferLowWatermark = low
+		o.onBufferHigh = onHigh
+		o.onBufferLow = onLow
+	}
+}
+
+// WithLogFlushInterval checks every interval that logs buffered before the previous check are sent,
+// and logs a warning through the internal logger (see: [WithVerbose]) when they're still waiting,
+// e.g. because the workers wait for a slow request to the server.
+//
+// Logs are sent in order as soon as a worker is free (see: [WithAsyncWorkers]), the check doesn't send them
+// by itself. The warning is logged once until the waiting logs are sent. By default, logs aren't checked.
+func WithLogFlushInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.logFlushInterval = interval
+	}
+}
+
+// WithAsyncWorkers sets the number of concurrent workers sending logs to the server.
+//
+// The default is 1 worker, which sends logs in order. With more workers, throughput
+// against high-latency servers is multiplied, but the order of logs is best-effort only.
+// The original order can be reconstructed from the sequence number of logs.
+func WithAsyncWorkers(n int) Option {
+	return func(o *options) {
+		o.asyncWorkers = n
+	}
+}
+
+// WithLogOverflowPolicy sets how to handle log overflow.
+func WithLogOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflowPolicy = policy
+	}
+}
+
+// WithMetricsBufferSize sets the size of the buffer for the metrics queue.
+func WithMetricsBufferSize(size int) Option {
+	return func(o *options) {
+		o.metricsBufferSize = size
+	}
+}
+
+// WithMetricsOverflowPolicy sets how to handle metrics overflow.
+//
+// Metrics are accumulated before sending, so the metrics queue is full only under extreme load.
+// The default is [OverflowPolicyBlock] to keep metrics accurate.
+func WithMetricsOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.metricsOverflowPolicy = policy
+	}
+}
+
+// WithHTTPTimeout sets the timeout for HTTP requests.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.httpTimeout = timeout
+	}
+}
+
+// WithUnixSocket sends logs and metrics over the Unix domain socket at the path instead of connecting to the host,
+// e.g. to a local agent of a sidecar architecture.
+//
+// Requests still carry the standard paths and headers. The host only forms their URLs, so it's set to
+// "http://localhost", unless it's set by [WithHost] following this option, e.g. to use TLS over the socket.
+// When used with [WithHTTPClient], the socket is dialed by a copy of its transport, as long as it's an [*http.Transport].
+// By default, the host is dialed over TCP.
+func WithUnixSocket(path string) Option {
+	return func(o *options) {
+		o.unixSocket = path
+		o.host = unixSocketHost
+	}
+}
+
+// unixSocketHost is the host of requests sent over a Unix domain socket, it doesn't affect where they're sent.
+const unixSocketHost = "http://localhost"
+
+// WithDialTimeout sets the timeout for connecting to the server, e.g. to fail fast when the host is down,
+// while [WithHTTPTimeout] allows a slow server more time to respond.
+//
+// When used with [WithHTTPClient], the timeout is applied to a copy of its transport, as long as it's an [*http.Transport].
+// By default, the dial timeout of [http.DefaultTransport] applies, bounded by [WithHTTPTimeout].
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithResponseHeaderTimeout sets the timeout for waiting for response headers after the request is sent,
+// e.g. to give up on a server which accepts connections but doesn't respond.
+//
+// When used with [WithHTTPClient], the timeout is applied to a copy of its transport, as long as it's an [*http.Transport].
+// By default, only [WithHTTPTimeout] bounds the wait.
+func WithResponseHeaderTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.responseHeaderTimeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for sending data to the server.
+//
+// The client is copied, so it's not modified by other options (e.g. [WithHTTPTimeout]).
+// Retries are still handled by Logdash (see: [WithHTTPRetries]).
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for connections to the server,
+// e.g. to pin the server certificate or restrict TLS versions.
+//
+// When used with [WithHTTPClient], the configuration is applied to the transport of the provided client,
+// as long as it's an [*http.Transport]. The provided transport is not modified, a copy is used instead.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// WithStartupProbe makes [New] and [NewWithError] wait until the server is reachable
+// and the API key is valid (see: [Logdash.Ping]), but no longer than the timeout.
+//
+// The probe uses the same HTTP retries and timeout as sending logs and metrics.
+// [NewWithError] returns the error when the probe fails, [New] reports it only in verbose mode.
+func WithStartupProbe(timeout time.Duration) Option {
+	return func(o *options) {
+		o.startupProbe = timeout
+	}
+}
+
+// WithSendDeadline bounds the total time spent on sending a single log or metric, including all retries.
+//
+// Unlike [WithHTTPTimeout], which limits a single attempt, the deadline makes the time
+// after which the entry is abandoned predictable, no matter how many retries are configured.
+// By default, there's no deadline.
+func WithSendDeadline(deadline time.Duration) Option {
+	return func(o *options) {
+		o.sendDeadline = deadline
+	}
+}
+
+// WithMaxRequestBytes limits the size of the body of requests sending logs and metrics,
+// e.g. to stay below the limit of a proxy in front of the server.
+//
+// The size is checked after the log or metric is marshaled, so larger items are dropped without being sent
+// and reported by the verbose output (see: [WithVerbose]) and the callback of [WithOnDrop],
+// as if the server rejected them (see: [ErrPayloadTooLarge]).
+// Logs and metrics are sent one per request, so there are no batches to split.
+// By default, the size is not limited.
+func WithMaxRequestBytes(n int) Option {
+	return func(o *options) {
+		o.maxRequestBytes = n
+	}
+}
+
+// WithHTTPRetries sets the number of retries for HTTP requests.
+func WithHTTPRetries(retries int) Option {
+	return func(o *options) {
+		o.httpRetries = retries
+	}
+}
+
+// WithHTTPRetryMin sets the minimum duration for HTTP retries.
+func WithHTTPRetryMin(min time.Duration) Option {
+	return func(o *options) {
+		o.httpRetryMin = min
+	}
+}
+
+// WithHTTPRetryMax sets the maximum duration for HTTP retries.
+func WithHTTPRetryMax(max time.Duration) Option {
+	return func(o *options) {
+		o.httpRetryMax = max
+	}
+}
+
+// WithConnectionKeepAlive exercises the connection to the server every interval in the background.
+//
+// When the server can't be reached, idle connections are dropped, so the next send starts with a fresh connection
+// instead of failing on a stale one, e.g. after the server or a load balancer restarted during an idle period.
+// The connection is exercised with a HEAD request to the logs endpoint, which doesn't send any logs.
+// A pending request is canceled on shutdown. By default, the connection is not exercised.
+func WithConnectionKeepAlive(interval time.Duration) Option {
+	return func(o *options) {
+		o.connectionKeepAlive = interval
+	}
+}
+
+// WithResponseValidator sets a validator of successful responses of the server,
+// e.g. to detect gateways responding with 200 OK and an error in the body.
+//
+// Responses rejected by the validator are retried like responses with error status (see: [WithHTTPRetries]),
+// and reported as failed sends when retries are exhausted.
+// The validator gets responses of all requests sent to the server, with status below 400.
+// By default, all responses with status below 400 are successful.
+func WithResponseValidator(validator func(status int, body []byte) error) Option {
+	return func(o *options) {
+		o.responseValidator = validator
+	}
+}
+
+// WithOnRemoteHealthy sets a callback invoked when sending to the server recovers
+// after the remote was considered unhealthy (see: [WithOnRemoteUnhealthy], [WithRemoteRecoveryThreshold]).
+//
+// The callback is invoked in a separate goroutine, so it doesn't block sending.
+// Callbacks are invoked in the order of transitions.
+func WithOnRemoteHealthy(fn func()) Option {
+	return func(o *options) {
+		o.onRemoteHealthy = fn
+	}
+}
+
+// WithOnRemoteUnhealthy sets a callback invoked when sending to the server
+// consistently fails (see: [WithRemoteFailureThreshold]).
+// The callback receives the error of the last failed send.
+//
+// The callback is invoked only once per transition, so it's not called for every failed send.
+// It's invoked in a separate goroutine, so it doesn't block sending.
+func WithOnRemoteUnhealthy(fn func(error)) Option {
+	return func(o *options) {
+		o.onRemoteUnhealthy = fn
+	}
+}
+
+// WithOnSend sets a callback invoked after logs or metrics are successfully sent to the server.
+// The callback receives the endpoint (e.g. "/logs"), the sent JSON payload and the response status.
+//
+// The callback is invoked synchronously by the background worker, so it should be fast.
+// The payload is a copy, so the callback may keep it.
+func WithOnSend(fn func(endpoint string, payload []byte, status int)) Option {
+	return func(o *options) {
+		o.onSend = fn
+	}
+}
+
+// WithWireLogFile writes the exact JSON of every log sent to the server to w, one object per line (NDJSON),
+// e.g. to debug what's actually sent or to archive logs locally.
+//
+// Lines are written in the background, so a slow writer doesn't delay sending;
+// when the writer can't keep up, lines are dropped. Lines are written before the result of sending is known,
+// so they include logs which failed to be sent. The writer is not closed by the SDK.
+// By default, sent logs are not written anywhere.
+func WithWireLogFile(w io.Writer) Option {
+	return func(o *options) {
+		o.wireLogWriter = w
+	}
+}
+
+// WithLogPayloadBuilder sets the function building the JSON payload of each log sent to the server,
+// e.g. for a self-hosted backend with its own ingestion schema:
+//
+//	logdash.WithLogPayloadBuilder(func(timestamp time.Time, level logdash.Level, message string, fields map[string]any) any {
+//		return map[string]any{
+//			"meta": map[string]any{"level": level, "time": timestamp},
+//			"body": map[string]any{"text": message, "fields": fields},
+//		}
+//	})
+//
+// The returned value is marshaled to JSON and sent as is. The builder gets the log after middlewares
+// (see: [WithLogEntryMiddleware]) and is called synchronously by the logging goroutine.
+// By default, logs are sent as [LogEntry].
+func WithLogPayloadBuilder(builder func(timestamp time.Time, level Level, message string, fields map[string]any) any) Option {
+	return func(o *options) {
+		o.logPayloadBuilder = builder
+	}
+}
+
+// WithAuditMode sends logs to the server in strict order without dropping any, e.g. for compliance logging.
+// It trades throughput and latency for correctness.
+//
+// Logs are sent one by one by a single worker, and a log which fails to be sent is retried until it's delivered,
+// regardless of [WithHTTPRetries] and [WithSendDeadline]. Rounds of retries are spaced by [WithHTTPRetryMax],
+// a second by default. While the server is unreachable, logs wait in the buffer and once it's full,
+// logging calls block until the server recovers (see: [WithBufferSize]). Sequence numbers don't wrap at 2^32.
+//
+// Logs not delivered when the context of [Logdash.Shutdown] is done, or pending on [Logdash.Close],
+// are written to the spill as JSON lines in order, e.g. to a file to be replayed later. A log whose request
+// was interrupted by the shutdown may be both delivered and spilled. Nil spill means such logs are lost.
+//
+// The mode takes precedence over [WithOverflowPolicy], [WithAsyncWorkers] and [WithReadinessGate],
+// as logs held by the gate could be discarded.
+func WithAuditMode(spill io.Writer) Option {
+	return func(o *options) {
+		o.auditMode = true
+		o.auditSpill = spill
+	}
+}
+
+// WithLogEntryPool recycles entries of logs sent to the server, to reduce allocations when logging heavily.
+//
+// An entry is returned to the pool after its request completes, including retries, or after it's dropped,
+// so entries are held only as long as their request. Logs are sent one per request, so there are no batches
+// holding entries longer. Middlewares (see: [WithLogEntryMiddleware]) must not keep the entry after they return,
+// as it's reused by following logs. By default, entries are not recycled.
+func WithLogEntryPool() Option {
+	return func(o *options) {
+		o.logEntryPool = true
+	}
+}
+
+// WithOnDrop sets a callback invoked with each log dropped because the buffer is full
+// (see: [WithLogOverflowPolicy]) or because it's too large to be sent (see: [ErrPayloadTooLarge]),
+// e.g. to write it to a dead-letter file.
+//
+// The callback of a full buffer is called synchronously by the logging goroutine, so it should be fast.
+// It's not called for logs which failed to be sent (see: [WithLogDeadLetterQueue]).
+func WithOnDrop(fn func(entry LogEntry)) Option {
+	return func(o *options) {
+		o.onDrop = fn
+	}
+}
+
+// WithLogDeadLetterQueue routes logs which failed to be sent, after all HTTP retries, to a dead-letter queue
+// holding up to the given number of logs, so they can be inspected or logged again (see: [Logdash.DeadLetters]).
+//
+// Logs dropped because the buffer is full aren't failed sends, they're passed to [WithOnDrop] instead.
+// When the queue is full, following failed logs are discarded. Instances created by [Logdash.ForProject]
+// have their own queues. By default, failed logs are only reported to the internal logger.
+func WithLogDeadLetterQueue(size int) Option {
+	return func(o *options) {
+		o.deadLetterQueueSize = size
+	}
+}
+
+// WithRemoteFailureThreshold sets the number of consecutive failed sends
+// (after all HTTP retries) after which the remote is considered unhealthy.
+func WithRemoteFailureThreshold(threshold int) Option {
+	return func(o *options) {
+		o.remoteFailureThreshold = threshold
+	}
+}
+
+// WithRemoteRecoveryThreshold sets the number of consecutive successful sends
+// after which the unhealthy remote is considered healthy again (see: [WithOnRemoteHealthy]).
+//
+// It keeps the state from flapping when the remote fails intermittently.
+func WithRemoteRecoveryThreshold(threshold int) Option {
+	return func(o *options) {
+		o.remoteRecoveryThreshold = threshold
+	}
+}
+
+// WithRetryJitter randomizes each HTTP retry interval by ±fraction (0 to 1) of its value.
+//
+// This is useful when many instances retry at the same time after a transient outage,
+// as it spreads their retries over time. Jittered intervals stay within
+// the range set by [WithHTTPRetryMin] and [WithHTTPRetryMax].
+// By default, there is no jitter.
+func WithRetryJitter(fraction float64) Option {
+	return func(o *options) {
+		o.httpRetryJitter = fraction
+	}
+}
+
+// WithLogsMethod sets the HTTP method used to send logs.
+//
+// This is useful when a proxy between the application and the Logdash server
+// doesn't allow the default method. Invalid methods are ignored.
+func WithLogsMethod(method string) Option {
+	return func(o *options) {
+		o.logsMethod = method
+	}
+}
+
+// WithMetricsMethod sets the HTTP method used to send metrics.
+//
+// This is useful when a proxy between the application and the Logdash server
+// doesn't allow the default method. Invalid methods are ignored.
+func WithMetricsMethod(method string) Option {
+	return func(o *options) {
+		o.metricsMethod = method
+	}
+}
+
+// WithMaxMetricNames limits the number of distinct metric names tracked at once.
+//
+// Each metric name is accumulated independently, which costs a goroutine per name.
+// Operations on new metric names beyond the limit are dropped and reported by the internal logger,
+// and their values are not tracked locally (see: [ExtendedMetrics.Snapshot]).
+// By default, there is no limit.
+func WithMaxMetricNames(n int) Option {
+	return func(o *options) {
+		o.maxMetricNames = n
+	}
+}
+
+// WithMaxConcurrentMetricAccumulators limits the number of metric names accumulated at once,
+// which bounds the number of goroutines without dropping metrics (see: [WithMaxMetricNames]).
+//
+// While the limit is reached, operations on other metric names are sent one by one, without accumulation,
+// and following operations wait for the sending (see: [WithMetricsBufferSize]). Accumulators are released
+// when they're idle (see: [WithMetricAccumulatorIdleTimeout]). By default, there is no limit.
+func WithMaxConcurrentMetricAccumulators(n int) Option {
+	return func(o *options) {
+		o.maxMetricAccumulators = n
+	}
+}
+
+// WithMetricAccumulatorIdleTimeout sets the inactivity timeout after which
+// the accumulator of a metric name is stopped to reclaim its resources.
+//
+// Only accumulators which have already sent their pending value are stopped.
+// The accumulator is transparently recreated when the metric name is used again.
+// By default, accumulators are never stopped before [Logdash.Shutdown] or [Logdash.Close].
+func WithMetricAccumulatorIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.metricIdleTimeout = timeout
+	}
+}
+
+// WithMetricCoalesceWindow bounds the time for which changes of a metric are accumulated into a single value.
+//
+// Changes of a metric are accumulated while previous values are being sent to the server.
+// During a long server stall, the accumulated value would arrive as one huge jump once the server recovers.
+// With the window, the accumulated value is queued for sending at least every window
+// and following changes are accumulated separately, which keeps the reported series smooth.
+// By default, changes are accumulated until they can be sent.
+func WithMetricCoalesceWindow(window time.Duration) Option {
+	return func(o *options) {
+		o.metricCoalesceWindow = window
+	}
+}
+
+// WithMetricSnapshotInterval enables the snapshot mode, in which each metric is sent at most once per interval.
+//
+// Changes of a metric within the interval only update its local value: the last set value wins
+// and relative changes are summed up. The value is sent on the next tick, only if the metric changed.
+// This suits gauges which change constantly, e.g. the number of active connections.
+// Pending values are sent on shutdown without waiting for the tick.
+// The mode takes precedence over [WithMetricCoalesceWindow].
+// By default, metrics are sent as soon as possible.
+func WithMetricSnapshotInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.metricSnapshotInterval = interval
+	}
+}
+
+// WithImmediateMetrics sends every [Metrics.Set] and [Metrics.Mutate] as its own request, in order,
+// e.g. when the exact sequence of operations must be audited.
+//
+// By default, operations on a metric are accumulated while previous values are being sent,
+// so a burst of changes results in fewer requests. In this mode, the buffer fills up quicker
+// (see: [WithMetricsBufferSize]): when it's full, callers wait for the sending with [OverflowPolicyBlock],
+// while with [OverflowPolicyDrop] the operation is dropped (see: [WithMetricsOverflowPolicy]).
+// Options of accumulation, e.g. [WithMetricCoalesceWindow] or [WithMetricSnapshotInterval], don't apply.
+func WithImmediateMetrics() Option {
+	return func(o *options) {
+		o.immediateMetrics = true
+	}
+}
+
+// WithMetricResend sets metrics with known absolute values again when the remote becomes healthy
+// after it was unhealthy (see: [WithOnRemoteHealthy]), so values which failed to be sent during the outage
+// are corrected and dashboards heal themselves.
+//
+// Only metrics which were set (see: [Metrics.Set]) are resent, with their last value including following changes.
+// Metrics which were only mutated are not resent, as resending changes would count them twice.
+// By default, metrics are not resent.
+func WithMetricResend() Option {
+	return func(o *options) {
+		o.metricResend = true
+	}
+}
+
+// WithMetricFlushThreshold bounds the magnitude of changes of a metric accumulated into a single value.
+//
+// When accumulated relative changes (see: [Metrics.Mutate]) reach the delta in either direction,
+// the accumulated value is queued for sending immediately, ahead of the following changes,
+// which are accumulated separately. If the sending is busy, e.g. during a server stall, the value
+// waits in the queue, so the spike on recovery is bounded by magnitude rather than by time
+// (see: [WithMetricCoalesceWindow]). In the snapshot mode, the value is sent without waiting for the tick
+// (see: [WithMetricSnapshotInterval]). By default, changes are accumulated regardless of their magnitude.
+func WithMetricFlushThreshold(delta float64) Option {
+	return func(o *options) {
+		o.metricFlushThreshold = delta
+	}
+}
+
+// WithMetricFlushAfterCount bounds the number of changes of a metric accumulated into a single value.
+//
+// When n relative changes (see: [Metrics.Mutate]) are accumulated, the accumulated value is queued
+// for sending immediately, like when it reaches the threshold (see: [WithMetricFlushThreshold]),
+// and the following changes are counted from zero. Changes sent without accumulating aren't counted.
+// By default, changes are accumulated regardless of their number.
+func WithMetricFlushAfterCount(n int) Option {
+	return func(o *options) {
+		o.metricFlushCount = n
+	}
+}
+
+// WithObserveAggregation aggregates observations of each metric (see: [ExtendedMetrics.Observe]) over the interval,
+// and sends summary statistics once per interval instead of every observation, e.g. for the "latency" metric:
+//   - "latency.count" is the number of observations,
+//   - "latency.min", "latency.max" and "latency.mean" are the minimum, maximum and mean of observed values,
+//   - "latency.p50", "latency.p95" and "latency.p99" are percentiles of observed values.
+//
+// Statistics are sent only for metrics observed within the interval. Pending statistics are sent on shutdown.
+//
+// The count, minimum, maximum and mean are exact. To keep the memory flat, percentiles are computed from
+// a uniform random sample of at most 1024 observations per interval, so they're estimates when there are more.
+// The standard error of the rank of an estimate is about 1.6% for p50, 0.7% for p95 and 0.3% for p99,
+// e.g. the estimated p50 is typically between the 48.4th and 51.6th percentile. The error in value
+// depends on the distribution, it's larger where observations are sparse, e.g. in a long tail.
+// By default, every observation sets the metric.
+func WithObserveAggregation(interval time.Duration) Option {
+	return func(o *options) {
+		o.observeAggregation = interval
 	}
+}
 
-	// Option is a function that configures a Logdash instance.
-	Option func(*options)
+// WithMetricsAtomicBatch holds a set of a metric for the window, merging changes following it within the window,
+// so the server receives a single set with the final value, e.g. Set("x", 0) followed by a burst of Mutate("x", 1)
+// is sent as a single set, rather than a dashboard briefly showing the reset to zero before the changes land.
+//
+// Each held set delays sending of the metric by up to the window. Pending sets are sent on shutdown without waiting.
+// It doesn't apply with [WithImmediateMetrics] and it's implied by [WithMetricSnapshotInterval].
+// By default, a set is sent as soon as possible, separately from the following changes.
+func WithMetricsAtomicBatch(window time.Duration) Option {
+	return func(o *options) {
+		o.metricAtomicBatch = window
+	}
+}
 
-	// options contains all the configuration options for Logdash.
-	options struct {
-		host           string
-		apiKey         string
-		verbose        bool
-		bufferSize     int
-		overflowPolicy OverflowPolicy
-		httpTimeout    time.Duration
-		httpRetries    int
-		httpRetryMin   time.Duration
-		httpRetryMax   time.Duration
+// WithMetricPrefix prepends the prefix to names of metrics sent to the server, e.g. with the "checkout." prefix,
+// the "orders" metric is sent as "checkout.orders", so metrics of services sharing a dashboard don't collide.
+//
+// Metrics are used with their names as given, e.g. by [ExtendedMetrics.Snapshot] and [ExtendedMetrics.DeclareUnit],
+// only names sent to the server are prefixed. By default, names are sent as they are.
+func WithMetricPrefix(prefix string) Option {
+	return func(o *options) {
+		o.metricPrefix = prefix
 	}
+}
 
-	// OverflowPolicy defines how to handle log overflow.
-	OverflowPolicy int
-)
+// WithMetricSendRetryOnShutdown retries failed sends of pending metrics during [Logdash.Shutdown],
+// waiting the interval between attempts, until they succeed or the shutdown context is done,
+// so final values of metrics aren't lost because of a transient failure.
+// Only network errors, server errors and rate limiting are retried, other failures are given up immediately.
+//
+// Sends before the shutdown, and after [Logdash.Close], are attempted once (see: [WithHTTPRetries]).
+// By default, failed sends are not retried during shutdown.
+func WithMetricSendRetryOnShutdown(interval time.Duration) Option {
+	return func(o *options) {
+		o.metricShutdownRetry = interval
+	}
+}
 
-const (
-	// OverflowPolicyDrop drops new logs when the internal buffer is full.
-	//
-	// This is the default behavior.
-	OverflowPolicyDrop OverflowPolicy = iota
+// WithMetricRounding rounds values of metrics before they're sent, e.g. to remove floating-point drift
+// of metrics which are conceptually integers (see: [WithIntegerMetrics]).
+//
+// Rounding applies to accumulated values rather than to each change. The remainder of rounding a change
+// is carried over to the following change of the metric, so the total of sent changes doesn't drift.
+// It doesn't apply to [WithImmediateMetrics] and to metrics beyond [WithMaxConcurrentMetricAccumulators].
+// By default, values are sent as they are.
+func WithMetricRounding(round func(float64) float64) Option {
+	return func(o *options) {
+		o.metricRounding = round
+	}
+}
 
-	// OverflowPolicyBlock blocks when the internal buffer is full.
-	//
-	// This is useful when you want to preserve logs even when the internal buffer is full.
-	OverflowPolicyBlock
-)
+// WithIntegerMetrics rounds values of metrics to the nearest integer before they're sent (see: [WithMetricRounding]).
+func WithIntegerMetrics() Option {
+	return WithMetricRounding(math.Round)
+}
 
-var (
-	// DefaultBufferSize is the default size of the buffer for the async queue.
-	DefaultBufferSize = 128
-)
+// WithStatsdMirror mirrors operations on metrics to a statsd or DogStatsD endpoint over UDP, e.g. "localhost:8125",
+// so Logdash can coexist with an existing metrics stack.
+//
+// [Metrics.Set] is mirrored as a gauge, [Metrics.Mutate] as a count, [ExtendedMetrics.Timing] as a timing
+// in milliseconds and [ExtendedMetrics.Observe] as a histogram of raw observations, while summary statistics
+// of [WithObserveAggregation] are not mirrored.
+// Operations are mirrored as they are, before accumulation and independently of sending to the server.
+// Mirroring is asynchronous and never blocks: lines are dropped when the endpoint can't keep up.
+// By default, metrics are not mirrored.
+func WithStatsdMirror(addr string) Option {
+	return func(o *options) {
+		o.statsdMirrorAddr = addr
+	}
+}
 
-// WithHost sets the host for the Logdash server.
-func WithHost(host string) Option {
+// WithResponseCaching suppresses [Metrics.Set] of the value the server already acknowledged for the metric.
+//
+// Unlike [WithMetricDedup], which compares with the last value set locally, the cache holds only values
+// confirmed by successful responses, so a value which failed to be sent is sent again when it's set again.
+// Values are compared right before sending, after previous operations of the metric are sent, so changes
+// of the metric (see: [Metrics.Mutate]) are taken into account. By default, every set value is sent.
+func WithResponseCaching() Option {
 	return func(o *options) {
-		o.host = host
+		o.responseCaching = true
 	}
 }
 
-// WithAPIKey sets the API key for the Logdash server.
-func WithAPIKey(apiKey string) Option {
+// WithMetricDedup suppresses [Metrics.Set] of the value the metric was last set to,
+// e.g. when a gauge is polled and reports the same value every second.
+//
+// The first value of a metric is always sent, and so is a value following [Metrics.Mutate] of the metric.
+// Unlike [WithResponseCaching], values are compared with the last value set locally, not with the last value
+// acknowledged by the server, so a value which failed to be sent isn't sent again until the metric changes.
+// Use [WithMetricDedupWindow] to resend unchanged values periodically. It doesn't apply to [WithImmediateMetrics].
+// By default, every set value is sent, like a heartbeat.
+func WithMetricDedup() Option {
 	return func(o *options) {
-		o.apiKey = apiKey
+		o.metricDedup = true
 	}
 }
 
-// WithVerbose enables verbose logging.
+// WithMetricDedupWindow sets the time after which an unchanged value is sent again despite [WithMetricDedup],
+// so dashboards see the metric is still reported. By default, unchanged values are never sent again.
+func WithMetricDedupWindow(window time.Duration) Option {
+	return func(o *options) {
+		o.metricDedupWindow = window
+	}
+}
+
+// WithStrictMetricTypes enables warnings about metrics which are both set and mutated.
 //
-// This is useful for debugging, showing internal logs and changes in the metrics.
-func WithVerbose() Option {
+// Calling both [Metrics.Set] and [Metrics.Mutate] on the same metric name is usually a bug:
+// the set value resets the metric and following changes are added to it.
+// In the strict mode, the first operation used on each metric name is remembered
+// and a warning is logged once when the other operation is used on that name (see: [WithVerbose]).
+// Metrics are still sent as usual.
+// By default, operations may be mixed freely.
+func WithStrictMetricTypes() Option {
 	return func(o *options) {
-		o.verbose = true
+		o.strictMetricTypes = true
 	}
 }
 
-// WithBufferSize sets the size of the buffer for the async queue.
-func WithBufferSize(size int) Option {
+// WithMetricsRateLimit limits the rate of metric requests sent to the server to perSecond.
+//
+// Requests are spaced out evenly, so bursts of many distinct metrics are smoothed.
+// Changes of a metric are accumulated while waiting, so no changes are lost.
+// The limit doesn't apply when metrics are flushed on shutdown.
+// By default, the rate is not limited.
+func WithMetricsRateLimit(perSecond float64) Option {
 	return func(o *options) {
-		o.bufferSize = size
+		o.metricsRateLimit = perSecond
 	}
 }
 
-// WithOverflowPolicy sets how to handle log overflow.
-func WithOverflowPolicy(policy OverflowPolicy) Option {
+// WithLogOutputShutdownTimeout limits the time [Logdash.Shutdown] waits for each log output,
+// e.g. for pending logs to be sent to the server, so a misbehaving output can't consume the whole shutdown budget.
+//
+// Outputs are shut down concurrently, the context of Shutdown limits all of them.
+// By default, only the context limits the shutdown.
+func WithLogOutputShutdownTimeout(timeout time.Duration) Option {
 	return func(o *options) {
-		o.overflowPolicy = policy
+		o.outputShutdownTimeout = timeout
 	}
 }
 
-// WithHTTPTimeout sets the timeout for HTTP requests.
-func WithHTTPTimeout(timeout time.Duration) Option {
+// WithShutdownHook adds a function run by [Logdash.Shutdown] after pending logs are flushed,
+// e.g. to close a resource which logs while it's being closed.
+//
+// Hooks run in the order they were added, with the context of Shutdown. Once the context is done,
+// remaining hooks are skipped. Errors of hooks are joined with errors of flushing.
+// Logs and metrics are stopped only after the hooks, so logs and metrics of hooks are sent as well.
+func WithShutdownHook(hook func(ctx context.Context) error) Option {
 	return func(o *options) {
-		o.httpTimeout = timeout
+		o.shutdownHooks = append(o.shutdownHooks, hook)
 	}
 }
 
-// WithHTTPRetries sets the number of retries for HTTP requests.
-func WithHTTPRetries(retries int) Option {
+// WithShutdownOrder sets the order in which logs and metrics are flushed by [Logdash.Shutdown].
+//
+// By default, logs and metrics are flushed concurrently (see: [ShutdownConcurrent]).
+func WithShutdownOrder(order ShutdownOrder) Option {
 	return func(o *options) {
-		o.httpRetries = retries
+		o.shutdownOrder = order
 	}
 }
 
-// WithHTTPRetryMin sets the minimum duration for HTTP retries.
-func WithHTTPRetryMin(min time.Duration) Option {
+// WithReadinessGate holds logs sent to the server from the start, until [Logdash.Release] is called,
+// keeping up to size of the most recent logs (see: [Logdash.HoldUntilReady]).
+//
+// The size also bounds logs held by later calls of [Logdash.HoldUntilReady].
+// By default, logs are not held and up to 128 logs are kept while held (see: [DefaultBufferSize]).
+func WithReadinessGate(size int) Option {
 	return func(o *options) {
-		o.httpRetryMin = min
+		o.readinessHeld = true
+		o.readinessQueueSize = size
 	}
 }
 
-// WithHTTPRetryMax sets the maximum duration for HTTP retries.
-func WithHTTPRetryMax(max time.Duration) Option {
+// WithLocalBuffer sets how many of the most recent logs are kept
+// until the API key is set by [Logdash.SetAPIKey].
+//
+// Set to 0 to disable replaying logs emitted before the API key is set.
+// The default size is 128 (see: [DefaultBufferSize]). The buffer grows up to the size as logs are kept,
+// so it takes no memory when the API key is provided to [New].
+func WithLocalBuffer(size int) Option {
 	return func(o *options) {
-		o.httpRetryMax = max
+		o.localBufferSize = size
 	}
 }
 
@@ -131,7 +1502,8 @@ func WithHTTPRetryMax(max time.Duration) Option {
 //
 // By default, the Logdash will use the Logdash API at https://api.logdash.io.
 //
-// If no API key is provided, the Logdash will not send any logs or metrics to the server.
+// If no API key is provided, the Logdash will not send any logs or metrics to the server,
+// until the API key is set by [Logdash.SetAPIKey].
 // Logging to the console is always enabled.
 //
 // The default buffer size is 128 (see: [DefaultBufferSize]).
@@ -139,72 +1511,637 @@ func WithHTTPRetryMax(max time.Duration) Option {
 // The default overflow policy is [OverflowPolicyDrop], to avoid blocking the logging thread.
 // For preserving logs in case of overflow, use [WithOverflowPolicy] to set [OverflowPolicyBlock].
 //
+// Metrics are buffered with the same default buffer size, but their default overflow policy
+// is [OverflowPolicyBlock] to keep metrics accurate (see: [WithMetricsBufferSize] and [WithMetricsOverflowPolicy]).
+//
 // The default HTTP settings are:
 //   - timeout: 5 seconds (see: [WithHTTPTimeout]).
 //   - retries: 3 (see: [WithHTTPRetries]).
 //   - retry minimum interval: 1 second (see: [WithHTTPRetryMin]).
 //   - retry maximum interval: 30 seconds (see: [WithHTTPRetryMax]).
+//   - logs method: POST (see: [WithLogsMethod]).
+//   - metrics method: PUT (see: [WithMetricsMethod]).
+//
+// The remote is considered unhealthy after 3 consecutive failed sends (see: [DefaultRemoteFailureThreshold])
+// and healthy again after 2 consecutive successful sends (see: [DefaultRemoteRecoveryThreshold]).
 func New(opts ...Option) *Logdash {
+	ld, err := newLogdash(opts)
+	if err != nil {
+		ld.internalLogger.ErrorF("%v", err)
+	}
+	if err := ld.probe(); err != nil {
+		ld.internalLogger.ErrorF("%v", err)
+	}
+	return ld
+}
+
+// NewWithError creates a new Logdash instance like [New],
+// but returns an error when the host is invalid (see: [ErrInvalidHost])
+// or the startup probe fails (see: [WithStartupProbe]).
+func NewWithError(opts ...Option) (*Logdash, error) {
+	ld, err := newLogdash(opts)
+	if err != nil {
+		_ = ld.Close()
+		return nil, err
+	}
+	if err := ld.probe(); err != nil {
+		_ = ld.Close()
+		return nil, err
+	}
+	return ld, nil
+}
+
+// newLogdash creates a new Logdash instance without running the startup probe.
+//
+// The instance is created even when the host is invalid, the error is returned along with it.
+func newLogdash(opts []Option) (*Logdash, error) {
 	o := &options{
-		host:           "https://api.logdash.io",
-		bufferSize:     DefaultBufferSize,
-		overflowPolicy: OverflowPolicyDrop,
+		host:               "https://api.logdash.io",
+		bufferSize:         DefaultBufferSize,
+		localBufferSize:    DefaultBufferSize,
+		readinessQueueSize: DefaultBufferSize,
+		overflowPolicy:     OverflowPolicyDrop,
+		logsMethod:         http.MethodPost,
+		metricsMethod:      http.MethodPut,
+		timeSource:         time.Now,
+
+		metricsBufferSize:       DefaultBufferSize,
+		metricsOverflowPolicy:   OverflowPolicyBlock,
+		remoteFailureThreshold:  DefaultRemoteFailureThreshold,
+		remoteRecoveryThreshold: DefaultRemoteRecoveryThreshold,
 	}
 
 	for _, opt := range opts {
 		opt(o)
 	}
 
-	ld := &Logdash{}
+	if o.auditMode {
+		// the audit mode takes precedence over options undermining the order or completeness of logs
+		o.overflowPolicy = OverflowPolicyBlock
+		o.asyncWorkers = 1
+	}
+
+	host, hostErr := normalizeHost(o.host)
+	if hostErr == nil {
+		o.host = host
+	}
+	if o.clockOffset != 0 {
+		now, offset := o.timeSource, o.clockOffset
+		o.timeSource = func() time.Time {
+			return now().Add(offset)
+		}
+	}
+	if o.autoClockSync {
+		o.clockSync = newClockSync(o.timeSource)
+		o.timeSource = o.clockSync.now
+	}
+
+	ld := &Logdash{
+		shutdownOrder: o.shutdownOrder,
+		shutdownHooks: o.shutdownHooks,
+		options:       *o,
+	}
 	ld.setup(o)
-	return ld
+	return ld, hostErr
+}
+
+// normalizeHost returns the host as a URL with http or https scheme, https by default, without trailing slashes.
+func normalizeHost(host string) (string, error) {
+	trimmed := strings.TrimSpace(host)
+	if !strings.Contains(trimmed, "://") {
+		trimmed = "https://" + trimmed
+	}
+	u, err := url.Parse(trimmed)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %w", ErrInvalidHost, host, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%w %q: scheme must be http or https", ErrInvalidHost, host)
+	}
+	if u.Hostname() == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("%w %q: expected scheme, host and optional port and path", ErrInvalidHost, host)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// probe pings the server if the startup probe is enabled.
+func (ld *Logdash) probe() error {
+	if ld.options.startupProbe <= 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ld.options.startupProbe)
+	defer cancel()
+	if err := ld.Ping(ctx); err != nil {
+		return fmt.Errorf("startup probe failed: %w", err)
+	}
+	return nil
+}
+
+// DashboardURL returns the URL of the dashboard where logs and metrics of the project can be viewed.
+//
+// The dashboard is derived from the host (see: [WithHost]), e.g. https://api.logdash.io
+// is viewed at https://app.logdash.io. When the API key is set, the URL points to the project,
+// which is resolved from the server on the first call and cached. The path of the project
+// follows the dashboard of Logdash, which is not a documented API, so it may change.
+//
+// When the project can't be resolved, the URL of the dashboard itself is returned. Resolving takes
+// at most 5 seconds or the HTTP timeout if shorter (see: [WithHTTPTimeout]), and a failed resolution
+// isn't retried for a minute. Concurrent calls return the dashboard itself while the project is resolved.
+func (ld *Logdash) DashboardURL() string {
+	ld.mu.Lock()
+	client := ld.client
+	host := ld.options.host
+	timeout := ld.options.httpTimeout
+	ld.mu.Unlock()
+
+	baseURL := dashboardBaseURL(host)
+	if client == nil {
+		return baseURL
+	}
+	projectID := ld.resolveProject(client, timeout)
+	if projectID == "" {
+		return baseURL
+	}
+	return baseURL + "/projects/" + url.PathEscape(projectID)
+}
+
+const (
+	// defaultDashboardTimeout limits resolving the project of the dashboard.
+	defaultDashboardTimeout = 5 * time.Second
+	// dashboardRetryInterval is the time after a failed resolution of the project until it's resolved again.
+	dashboardRetryInterval = time.Minute
+)
+
+// resolveProject returns the project of the API key, resolving it from the server unless it's cached,
+// being resolved by another call or failed recently. Returns an empty string if it's not resolved.
+func (ld *Logdash) resolveProject(client *httpClient, timeout time.Duration) string {
+	ld.projectMu.Lock()
+	if ld.projectID != "" || ld.projectResolving || time.Since(ld.projectFailedAt) < dashboardRetryInterval {
+		projectID := ld.projectID
+		ld.projectMu.Unlock()
+		return projectID
+	}
+	ld.projectResolving = true
+	ld.projectMu.Unlock()
+
+	if timeout <= 0 {
+		timeout = defaultDashboardTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), min(timeout, defaultDashboardTimeout))
+	defer cancel()
+	projectID, err := client.authenticate(ctx)
+
+	ld.projectMu.Lock()
+	defer ld.projectMu.Unlock()
+	ld.projectResolving = false
+	if err != nil || projectID == "" {
+		ld.internalLogger.VerboseF("Failed to resolve project of the dashboard: %v", err)
+		ld.projectFailedAt = time.Now()
+		return ""
+	}
+	ld.projectID = projectID
+	return projectID
+}
+
+// dashboardBaseURL returns the URL of the dashboard for the host.
+//
+// API hosts prefixed with "api." are served by dashboards prefixed with "app.",
+// other hosts are assumed to serve the dashboard themselves.
+func dashboardBaseURL(host string) string {
+	u, err := url.Parse(host)
+	if err != nil || u.Host == "" {
+		return "https://app.logdash.io"
+	}
+	hostname := u.Hostname()
+	if rest, ok := strings.CutPrefix(hostname, "api."); ok {
+		hostname = "app." + rest
+	}
+	if port := u.Port(); port != "" {
+		hostname = net.JoinHostPort(hostname, port)
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: hostname}).String()
+}
+
+// ForProject returns an instance sending logs and metrics to another project, identified by the API key.
+//
+// The instance shares the HTTP connection pool and retries with this instance, but it has its own
+// logs and metrics queues (see: [Logdash.Stats]). All other options are the same as for this instance.
+// The instance is shut down together with this instance, but it can be shut down on its own as well.
+// When this instance is already shut down or closed, the returned instance is closed as well,
+// so it doesn't start workers which are never stopped.
+func (ld *Logdash) ForProject(apiKey string) *Logdash {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	o := ld.options
+	o.apiKey = apiKey
+	ld.secrets.add(apiKey)
+	project := &Logdash{
+		internalLogger: ld.internalLogger,
+		secrets:        ld.secrets,
+		shutdownOrder:  o.shutdownOrder,
+		options:        o,
+	}
+	if ld.client != nil && apiKey != "" {
+		project.client = ld.client.forProject(&o)
+	} else {
+		project.setupHTTPClient(&o)
+	}
+	project.setupLogger(&o)
+	project.setupMetrics(&o)
+
+	if ld.closed {
+		_ = project.Close()
+		return project
+	}
+	ld.projects = append(ld.projects, project)
+	return project
+}
+
+// Ping checks that the server is reachable and the API key is valid.
+//
+// It returns [ErrNoAPIKey] when the API key is not set and [ErrUnauthorized] when the server rejects it.
+func (ld *Logdash) Ping(ctx context.Context) error {
+	ld.mu.Lock()
+	client := ld.client
+	ld.mu.Unlock()
+
+	if client == nil {
+		return ErrNoAPIKey
+	}
+	_, err := client.authenticate(ctx)
+	return err
 }
 
 func (ld *Logdash) setup(o *options) {
 	ld.setupInternalLogger(o)
+	ld.setupHTTPClient(o)
 	ld.setupLogger(o)
 	ld.setupMetrics(o)
 }
 
 func (ld *Logdash) setupInternalLogger(o *options) {
+	ld.secrets = &secretMasker{}
+	ld.secrets.add(o.apiKey)
 	if o.verbose {
-		ld.internalLogger = newLogger(newConsoleLogger())
+		// messages of the HTTP client may contain the API key, e.g. in headers
+		ld.internalLogger = newLogger(&maskingLogger{syncLogger: newConsoleLogger(o), masker: ld.secrets})
 	} else {
 		ld.internalLogger = newLogger(newNoopLogger())
 	}
 }
 
+func (ld *Logdash) setupHTTPClient(o *options) {
+	if o.apiKey != "" {
+		if !isValidHTTPMethod(o.logsMethod) {
+			ld.internalLogger.ErrorF("Invalid logs HTTP method %q, using %s", o.logsMethod, http.MethodPost)
+			o.logsMethod = http.MethodPost
+		}
+		if !isValidHTTPMethod(o.metricsMethod) {
+			ld.internalLogger.ErrorF("Invalid metrics HTTP method %q, using %s", o.metricsMethod, http.MethodPut)
+			o.metricsMethod = http.MethodPut
+		}
+		ld.client = newHTTPClient(o, ld.internalLogger)
+	}
+}
+
 func (ld *Logdash) setupLogger(o *options) {
+	if o.deadLetterQueueSize > 0 {
+		ld.deadLetters = make(chan LogEntry, o.deadLetterQueueSize)
+	}
 	if o.apiKey != "" {
-		ld.internalLogger.VerboseF("Creating Logger with host %s", o.host)
-		httpLogger := newHTTPLogger(o, ld.internalLogger, o.bufferSize)
-		httpLogger.SetOverflowPolicy(o.overflowPolicy)
 		ld.Logger = newLogger(
-			newConsoleLogger(),
-			httpLogger,
+			withMinLevel(newConsoleLogger(o), o.consoleMinLevel),
+			withMinLevel(ld.newReadinessGate(ld.newHTTPLogger(o), o), o.remoteMinLevel),
 		)
 	} else {
 		ld.internalLogger.Warn("No API key provided, using local logger only")
-		ld.Logger = newLogger(newConsoleLogger())
+		ld.deferredLogger = newDeferredLogger(o.localBufferSize)
+		var console syncLogger = newConsoleLogger(o)
+		if o.silentWhenUnconfigured {
+			console = newNoopLogger()
+		}
+		ld.Logger = newLogger(
+			withMinLevel(console, o.consoleMinLevel),
+			withMinLevel(ld.newReadinessGate(ld.deferredLogger, o), o.remoteMinLevel),
+		)
+	}
+	for _, sink := range o.sinks {
+		ld.Logger.loggers = append(ld.Logger.loggers, &sinkLogger{sink: sink})
+	}
+	ld.Logger.internalLogger = ld.internalLogger
+	ld.Logger.prefix = o.messagePrefix
+	ld.Logger.suffix = o.messageSuffix
+	if o.messageTemplate != "" {
+		ld.Logger.template = newMessageTemplate(o.messageTemplate, o.templateFallback)
+	}
+	ld.Logger.now = o.timeSource
+	ld.Logger.filters = o.logFilters
+	ld.Logger.fields = mergeFields(o.deploymentMetadata, o.fields)
+	ld.Logger.outputShutdownTimeout = o.outputShutdownTimeout
+	ld.Logger.httpHeaders = o.httpRequestHeaders
+	ld.Logger.sampler = o.sampler
+	ld.Logger.traceSampled = o.traceSampled
+	ld.Logger.baggageKeys = o.baggageKeys
+	ld.Logger.baggageSource = o.baggageSource
+	if o.source {
+		sourceLevel := o.sourceMinLevel
+		ld.Logger.sourceLevel = &sourceLevel
+	}
+	if o.runtimeStatsOnError {
+		ld.Logger.runtimeStats = newRuntimeStats(runtimeStatsInterval)
+	}
+	if o.exitFunc != nil {
+		ld.Logger.exit = o.exitFunc
+	}
+	if o.minLevel != "" {
+		ld.Logger.setMinLevel(o.minLevel)
+	}
+	ld.internalLogger.VerboseF("Logs and metrics can be viewed at %s", dashboardBaseURL(o.host))
+}
+
+func (ld *Logdash) newHTTPLogger(o *options) *httpLogger {
+	ld.internalLogger.VerboseF("Creating Logger with host %s", o.host)
+	httpLogger := newHTTPLogger(o, ld.client, ld.internalLogger)
+	httpLogger.SetOverflowPolicy(o.overflowPolicy)
+	httpLogger.deadLetters = ld.deadLetters
+	ld.httpLogger = httpLogger
+	return httpLogger
+}
+
+// newReadinessGate wraps the remote output in the gate of [Logdash.HoldUntilReady].
+func (ld *Logdash) newReadinessGate(remote syncLogger, o *options) *readinessGate {
+	ld.readinessGate = newReadinessGate(remote, o.readinessQueueSize)
+	// held logs could be discarded, so audit mode sends them right away
+	ld.readinessGate.disabled = o.auditMode
+	ld.readinessGate.onDrop = func() {
+		ld.mu.Lock()
+		client := ld.client
+		ld.mu.Unlock()
+		if client != nil {
+			client.recordDrop("/logs")
+		}
+	}
+	if o.readinessHeld {
+		ld.readinessGate.hold()
+	}
+	return ld.readinessGate
+}
+
+// HoldUntilReady holds logs sent to the server until [Logdash.Release] is called,
+// e.g. until the configuration of the application is loaded, so no half-configured state is sent.
+//
+// Held logs are still printed to the console. Up to the size of the queue of held logs (see: [WithReadinessGate]),
+// the most recent logs are kept and sent in order on release, older logs are discarded.
+// Held logs are released on [Logdash.Shutdown] as well, while [Logdash.Close] discards them.
+// Discarded logs are counted as dropped (see: [Logdash.RatePerSecond]). Metrics are not held.
+// In audit mode, logs are never held (see: [WithAuditMode]).
+// Unlike setting the API key later (see: [Logdash.SetAPIKey]), the gate can be held again after it's released.
+func (ld *Logdash) HoldUntilReady() {
+	ld.readinessGate.hold()
+}
+
+// Release sends logs held since [Logdash.HoldUntilReady] in order, and sends following logs as usual.
+//
+// It does nothing if logs aren't held.
+func (ld *Logdash) Release() {
+	ld.readinessGate.release()
+}
+
+// ResizeLogBuffer changes the size of the buffer of logs waiting to be sent at runtime (see: [WithBufferSize]),
+// e.g. to absorb a spike of logs which would otherwise be dropped.
+//
+// Buffered logs are moved to the new buffer, none of them is lost. They're sent after logs being sent
+// during the resize, so logs keep their order, e.g. in audit mode (see: [WithAuditMode]). Returns [ErrBufferTooSmall] if the new size is smaller than the number of buffered logs.
+// If no API key is set, the size applies to the buffer created when the API key is set (see: [Logdash.SetAPIKey]).
+// It's safe to call concurrently with logging.
+func (ld *Logdash) ResizeLogBuffer(newSize int) error {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	if ld.httpLogger == nil {
+		if newSize < 0 {
+			return fmt.Errorf("%w: negative size %d", ErrBufferTooSmall, newSize)
+		}
+		ld.options.bufferSize = newSize
+		return nil
+	}
+	if err := ld.httpLogger.resizeBuffer(newSize); err != nil {
+		return err
 	}
+	ld.options.bufferSize = newSize
+	return nil
+}
+
+// ExtendedMetrics returns the metrics object created for [Logdash.Metrics] with extended operations,
+// e.g. [ExtendedMetrics.Snapshot]. It's returned even if [Logdash.Metrics] is replaced.
+func (ld *Logdash) ExtendedMetrics() ExtendedMetrics {
+	return ld.extendedMetrics
+}
+
+// DeadLetters returns the dead-letter queue receiving logs which failed to be sent (see: [WithLogDeadLetterQueue]).
+//
+// It returns nil when the dead-letter queue isn't enabled. The channel is never closed.
+func (ld *Logdash) DeadLetters() <-chan LogEntry {
+	return ld.deadLetters
 }
 
 func (ld *Logdash) setupMetrics(o *options) {
-	var innerMetrics Metrics
+	var innerMetrics metricsBackend
+
+	ld.metricUnits = newMetricUnits(ld.internalLogger)
+	if o.strictMetricTypes {
+		ld.metricOperations = newMetricOperations()
+	}
 
 	if o.apiKey != "" {
-		ld.internalLogger.VerboseF("Creating Metrics with host %s", o.host)
-		httpMetrics := newHTTPMetrics(o, ld.internalLogger)
-		innerMetrics = httpMetrics
+		innerMetrics = ld.newHTTPMetrics(o)
 	} else {
 		ld.internalLogger.Warn("No API key provided, using noop metrics")
-		innerMetrics = noopMetrics{}
+		ld.deferredMetrics = newDeferredMetrics()
+		innerMetrics = ld.deferredMetrics
+	}
+
+	localMetrics := newLocalMetrics(newVerboseLogMetricsWrapper(ld.internalLogger, innerMetrics))
+	localMetrics.now = o.timeSource
+	localMetrics.units = ld.metricUnits
+	localMetrics.maxNames = o.maxMetricNames
+	localMetrics.operations = ld.metricOperations
+	if o.observeAggregation > 0 {
+		localMetrics.aggregator = newObserveAggregator(o.observeAggregation, observeReservoirSize, localMetrics.set)
+	}
+	if o.statsdMirrorAddr != "" {
+		mirror, err := newStatsdMirror(o.statsdMirrorAddr, ld.internalLogger)
+		if err != nil {
+			ld.internalLogger.ErrorF("Failed to mirror metrics to statsd at %s: %v", o.statsdMirrorAddr, err)
+		} else {
+			localMetrics.mirror = mirror
+		}
+	}
+	ld.Metrics = localMetrics
+	ld.extendedMetrics = localMetrics
+}
+
+func (ld *Logdash) newHTTPMetrics(o *options) *httpMetrics {
+	ld.internalLogger.VerboseF("Creating Metrics with host %s", o.host)
+	metrics := newHTTPMetrics(o, ld.client, ld.internalLogger)
+	metrics.units = ld.metricUnits
+	metrics.operations = ld.metricOperations
+	return metrics
+}
+
+// SetAPIKey sets the API key when it wasn't provided to [New], e.g. when it's fetched
+// from a secret manager after startup.
+//
+// Logs emitted before the API key is set are sent to the server,
+// up to the size of the local buffer (see: [WithLocalBuffer]).
+// Metrics changed before the API key is set are not sent.
+//
+// Returns [ErrAPIKeyAlreadySet] if the API key is already set, [ErrEmptyAPIKey] if the API key is empty,
+// and [ErrAlreadyClosed] if the instance is already shut down or closed.
+func (ld *Logdash) SetAPIKey(apiKey string) error {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	if ld.closed {
+		return ErrAlreadyClosed
+	}
+	if ld.client != nil {
+		return ErrAPIKeyAlreadySet
+	}
+	if apiKey == "" {
+		return ErrEmptyAPIKey
+	}
+
+	o := ld.options
+	o.apiKey = apiKey
+	ld.secrets.add(apiKey)
+	ld.setupHTTPClient(&o)
+	ld.deferredLogger.activate(ld.newHTTPLogger(&o))
+	ld.deferredMetrics.activate(ld.newHTTPMetrics(&o))
+	return nil
+}
+
+// SetHTTPRetryConfig changes the number of HTTP retries and the bounds of the wait between them at runtime
+// (see: [WithHTTPRetries], [WithHTTPRetryMin], [WithHTTPRetryMax]).
+//
+// The change applies to both logs and metrics, and to all projects sharing the retries (see: [Logdash.ForProject]).
+// Requests in flight complete with the previous configuration, subsequent requests use the new one.
+// It's safe to call concurrently with logging and metrics.
+func (ld *Logdash) SetHTTPRetryConfig(retries int, min, max time.Duration) {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	ld.options.httpRetries = retries
+	ld.options.httpRetryMin = min
+	ld.options.httpRetryMax = max
+	if ld.client != nil {
+		ld.client.setRetryConfig(&ld.options)
+	}
+}
+
+// Stats returns statistics of the Logdash SDK itself,
+// e.g. the number and latency of HTTP requests sent to the Logdash server.
+//
+// If no API key is set, all statistics are zero.
+func (ld *Logdash) Stats() Stats {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	if ld.client == nil {
+		return Stats{}
+	}
+	return Stats{
+		Logs:    ld.client.requestStats("/logs"),
+		Metrics: ld.client.requestStats("/metrics"),
 	}
+}
+
+// SetMinLevel changes the minimum level of logs at runtime, less severe logs are discarded
+// (see: [WithMinLevel]).
+//
+// Unknown levels are reported through the internal logger (see: [WithVerbose]) and ignored,
+// so the minimum level doesn't change. It's safe to call concurrently with logging,
+// e.g. from a signal handler or an admin endpoint.
+func (ld *Logdash) SetMinLevel(level Level) {
+	ld.Logger.setMinLevel(level)
+}
+
+// RatePerSecond returns current rates of logs and metrics sent and dropped by the SDK.
+//
+// Unlike [Logdash.Stats], rates reflect the load of about the last minute (see: [Rates]),
+// e.g. to alert on sustained drops rather than on lifetime totals.
+func (ld *Logdash) RatePerSecond() Rates {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
 
-	ld.Metrics = newVerboseLogMetricsWrapper(ld.internalLogger, innerMetrics)
+	if ld.client == nil {
+		return Rates{}
+	}
+	return Rates{
+		Logs:    ld.client.requestRates("/logs"),
+		Metrics: ld.client.requestRates("/metrics"),
+	}
 }
 
+// Shutdown flushes all pending logs and metrics and stops background workers.
+//
+// The order of flushing logs and metrics is set by [WithShutdownOrder].
+// Instances created by [Logdash.ForProject] are shut down as well.
 func (ld *Logdash) Shutdown(ctx context.Context) error {
+	// hooks run while logs and metrics are still accepted, so their own logs are sent as well
+	hooksErr := ld.runShutdownHooks(ctx)
+
+	errg, _ := errgroup.WithContext(ctx)
+	for _, project := range ld.takeProjects() {
+		errg.Go(func() error {
+			return ignoreAlreadyClosed(project.Shutdown(ctx))
+		})
+	}
+	errg.Go(func() error {
+		return ld.shutdown(ctx)
+	})
+	err := errg.Wait()
+	return errors.Join(err, hooksErr)
+}
+
+// runShutdownHooks flushes logs and runs the shutdown hooks in order, until the context is done.
+func (ld *Logdash) runShutdownHooks(ctx context.Context) error {
+	// hooks run only on the first shutdown
+	ld.mu.Lock()
+	hooks := ld.shutdownHooks
+	ld.shutdownHooks = nil
+	ld.mu.Unlock()
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if err := ld.Logger.flush(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	for _, hook := range hooks {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hooks skipped: %w", err))
+			break
+		}
+		errs = append(errs, hook(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (ld *Logdash) shutdown(ctx context.Context) error {
+	defer ld.stopClient()
+
+	switch ld.shutdownOrder {
+	case ShutdownLogsFirst:
+		return errors.Join(ld.Logger.Shutdown(ctx), ld.Metrics.Shutdown(ctx))
+	case ShutdownMetricsFirst:
+		return errors.Join(ld.Metrics.Shutdown(ctx), ld.Logger.Shutdown(ctx))
+	}
+
 	errg, _ := errgroup.WithContext(ctx)
 	errg.Go(func() error {
 		return ld.Logger.Shutdown(ctx)
@@ -215,9 +2152,51 @@ func (ld *Logdash) Shutdown(ctx context.Context) error {
 	return errg.Wait()
 }
 
+// Close stops background workers immediately, without flushing pending logs and metrics.
+//
+// Instances created by [Logdash.ForProject] are closed as well.
 func (ld *Logdash) Close() error {
 	errg, _ := errgroup.WithContext(context.Background())
+	for _, project := range ld.takeProjects() {
+		errg.Go(func() error {
+			return ignoreAlreadyClosed(project.Close())
+		})
+	}
 	errg.Go(ld.Logger.Close)
 	errg.Go(ld.Metrics.Close)
-	return errg.Wait()
+	err := errg.Wait()
+	ld.stopClient()
+	return err
+}
+
+// stopClient stops background work of the HTTP client, if it's set.
+func (ld *Logdash) stopClient() {
+	ld.mu.Lock()
+	client := ld.client
+	ld.mu.Unlock()
+
+	if client != nil {
+		client.stop()
+	}
+}
+
+// takeProjects returns instances created by [Logdash.ForProject] and forgets them,
+// so they're stopped only once. It marks the instance as closed, so following instances
+// of projects are closed right away and the API key can't be set anymore.
+func (ld *Logdash) takeProjects() []*Logdash {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	ld.closed = true
+	projects := ld.projects
+	ld.projects = nil
+	return projects
+}
+
+// ignoreAlreadyClosed ignores the error of instances which were already stopped on their own.
+func ignoreAlreadyClosed(err error) error {
+	if errors.Is(err, ErrAlreadyClosed) {
+		return nil
+	}
+	return err
 }
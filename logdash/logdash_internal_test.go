@@ -0,0 +1,278 @@
+package logdash
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	// shutdownRecorder records the order in which resources finished shutdown.
+	shutdownRecorder struct {
+		mu       sync.Mutex
+		finished []string
+	}
+
+	// recordingResource is a resource which takes delay to shut down.
+	recordingResource struct {
+		noopResourceManager
+		name     string
+		delay    time.Duration
+		recorder *shutdownRecorder
+	}
+
+	recordingLogger struct {
+		recordingResource
+	}
+
+	recordingMetrics struct {
+		recordingResource
+	}
+)
+
+func (r *recordingResource) Shutdown(ctx context.Context) error {
+	time.Sleep(r.delay)
+	r.recorder.mu.Lock()
+	defer r.recorder.mu.Unlock()
+	r.recorder.finished = append(r.recorder.finished, r.name)
+	return nil
+}
+
+func (l *recordingLogger) syncLog(timestamp time.Time, level Level, message string, data map[string]any) {
+}
+
+func (m *recordingMetrics) Set(name string, value float64) {}
+
+func (m *recordingMetrics) Mutate(name string, value float64) {}
+
+func TestLogdashShutdownOrder(t *testing.T) {
+	testCases := []struct {
+		name             string
+		order            ShutdownOrder
+		logsDelay        time.Duration
+		metricsDelay     time.Duration
+		expectedFinished []string
+	}{
+		{
+			name:             "should flush logs and metrics concurrently",
+			order:            ShutdownConcurrent,
+			logsDelay:        20 * time.Millisecond,
+			expectedFinished: []string{"metrics", "logs"},
+		},
+		{
+			name:             "should flush logs first",
+			order:            ShutdownLogsFirst,
+			logsDelay:        20 * time.Millisecond,
+			expectedFinished: []string{"logs", "metrics"},
+		},
+		{
+			name:             "should flush metrics first",
+			order:            ShutdownMetricsFirst,
+			metricsDelay:     20 * time.Millisecond,
+			expectedFinished: []string{"metrics", "logs"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			recorder := &shutdownRecorder{}
+			ld := &Logdash{
+				Logger: newLogger(&recordingLogger{recordingResource{
+					name: "logs", delay: tc.logsDelay, recorder: recorder,
+				}}),
+				Metrics: newLocalMetrics(&recordingMetrics{recordingResource{
+					name: "metrics", delay: tc.metricsDelay, recorder: recorder,
+				}}),
+				shutdownOrder: tc.order,
+			}
+
+			// WHEN
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedFinished, recorder.finished)
+		})
+	}
+}
+
+func TestDashboardBaseURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		host     string
+		expected string
+	}{
+		{
+			name:     "should map the default API host to the dashboard host",
+			host:     "https://api.logdash.io",
+			expected: "https://app.logdash.io",
+		},
+		{
+			name:     "should drop trailing slash and path",
+			host:     "https://api.logdash.io/v1/",
+			expected: "https://app.logdash.io",
+		},
+		{
+			name:     "should keep scheme and port",
+			host:     "http://api.example.com:8080",
+			expected: "http://app.example.com:8080",
+		},
+		{
+			name:     "should keep host without API prefix",
+			host:     "http://localhost:3000",
+			expected: "http://localhost:3000",
+		},
+		{
+			name:     "should fallback to the default dashboard for invalid host",
+			host:     "not a host",
+			expected: "https://app.logdash.io",
+		},
+		{
+			name:     "should fallback to the default dashboard for empty host",
+			host:     "",
+			expected: "https://app.logdash.io",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, dashboardBaseURL(tc.host))
+		})
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	testCases := []struct {
+		name          string
+		host          string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:     "should keep valid host",
+			host:     "https://api.logdash.io",
+			expected: "https://api.logdash.io",
+		},
+		{
+			name:     "should add https scheme to schemeless host",
+			host:     "api.logdash.io",
+			expected: "https://api.logdash.io",
+		},
+		{
+			name:     "should add https scheme to schemeless host with port",
+			host:     "localhost:3000",
+			expected: "https://localhost:3000",
+		},
+		{
+			name:     "should keep explicit http scheme",
+			host:     "http://localhost:3000",
+			expected: "http://localhost:3000",
+		},
+		{
+			name:     "should strip trailing slashes",
+			host:     "https://api.logdash.io//",
+			expected: "https://api.logdash.io",
+		},
+		{
+			name:     "should keep path without trailing slash",
+			host:     " https://example.com/logdash/ ",
+			expected: "https://example.com/logdash",
+		},
+		{
+			name:          "should reject empty host",
+			host:          "",
+			expectedError: true,
+		},
+		{
+			name:          "should reject host with spaces",
+			host:          "not a host",
+			expectedError: true,
+		},
+		{
+			name:          "should reject unsupported scheme",
+			host:          "ftp://api.logdash.io",
+			expectedError: true,
+		},
+		{
+			name:          "should reject host with query",
+			host:          "https://api.logdash.io?key=value",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			host, err := normalizeHost(tc.host)
+			if tc.expectedError {
+				assert.ErrorIs(t, err, ErrInvalidHost)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, host)
+		})
+	}
+}
+
+// blockingLogger is a log output which doesn't shut down until its context is done.
+type blockingLogger struct {
+	noopResourceManager
+	err error
+}
+
+func (l *blockingLogger) Shutdown(ctx context.Context) error {
+	<-ctx.Done()
+	return l.err
+}
+
+func (l *blockingLogger) syncLog(timestamp time.Time, level Level, message string, data map[string]any) {
+}
+
+func TestLoggerShutdownOutputs(t *testing.T) {
+	t.Run("should not wait for slow output to shut down fast output", func(t *testing.T) {
+		// GIVEN
+		recorder := &shutdownRecorder{}
+		slowErr := errors.New("slow output error")
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		logger := newLogger(
+			&blockingLogger{err: slowErr},
+			&recordingLogger{recordingResource{name: "fast", recorder: recorder}},
+		)
+
+		// WHEN
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- logger.Shutdown(ctx)
+		}()
+
+		// THEN
+		assert.Eventually(t, func() bool {
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			return len(recorder.finished) == 1
+		}, 50*time.Millisecond, time.Millisecond)
+		assert.ErrorIs(t, <-errCh, slowErr)
+	})
+
+	t.Run("should limit shutdown of each output and join errors", func(t *testing.T) {
+		// GIVEN
+		firstErr := errors.New("first output error")
+		secondErr := errors.New("second output error")
+		logger := newLogger(&blockingLogger{err: firstErr}, &blockingLogger{err: secondErr})
+		logger.outputShutdownTimeout = 20 * time.Millisecond
+
+		// WHEN
+		start := time.Now()
+		err := logger.Shutdown(context.Background())
+
+		// THEN
+		assert.Less(t, time.Since(start), time.Second)
+		assert.ErrorIs(t, err, firstErr)
+		assert.ErrorIs(t, err, secondErr)
+	})
+}
@@ -3,9 +3,12 @@ package logdash_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
@@ -119,6 +122,125 @@ func TestLogdashShutdownImmediatelly(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestLogdashShutdownHook(t *testing.T) {
+	t.Run("should run hooks in order after the last log is sent and propagate their errors", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		hookErr := errors.New("close failed")
+		var calls []string
+		var requestsSeenByHook int
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithShutdownHook(func(ctx context.Context) error {
+				requestsCollector.mu.Lock()
+				defer requestsCollector.mu.Unlock()
+				requestsSeenByHook = len(requestsCollector.requests)
+				calls = append(calls, "first")
+				return nil
+			}),
+			logdash.WithShutdownHook(func(ctx context.Context) error {
+				calls = append(calls, "second")
+				return hookErr
+			}),
+		)
+		ld.Logger.Info("last log")
+
+		// WHEN
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.ErrorIs(t, err, hookErr)
+		assert.Equal(t, []string{"first", "second"}, calls)
+		assert.Equal(t, 1, requestsSeenByHook)
+	})
+
+	t.Run("should skip hooks when shutdown context is done", func(t *testing.T) {
+		// GIVEN
+		called := false
+		ld := logdash.New(
+			logdash.WithShutdownHook(func(ctx context.Context) error {
+				called = true
+				return nil
+			}),
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// WHEN
+		err := ld.Shutdown(ctx)
+
+		// THEN
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, called)
+	})
+
+	t.Run("should send logs of hooks with blocking overflow policy", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		var ld *logdash.Logdash
+		ld = logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+			logdash.WithShutdownHook(func(ctx context.Context) error {
+				ld.Logger.Info("goodbye")
+				return nil
+			}),
+		)
+
+		// WHEN
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		err := ld.Shutdown(ctx)
+
+		// THEN
+		assert.NoError(t, err)
+		if assert.Len(t, requestsCollector.requests, 1) {
+			assert.Contains(t, string(requestsCollector.requests[0].body), "goodbye")
+		}
+	})
+
+	t.Run("should report logs after shutdown as already closed", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+		assert.NoError(t, ld.Shutdown(context.Background()))
+
+		// WHEN
+		err := ld.Logger.TryInfo("too late")
+
+		// THEN
+		assert.ErrorIs(t, err, logdash.ErrAlreadyClosed)
+	})
+}
+
 func TestLogdashMetricMetric(t *testing.T) {
 	t.Run("should send one set metric command to the server", func(t *testing.T) {
 		// GIVEN
@@ -137,6 +259,8 @@ func TestLogdashMetricMetric(t *testing.T) {
 			logdash.WithHost(httpServer.URL),
 			logdash.WithAPIKey("test-api-key"),
 			logdash.WithVerbose(),
+			// unbuffered dispatch hands the first metric to the sending loop before following ones
+			logdash.WithMetricsBufferSize(0),
 		)
 
 		beforeMetricSent := time.Now()
@@ -189,6 +313,8 @@ func TestLogdashMetricMetric(t *testing.T) {
 				logdash.WithHost(httpServer.URL),
 				logdash.WithAPIKey("test-api-key"),
 				logdash.WithVerbose(),
+				// unbuffered dispatch hands the first metric to the sending loop before following ones
+				logdash.WithMetricsBufferSize(0),
 			)
 
 			beforeMetricSent := time.Now()
@@ -253,6 +379,8 @@ func TestLogdashMetricMetric(t *testing.T) {
 				logdash.WithHost(httpServer.URL),
 				logdash.WithAPIKey("test-api-key"),
 				logdash.WithVerbose(),
+				// unbuffered dispatch hands the first metric to the sending loop before following ones
+				logdash.WithMetricsBufferSize(0),
 			)
 
 			beforeMetricSent := time.Now()
@@ -290,3 +418,524 @@ func TestLogdashMetricMetric(t *testing.T) {
 		})
 	})
 }
+
+func TestLogdashTimeSource(t *testing.T) {
+	t.Run("should use the same clock for logs and metrics", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		fixedTime := time.Date(2024, 5, 17, 12, 30, 45, 123456789, time.FixedZone("CEST", 2*60*60))
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithTimeSource(func() time.Time { return fixedTime }),
+		)
+
+		// WHEN
+		ld.Logger.Info("Hello, clock!")
+		ld.Metrics.Set("test-metric", 1)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 2)
+		timestamps := make(map[string]string)
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			switch r.request.URL.Path {
+			case "/logs":
+				timestamps["log"] = body["createdAt"].(string)
+			case "/metrics":
+				timestamps["metric"] = body["timestamp"].(string)
+			}
+		}
+		assert.Equal(t, "2024-05-17T10:30:45.123456789Z", timestamps["log"])
+		assert.Equal(t, timestamps["log"], timestamps["metric"])
+	})
+}
+
+func TestLogdashClockOffset(t *testing.T) {
+	t.Run("should shift timestamps of logs by the offset relative to real time", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		const offset = -time.Hour
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithClockOffset(offset),
+		)
+
+		// WHEN
+		before := time.Now()
+		ld.Logger.Info("Hello, skewed clock!")
+		after := time.Now()
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		createdAt, err := time.Parse(time.RFC3339Nano, body["createdAt"].(string))
+		assert.NoError(t, err)
+		assert.False(t, createdAt.Before(before.Add(offset)), "createdAt %v before %v", createdAt, before.Add(offset))
+		assert.False(t, createdAt.After(after.Add(offset)), "createdAt %v after %v", createdAt, after.Add(offset))
+	})
+}
+
+func TestLogdashAutoClockSync(t *testing.T) {
+	// createdAt returns the timestamp of the collected log request.
+	createdAt := func(t *testing.T, r requestAndBody) time.Time {
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(r.body, &body))
+		timestamp, err := time.Parse(time.RFC3339Nano, body["createdAt"].(string))
+		assert.NoError(t, err)
+		return timestamp
+	}
+
+	t.Run("should correct timestamps toward the Date header of the server", func(t *testing.T) {
+		// GIVEN
+		localTime := time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)
+		serverTime := localTime.Add(time.Hour)
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.Header().Set("Date", serverTime.Format(http.TimeFormat))
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithTimeSource(func() time.Time { return localTime }),
+			logdash.WithAutoClockSync(),
+		)
+		defer ld.Close()
+
+		// WHEN
+		ld.Logger.Info("Before sync")
+
+		// THEN
+		assert.Eventually(t, func() bool {
+			ld.Logger.Info("After sync")
+			requestsCollector.mu.Lock()
+			defer requestsCollector.mu.Unlock()
+			n := len(requestsCollector.requests)
+			return n > 1 && createdAt(t, requestsCollector.requests[n-1]).Sub(serverTime).Abs() < time.Second
+		}, time.Second, 10*time.Millisecond)
+		requestsCollector.mu.Lock()
+		defer requestsCollector.mu.Unlock()
+		assert.Equal(t, localTime, createdAt(t, requestsCollector.requests[0]))
+	})
+
+	t.Run("should not correct skew below resolution of the Date header", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		localTime := time.Now()
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithTimeSource(func() time.Time { return localTime }),
+			logdash.WithAutoClockSync(),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+		)
+
+		// WHEN
+		for range 5 {
+			ld.Logger.Info("Hello, synced clock!")
+			time.Sleep(10 * time.Millisecond)
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 5)
+		for _, r := range requestsCollector.requests {
+			assert.Equal(t, localTime.UTC(), createdAt(t, r))
+		}
+	})
+}
+
+func TestLogdashForProject(t *testing.T) {
+	t.Run("should send logs and metrics with API key of each project", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("first-api-key"),
+		)
+		project := ld.ForProject("second-api-key")
+
+		// WHEN
+		ld.Logger.Info("first")
+		project.Logger.Info("second")
+		project.Metrics.Set("second-metric", 1)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		sent := map[string][]string{}
+		for _, rb := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(rb.body, &body))
+			name := body["message"]
+			if rb.request.URL.Path == "/metrics" {
+				name = body["name"]
+			}
+			apiKey := rb.request.Header.Get("project-api-key")
+			sent[apiKey] = append(sent[apiKey], name.(string))
+		}
+		assert.Equal(t, []string{"first"}, sent["first-api-key"])
+		assert.ElementsMatch(t, []string{"second", "second-metric"}, sent["second-api-key"])
+	})
+
+	t.Run("should shut down project handle on its own", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New(
+			logdash.WithHost("http://localhost:1"),
+			logdash.WithAPIKey("first-api-key"),
+		)
+		project := ld.ForProject("second-api-key")
+
+		// WHEN
+		projectErr := project.Shutdown(context.Background())
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, projectErr)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should return closed project handle after shutdown", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New(
+			logdash.WithHost("http://localhost:1"),
+			logdash.WithAPIKey("first-api-key"),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+		assert.NoError(t, ld.Shutdown(context.Background()))
+
+		// WHEN
+		project := ld.ForProject("second-api-key")
+
+		// THEN
+		assert.ErrorIs(t, project.Logger.TryInfo("after shutdown"), logdash.ErrAlreadyClosed)
+		assert.ErrorIs(t, project.Shutdown(context.Background()), logdash.ErrAlreadyClosed)
+	})
+}
+
+func TestLogdashMinimalMode(t *testing.T) {
+	// goroutinesInUse returns the number of goroutines started by the instance
+	// after it sent a log and operations on the given number of metric names.
+	goroutinesInUse := func(t *testing.T, names int, opts ...logdash.Option) int {
+		t.Helper()
+
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		goroutinesBefore := runtime.NumGoroutine()
+		ld := logdash.New(append([]logdash.Option{
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		}, opts...)...)
+		defer ld.Shutdown(context.Background())
+
+		ld.Logger.Info("Hello, minimal mode!")
+		for i := range names {
+			ld.Metrics.Set(fmt.Sprintf("metric-%d", i), float64(i))
+		}
+		assert.Eventually(t, func() bool {
+			requestsCollector.mu.Lock()
+			defer requestsCollector.mu.Unlock()
+			return len(requestsCollector.requests) == names+1
+		}, time.Second, 10*time.Millisecond)
+		return runtime.NumGoroutine() - goroutinesBefore
+	}
+
+	t.Run("should use fewer goroutines than default configuration", func(t *testing.T) {
+		// GIVEN
+		const names = 20
+
+		// WHEN
+		defaultGoroutines := goroutinesInUse(t, names)
+		minimalGoroutines := goroutinesInUse(t, names, logdash.WithMinimalMode())
+
+		// THEN
+		// the default configuration accumulates each metric name in its own goroutine
+		assert.LessOrEqual(t, minimalGoroutines, defaultGoroutines-names)
+		// a log worker, a metrics dispatcher, waiting for workers and HTTP connections
+		assert.Less(t, minimalGoroutines, 10)
+	})
+
+	t.Run("should allow overriding preset by following options", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMinimalMode(),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+			logdash.WithBufferSize(100),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+		)
+
+		// WHEN
+		for range 50 {
+			ld.Logger.Info("Hello, minimal mode!")
+			ld.Metrics.Mutate("requests", 1)
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		var logs, metrics int
+		for _, r := range requestsCollector.requests {
+			if r.request.URL.Path == "/logs" {
+				logs++
+			} else {
+				metrics++
+			}
+		}
+		assert.Equal(t, 50, logs)
+		// metrics aren't accumulated, so every operation is sent
+		assert.Equal(t, 50, metrics)
+	})
+}
+
+func TestLogdashSilentWhenUnconfigured(t *testing.T) {
+	testCases := []struct {
+		name           string
+		opts           []logdash.Option
+		expectedOutput bool
+	}{
+		{
+			name:           "should print logs to console without API key by default",
+			expectedOutput: true,
+		},
+		{
+			name: "should not print anything to console without API key",
+			opts: []logdash.Option{logdash.WithSilentWhenUnconfigured()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			sink := logdash.NewMemorySink()
+
+			// WHEN
+			output := captureStdout(t, func() {
+				ld := logdash.New(append([]logdash.Option{logdash.WithSink(sink)}, tc.opts...)...)
+				ld.Logger.Info("Hello, silent mode!")
+				ld.Logger.Error("Something failed")
+				err := ld.Shutdown(context.Background())
+				assert.NoError(t, err)
+			})
+
+			// THEN
+			if tc.expectedOutput {
+				assert.Contains(t, output, "Hello, silent mode!")
+			} else {
+				assert.Empty(t, output)
+			}
+			assert.Len(t, sink.Entries(), 2)
+		})
+	}
+}
+
+func TestLogdashReadinessGate(t *testing.T) {
+	// messages returns messages of logs received by the server, in order
+	messages := func(t *testing.T, requestsCollector *requestsCollector) []string {
+		t.Helper()
+
+		requestsCollector.mu.Lock()
+		defer requestsCollector.mu.Unlock()
+		var messages []string
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			messages = append(messages, body["message"].(string))
+		}
+		return messages
+	}
+
+	t.Run("should send nothing until released and then all held logs in order", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithReadinessGate(10),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+		for i := range 5 {
+			ld.Logger.InfoF("starting %d", i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		heldMessages := messages(t, requestsCollector)
+
+		// WHEN
+		ld.Release()
+		ld.Logger.Info("ready")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Empty(t, heldMessages)
+		assert.Equal(t, []string{"starting 0", "starting 1", "starting 2", "starting 3", "starting 4", "ready"},
+			messages(t, requestsCollector))
+	})
+
+	t.Run("should keep most recent logs when holding queue is full", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithReadinessGate(2),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+
+		// WHEN
+		for i := range 5 {
+			ld.Logger.InfoF("starting %d", i)
+		}
+		ld.Release()
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"starting 3", "starting 4"}, messages(t, requestsCollector))
+	})
+
+	t.Run("should hold again after release and send held logs on shutdown", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+		ld.Logger.Info("before hold")
+		assert.Eventually(t, func() bool {
+			return len(messages(t, requestsCollector)) == 1
+		}, time.Second, time.Millisecond)
+
+		// WHEN
+		ld.HoldUntilReady()
+		ld.Logger.Info("held")
+		time.Sleep(50 * time.Millisecond)
+		heldMessages := messages(t, requestsCollector)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"before hold"}, heldMessages)
+		assert.Equal(t, []string{"before hold", "held"}, messages(t, requestsCollector))
+	})
+
+	t.Run("should not hold logs in audit mode", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithReadinessGate(2),
+			logdash.WithAuditMode(nil),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+
+		// WHEN
+		for i := range 5 {
+			ld.Logger.InfoF("starting %d", i)
+		}
+		ld.HoldUntilReady()
+		ld.Logger.Info("after hold")
+
+		// THEN
+		assert.Eventually(t, func() bool {
+			return len(messages(t, requestsCollector)) == 6
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, []string{"starting 0", "starting 1", "starting 2", "starting 3", "starting 4", "after hold"},
+			messages(t, requestsCollector))
+		assert.NoError(t, ld.Shutdown(context.Background()))
+	})
+}
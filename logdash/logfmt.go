@@ -0,0 +1,104 @@
+package logdash
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// logfmtCollisionPrefix is prepended to data keys colliding with built-in or other keys, until they're unique.
+const logfmtCollisionPrefix = "data."
+
+// formatLogfmt returns the log as a logfmt line: time, level, msg and data sorted by key.
+func formatLogfmt(timestamp time.Time, level Level, message string, data map[string]any) string {
+	var b strings.Builder
+	writeLogfmtPair(&b, "time", timestamp.Format(time.RFC3339Nano))
+	writeLogfmtPair(&b, "level", string(level))
+	writeLogfmtPair(&b, "msg", message)
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	// keys are compared once sanitized, as keys differing only in replaced characters collide as well
+	used := map[string]struct{}{"time": {}, "level": {}, "msg": {}}
+	for _, key := range keys {
+		name := logfmtKey(key)
+		for {
+			if _, ok := used[name]; !ok {
+				break
+			}
+			name = logfmtCollisionPrefix + name
+		}
+		used[name] = struct{}{}
+		writeLogfmtPair(&b, name, logfmtValue(data[key]))
+	}
+	return b.String()
+}
+
+// writeLogfmtPair writes the key and value separated by "=", preceded by a space unless it's the first pair.
+func writeLogfmtPair(b *strings.Builder, key string, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(logfmtKey(key))
+	b.WriteByte('=')
+	if logfmtNeedsQuoting(value) {
+		b.WriteString(strconv.Quote(value))
+	} else {
+		b.WriteString(value)
+	}
+}
+
+// logfmtKey returns the key with characters not allowed in logfmt keys replaced by "_".
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtValue formats the value, strings as-is and composite values as compact JSON.
+func logfmtValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	}
+	if jsonValue, err := json.Marshal(value); err == nil {
+		return string(jsonValue)
+	}
+	return fmt.Sprintf("%+v", value)
+}
+
+// logfmtNeedsQuoting reports whether the value must be quoted: it's empty
+// or contains spaces, quotes, "=" or characters which are not printable.
+func logfmtNeedsQuoting(value string) bool {
+	if value == "" {
+		return true
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
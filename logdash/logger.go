@@ -1,62 +1,454 @@
 package logdash
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // syncLogger defines the internal interface for synchronous logging.
 type syncLogger interface {
 	resourceManager
-	// syncLog logs a message with the given timestamp, level and message.
-	syncLog(timestamp time.Time, level logLevel, message string)
+	// syncLog logs a message with the given timestamp, level, message and optional structured data.
+	syncLog(timestamp time.Time, level Level, message string, data map[string]any)
 }
 
+// trySyncLogger is implemented by syncLoggers which may drop logs due to overflow.
+type trySyncLogger interface {
+	// trySyncLog is like syncLog, but returns ErrOverflow when the log is dropped due to overflow.
+	trySyncLog(timestamp time.Time, level Level, message string, data map[string]any) error
+}
+
+// trySyncLog logs with the logger, returning ErrOverflow when the log is dropped due to overflow.
+func trySyncLog(logger syncLogger, timestamp time.Time, level Level, message string, data map[string]any) error {
+	if l, ok := logger.(trySyncLogger); ok {
+		return l.trySyncLog(timestamp, level, message, data)
+	}
+	logger.syncLog(timestamp, level, message, data)
+	return nil
+}
+
+// wrapperLogger is implemented by syncLoggers which wrap another syncLogger without state of their own,
+// so options can replace the wrapped logger (see: [WithLoggerConsoleOutput]).
+type wrapperLogger interface {
+	syncLogger
+	// unwrap returns the wrapped logger.
+	unwrap() syncLogger
+	// wrap returns a copy of the wrapper around the logger.
+	wrap(logger syncLogger) syncLogger
+}
+
+// flusher is implemented by syncLoggers which send logs asynchronously.
+type flusher interface {
+	// flush waits until logs logged so far are sent, without stopping the logger.
+	flush(ctx context.Context) error
+}
+
+// fatalFlushTimeout limits flushing of logs by [Logger.Fatal] and [Logger.Panic].
+const fatalFlushTimeout = 3 * time.Second
+
 // Logger is a struct that provides logging functionality.
 //
 // This is created internally as a part of the [Logdash] object and accessed via the [Logdash.Logger] field.
 type Logger struct {
 	loggers []syncLogger
+	// prefix and suffix are added to every message
+	prefix string
+	suffix string
+	// minSeverity is the severity of the minimum level of logs, 0 means all logs
+	minSeverity *atomic.Int64
+	// workers assigns worker indices to scoped loggers, shared with child loggers
+	workers *workerIndices
+	// now returns the current time used as the timestamp of logs
+	now func() time.Time
+	// filters decide whether the log is kept, all of them must keep it
+	filters []func(level Level, message string) bool
+	// exit terminates the process after fatal logs
+	exit func(code int)
+	// fields are attached to the data of every log, data of the log takes precedence
+	fields map[string]any
+	// outputShutdownTimeout limits shutdown of each output, 0 means only the context limits it
+	outputShutdownTimeout time.Duration
+	// httpHeaders are request headers logged by HTTPRequest
+	httpHeaders []string
+	// sampler decides whether the log is kept by its data, nil means all logs are kept
+	sampler *keyedSampler
+	// template renders messages with fields of the log, nil means messages are not templated
+	template *messageTemplate
+	// runtimeStats are attached to logs at the error level and above, nil means they are not attached
+	runtimeStats *runtimeStats
+	// sourceLevel is the minimum level of logs with the source of the call attached, empty means all levels,
+	// nil means the source isn't attached
+	sourceLevel *Level
+	// baggageKeys are keys of baggage in the context attached to logs, nil means baggage isn't attached
+	baggageKeys []string
+	// baggageSource returns the value of the key from baggage of other libraries, nil means only ContextWithBaggage
+	baggageSource func(ctx context.Context, key string) (value string, ok bool)
+	// traceSampled reports the sampling decision of the trace in the context, nil means traces are not followed
+	traceSampled func(ctx context.Context) (sampled bool, ok bool)
+	// internalLogger reports problems with logs, e.g. unknown level names, nil means they are not reported
+	internalLogger *Logger
 }
 
 // newLogger creates a new Logger instance with the given syncLoggers.
 func newLogger(loggers ...syncLogger) *Logger {
 	return &Logger{
-		loggers: loggers,
+		loggers:     loggers,
+		minSeverity: &atomic.Int64{},
+		workers:     &workerIndices{},
+		now:         time.Now,
+		exit:        os.Exit,
+	}
+}
+
+// setMinLevel sets the minimum level of logs, logs below are discarded.
+//
+// Unknown levels are reported and ignored, so the minimum level doesn't change.
+func (l *Logger) setMinLevel(level Level) {
+	if !level.registered() {
+		if l.internalLogger != nil {
+			l.internalLogger.WarnF("Unknown minimum level %q ignored", level)
+		}
+		return
+	}
+	l.minSeverity.Store(level.severity())
+}
+
+// enabled reports whether logs of the level are not discarded.
+func (l *Logger) enabled(level Level) bool {
+	return level.severity() >= l.minSeverity.Load()
+}
+
+// WithFields returns a child logger attaching the fields to the data of every log,
+// in addition to fields of this logger, e.g. the ID of the handled request.
+//
+// The child shares outputs and the minimum level with this logger, so it doesn't need to be shut down.
+func (l *Logger) WithFields(fields map[string]any) *Logger {
+	child := *l
+	child.fields = mergeFields(l.fields, fields)
+	return &child
+}
+
+// Task returns a child logger attaching the ID of the task to every log in the "task" field,
+// e.g. to trace work fanned out across goroutines.
+func (l *Logger) Task(id string) *Logger {
+	return l.WithFields(map[string]any{"task": id})
+}
+
+// Scope returns a child logger attaching a worker index to every log in the "worker" field
+// and a function releasing the index, e.g. for goroutines of a worker pool:
+//
+//	go func() {
+//		logger, done := ld.Logger.Scope()
+//		defer done()
+//		logger.Info("Processing")
+//	}()
+//
+// The index is the lowest one not held by another scope of this logger or its children,
+// so indices are reused after their scopes are done and stay within the number of concurrent scopes.
+// The child can still be used after done is called, but its index may be assigned to another scope.
+func (l *Logger) Scope() (*Logger, func()) {
+	index := l.workers.acquire()
+	var released atomic.Bool
+	return l.WithFields(map[string]any{"worker": index}), func() {
+		if !released.Swap(true) {
+			l.workers.release(index)
+		}
 	}
 }
 
+// LoggerOption overrides the configuration of a logger created by [Logger.Clone].
+type LoggerOption func(*Logger)
+
+// WithLoggerMinLevel sets the minimum level of the cloned logger, independently of the logger it's cloned from
+// (see: [Logdash.SetMinLevel]).
+func WithLoggerMinLevel(level Level) LoggerOption {
+	return func(l *Logger) {
+		l.minSeverity = &atomic.Int64{}
+		l.setMinLevel(level)
+	}
+}
+
+// WithLoggerFields replaces fields of the cloned logger, unlike [Logger.WithFields], which adds them.
+func WithLoggerFields(fields map[string]any) LoggerOption {
+	return func(l *Logger) {
+		l.fields = mergeFields(fields)
+	}
+}
+
+// WithLoggerConsoleOutput prints logs of the cloned logger to the writer instead of the console of the logger
+// it's cloned from, including logs printed to stderr (see: [WithConsoleStreams]).
+func WithLoggerConsoleOutput(out io.Writer) LoggerOption {
+	return func(l *Logger) {
+		loggers := make([]syncLogger, len(l.loggers))
+		for i, logger := range l.loggers {
+			loggers[i] = withConsoleOutput(logger, out)
+		}
+		l.loggers = loggers
+	}
+}
+
+// Clone returns a logger which shares outputs with this logger, e.g. the connection to the server,
+// but with its configuration overridden by the options, e.g.:
+//
+//	auditLogger := ld.Logger.Clone(logdash.WithLoggerMinLevel(logdash.LevelDebug))
+//
+// Unlike creating another instance with [New], no HTTP client nor background workers are created.
+// Settings which aren't overridden are shared, e.g. the minimum level changed by [Logdash.SetMinLevel].
+// The clone shares outputs with this logger, so it doesn't need to be shut down.
+func (l *Logger) Clone(opts ...LoggerOption) *Logger {
+	clone := *l
+	for _, opt := range opts {
+		opt(&clone)
+	}
+	return &clone
+}
+
+// withConsoleOutput returns the logger printing to the writer if it's a console logger,
+// or a wrapper of one (see: wrapperLogger), otherwise the logger itself.
+func withConsoleOutput(logger syncLogger, out io.Writer) syncLogger {
+	switch l := logger.(type) {
+	case *consoleLogger:
+		return l.withOutput(out)
+	case wrapperLogger:
+		return l.wrap(withConsoleOutput(l.unwrap(), out))
+	default:
+		return logger
+	}
+}
+
+// workerIndices assigns the lowest free indices to scoped loggers (see: [Logger.Scope]).
+type workerIndices struct {
+	mu   sync.Mutex
+	used []bool
+}
+
+// acquire returns the lowest free index and marks it as used.
+func (w *workerIndices) acquire() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, used := range w.used {
+		if !used {
+			w.used[i] = true
+			return i
+		}
+	}
+	w.used = append(w.used, true)
+	return len(w.used) - 1
+}
+
+// release marks the index as free.
+func (w *workerIndices) release(index int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.used[index] = false
+}
+
 // Error logs an error message.
 func (l *Logger) Error(args ...any) {
-	l.log(logLevelError, args...)
+	l.log(LevelError, args...)
 }
 
 // ErrorF logs a formatted error message.
 func (l *Logger) ErrorF(format string, args ...any) {
-	l.log(logLevelError, fmt.Sprintf(format, args...))
+	l.log(LevelError, fmt.Sprintf(format, args...))
+}
+
+// ErrorJSON logs an error message with the payload sent as structured data.
+//
+// Arguments are optional and form the message like in [Logger.Error].
+func (l *Logger) ErrorJSON(payload any, args ...any) {
+	l.logData(LevelError, payloadData(payload), args...)
+}
+
+// Errorw logs an error message with alternating keys and values sent as structured data, e.g.:
+//
+//	logger.Errorw("Request failed", "status", 500, "path", "/users")
+//
+// Like in [log/slog], a value without a key, or with a key which isn't a string, is sent under the "!BADKEY" key.
+func (l *Logger) Errorw(message string, keysAndValues ...any) {
+	l.logData(LevelError, keysAndValuesData(keysAndValues), message)
+}
+
+// Fatal logs an error message, flushes pending logs and terminates the process with exit code 1,
+// like [log.Fatal].
+//
+// Flushing is limited to a few seconds, so the process terminates even if the server is unreachable.
+func (l *Logger) Fatal(args ...any) {
+	l.log(LevelError, args...)
+	l.flushAndExit()
+}
+
+// FatalF logs a formatted error message, flushes pending logs and terminates the process with exit code 1,
+// like [log.Fatalf].
+func (l *Logger) FatalF(format string, args ...any) {
+	l.log(LevelError, fmt.Sprintf(format, args...))
+	l.flushAndExit()
+}
+
+// Panic logs an error message, flushes pending logs and panics with the message, like [log.Panic].
+//
+// Flushing is limited to a few seconds, so the panic isn't delayed indefinitely if the server is unreachable.
+func (l *Logger) Panic(args ...any) {
+	message := formatMessage(args...)
+	l.log(LevelError, message)
+	l.flushWithTimeout()
+	panic(message)
+}
+
+// PanicF logs a formatted error message, flushes pending logs and panics with the message, like [log.Panicf].
+func (l *Logger) PanicF(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	l.log(LevelError, message)
+	l.flushWithTimeout()
+	panic(message)
+}
+
+// TryError is like [Logger.Error], but returns [ErrOverflow] when the log is dropped,
+// because the buffer is full and the overflow policy is set to drop (see: [WithLogOverflowPolicy]).
+//
+// Logs discarded by the minimum level, filters or sampling are not errors.
+func (l *Logger) TryError(args ...any) error {
+	return l.tryLog(LevelError, args...)
+}
+
+// TryErrorF is like [Logger.ErrorF], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryErrorF(format string, args ...any) error {
+	return l.tryLog(LevelError, fmt.Sprintf(format, args...))
+}
+
+// TryWarn is like [Logger.Warn], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryWarn(args ...any) error {
+	return l.tryLog(LevelWarn, args...)
+}
+
+// TryWarnF is like [Logger.WarnF], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryWarnF(format string, args ...any) error {
+	return l.tryLog(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// TryInfo is like [Logger.Info], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryInfo(args ...any) error {
+	return l.tryLog(LevelInfo, args...)
+}
+
+// TryInfoF is like [Logger.InfoF], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryInfoF(format string, args ...any) error {
+	return l.tryLog(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// TryHTTP is like [Logger.HTTP], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryHTTP(args ...any) error {
+	return l.tryLog(LevelHTTP, args...)
+}
+
+// TryHTTPF is like [Logger.HTTPF], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryHTTPF(format string, args ...any) error {
+	return l.tryLog(LevelHTTP, fmt.Sprintf(format, args...))
+}
+
+// TryVerbose is like [Logger.Verbose], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryVerbose(args ...any) error {
+	return l.tryLog(LevelVerbose, args...)
+}
+
+// TryVerboseF is like [Logger.VerboseF], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryVerboseF(format string, args ...any) error {
+	return l.tryLog(LevelVerbose, fmt.Sprintf(format, args...))
+}
+
+// TryDebug is like [Logger.Debug], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryDebug(args ...any) error {
+	return l.tryLog(LevelDebug, args...)
+}
+
+// TryDebugF is like [Logger.DebugF], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryDebugF(format string, args ...any) error {
+	return l.tryLog(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// TrySilly is like [Logger.Silly], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TrySilly(args ...any) error {
+	return l.tryLog(LevelSilly, args...)
+}
+
+// TrySillyF is like [Logger.SillyF], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TrySillyF(format string, args ...any) error {
+	return l.tryLog(LevelSilly, fmt.Sprintf(format, args...))
+}
+
+// TryLog is like [Logger.Log], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryLog(args ...any) error {
+	return l.tryLog(LevelInfo, args...)
+}
+
+// TryLogF is like [Logger.LogF], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryLogF(format string, args ...any) error {
+	return l.tryLog(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// TryLogLevel is like [Logger.LogLevel], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryLogLevel(level Level, args ...any) error {
+	return l.tryLog(level, args...)
+}
+
+// TryLogLevelF is like [Logger.LogLevelF], but returns [ErrOverflow] when the log is dropped (see: [Logger.TryError]).
+func (l *Logger) TryLogLevelF(level Level, format string, args ...any) error {
+	return l.tryLog(level, fmt.Sprintf(format, args...))
 }
 
 // Warn logs a warning message.
 func (l *Logger) Warn(args ...any) {
-	l.log(logLevelWarn, args...)
+	l.log(LevelWarn, args...)
 }
 
 // WarnF logs a formatted warning message.
 func (l *Logger) WarnF(format string, args ...any) {
-	l.log(logLevelWarn, fmt.Sprintf(format, args...))
+	l.log(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// WarnJSON logs a warning message with the payload sent as structured data.
+//
+// Arguments are optional and form the message like in [Logger.Warn].
+func (l *Logger) WarnJSON(payload any, args ...any) {
+	l.logData(LevelWarn, payloadData(payload), args...)
+}
+
+// Warnw logs a warning message with alternating keys and values sent as structured data (see: [Logger.Errorw]).
+func (l *Logger) Warnw(message string, keysAndValues ...any) {
+	l.logData(LevelWarn, keysAndValuesData(keysAndValues), message)
 }
 
 // Info logs an informational message.
 func (l *Logger) Info(args ...any) {
-	l.log(logLevelInfo, args...)
+	l.log(LevelInfo, args...)
 }
 
 // InfoF logs a formatted informational message.
 func (l *Logger) InfoF(format string, args ...any) {
-	l.log(logLevelInfo, fmt.Sprintf(format, args...))
+	l.log(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// InfoJSON logs an informational message with the payload sent as structured data.
+//
+// Arguments are optional and form the message like in [Logger.Info].
+func (l *Logger) InfoJSON(payload any, args ...any) {
+	l.logData(LevelInfo, payloadData(payload), args...)
+}
+
+// Infow logs an informational message with alternating keys and values sent as structured data (see: [Logger.Errorw]).
+func (l *Logger) Infow(message string, keysAndValues ...any) {
+	l.logData(LevelInfo, keysAndValuesData(keysAndValues), message)
 }
 
 // Log is an alias for Info.
@@ -69,61 +461,272 @@ func (l *Logger) LogF(format string, args ...any) {
 	l.InfoF(format, args...)
 }
 
+// LogLevel logs a message with the given level, e.g. registered by [RegisterLevel].
+func (l *Logger) LogLevel(level Level, args ...any) {
+	l.log(level, args...)
+}
+
+// LogLevelF logs a formatted message with the given level, e.g. registered by [RegisterLevel].
+func (l *Logger) LogLevelF(level Level, format string, args ...any) {
+	l.log(level, fmt.Sprintf(format, args...))
+}
+
+// LogNamed logs a message with the level of the name, e.g. received from another system.
+//
+// The name is either a level name or an alias (see: [RegisterLevelAlias]), matched case-insensitively.
+// Unknown names are logged at [LevelInfo] and reported in verbose mode (see: [WithVerbose]).
+func (l *Logger) LogNamed(levelName string, args ...any) {
+	level, ok := lookupLevel(levelName)
+	if !ok {
+		if l.internalLogger != nil {
+			l.internalLogger.VerboseF("Unknown level %q, logging at %s level", levelName, LevelInfo)
+		}
+		level = LevelInfo
+	}
+	l.log(level, args...)
+}
+
 // HTTP logs an HTTP-related message.
 func (l *Logger) HTTP(args ...any) {
-	l.log(logLevelHTTP, args...)
+	l.log(LevelHTTP, args...)
 }
 
 // HTTPF logs a formatted HTTP-related message.
 func (l *Logger) HTTPF(format string, args ...any) {
-	l.log(logLevelHTTP, fmt.Sprintf(format, args...))
+	l.log(LevelHTTP, fmt.Sprintf(format, args...))
+}
+
+// HTTPJSON logs an HTTP-related message with the payload sent as structured data.
+//
+// Arguments are optional and form the message like in [Logger.HTTP].
+func (l *Logger) HTTPJSON(payload any, args ...any) {
+	l.logData(LevelHTTP, payloadData(payload), args...)
+}
+
+// HTTPw logs an HTTP-related message with alternating keys and values sent as structured data (see: [Logger.Errorw]).
+func (l *Logger) HTTPw(message string, keysAndValues ...any) {
+	l.logData(LevelHTTP, keysAndValuesData(keysAndValues), message)
 }
 
 // Verbose logs a verbose message.
 func (l *Logger) Verbose(args ...any) {
-	l.log(logLevelVerbose, args...)
+	l.log(LevelVerbose, args...)
 }
 
 // VerboseF logs a formatted verbose message.
 func (l *Logger) VerboseF(format string, args ...any) {
-	l.log(logLevelVerbose, fmt.Sprintf(format, args...))
+	l.log(LevelVerbose, fmt.Sprintf(format, args...))
+}
+
+// VerboseJSON logs a verbose message with the payload sent as structured data.
+//
+// Arguments are optional and form the message like in [Logger.Verbose].
+func (l *Logger) VerboseJSON(payload any, args ...any) {
+	l.logData(LevelVerbose, payloadData(payload), args...)
+}
+
+// Verbosew logs a verbose message with alternating keys and values sent as structured data (see: [Logger.Errorw]).
+func (l *Logger) Verbosew(message string, keysAndValues ...any) {
+	l.logData(LevelVerbose, keysAndValuesData(keysAndValues), message)
 }
 
 // Debug logs a debug message.
 func (l *Logger) Debug(args ...any) {
-	l.log(logLevelDebug, args...)
+	l.log(LevelDebug, args...)
 }
 
 // DebugF logs a formatted debug message.
 func (l *Logger) DebugF(format string, args ...any) {
-	l.log(logLevelDebug, fmt.Sprintf(format, args...))
+	l.log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// DebugJSON logs a debug message with the payload sent as structured data.
+//
+// Arguments are optional and form the message like in [Logger.Debug].
+func (l *Logger) DebugJSON(payload any, args ...any) {
+	l.logData(LevelDebug, payloadData(payload), args...)
+}
+
+// Debugw logs a debug message with alternating keys and values sent as structured data (see: [Logger.Errorw]).
+func (l *Logger) Debugw(message string, keysAndValues ...any) {
+	l.logData(LevelDebug, keysAndValuesData(keysAndValues), message)
 }
 
 // Silly logs a silly message (lowest priority).
 func (l *Logger) Silly(args ...any) {
-	l.log(logLevelSilly, args...)
+	l.log(LevelSilly, args...)
 }
 
 // SillyF logs a formatted silly message (lowest priority).
 func (l *Logger) SillyF(format string, args ...any) {
-	l.log(logLevelSilly, fmt.Sprintf(format, args...))
+	l.log(LevelSilly, fmt.Sprintf(format, args...))
 }
 
-// log is the common implementation for all logging methods.
-func (l *Logger) log(level logLevel, args ...any) {
-	timestamp := time.Now()
-	message := formatMessage(args...)
+// SillyJSON logs a silly message with the payload sent as structured data.
+//
+// Arguments are optional and form the message like in [Logger.Silly].
+func (l *Logger) SillyJSON(payload any, args ...any) {
+	l.logData(LevelSilly, payloadData(payload), args...)
+}
 
+// Sillyw logs a silly message with alternating keys and values sent as structured data (see: [Logger.Errorw]).
+func (l *Logger) Sillyw(message string, keysAndValues ...any) {
+	l.logData(LevelSilly, keysAndValuesData(keysAndValues), message)
+}
+
+// log logs the message built from args.
+func (l *Logger) log(level Level, args ...any) {
+	l.logData(level, nil, args...)
+}
+
+// baggageFields returns values of selected keys of the baggage in the context as fields (see: [WithBaggageFields]),
+// keys absent from the baggage are omitted, nil if there are none.
+func (l *Logger) baggageFields(ctx context.Context) map[string]any {
+	if len(l.baggageKeys) == 0 || ctx == nil {
+		return nil
+	}
+	baggage := baggageFromContext(ctx)
+	var fields map[string]any
+	for _, key := range l.baggageKeys {
+		value, ok := baggage[key]
+		if !ok && l.baggageSource != nil {
+			value, ok = l.baggageSource(ctx, key)
+		}
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]any, len(l.baggageKeys))
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+// keepForTrace reports whether the log with the context is kept by the sampling of its trace
+// (see: [WithFollowTraceSampling]).
+func (l *Logger) keepForTrace(ctx context.Context, level Level) bool {
+	if l.traceSampled == nil || ctx == nil || level.severity() >= LevelInfo.severity() {
+		return true
+	}
+	sampled, ok := l.traceSampled(ctx)
+	return !ok || sampled
+}
+
+// tryLog is like log, but returns the error of outputs which dropped the log.
+func (l *Logger) tryLog(level Level, args ...any) error {
+	if !l.enabled(level) {
+		return nil
+	}
+	return l.logWithData(l.now(), level, formatMessage(args...), l.withSource(level, nil))
+}
+
+// logData is like log, but with structured data attached.
+func (l *Logger) logData(level Level, data map[string]any, args ...any) {
+	if !l.enabled(level) {
+		return
+	}
+	l.logWithData(l.now(), level, formatMessage(args...), l.withSource(level, data))
+}
+
+func (l *Logger) logWithAttrs(timestamp time.Time, level Level, attrs []string, data map[string]any) {
+	if !l.enabled(level) {
+		return
+	}
+	l.logWithData(timestamp, level, strings.Join(attrs, " "), data)
+}
+
+// logWithData is the common implementation for all logging methods.
+//
+// Returns ErrOverflow when any output dropped the log due to overflow.
+func (l *Logger) logWithData(timestamp time.Time, level Level, message string, data map[string]any) error {
+	if !l.enabled(level) {
+		return nil
+	}
+	message = l.wrapMessage(message)
+	for _, filter := range l.filters {
+		if !filter(level, message) {
+			return nil
+		}
+	}
+	if l.runtimeStats != nil && level.severity() >= LevelError.severity() {
+		data = mergeFields(l.runtimeStats.fields(), l.fields, data)
+	} else if len(l.fields) > 0 {
+		data = l.withFields(data)
+	}
+	if l.template != nil {
+		message = l.template.render(message, data)
+	}
+	if l.sampler != nil && !l.sampler.sample(data) {
+		return nil
+	}
+	var errs []error
 	for _, logger := range l.loggers {
-		logger.syncLog(timestamp, level, message)
+		if err := trySyncLog(logger, timestamp, level, message, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 1 {
+		// the error of a single output is returned as is, so it can be compared directly
+		return errs[0]
 	}
+	return errors.Join(errs...)
+}
+
+// withFields returns data merged with fields of the logger.
+func (l *Logger) withFields(data map[string]any) map[string]any {
+	return mergeFields(l.fields, data)
+}
+
+// mergeFields returns fields merged into a single map, later fields take precedence, nil if there are none.
+func mergeFields(fields ...map[string]any) map[string]any {
+	var merged map[string]any
+	for _, f := range fields {
+		for key, value := range f {
+			if merged == nil {
+				merged = make(map[string]any)
+			}
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// flushWithTimeout waits until pending logs are sent, but no longer than fatalFlushTimeout.
+func (l *Logger) flushWithTimeout() {
+	ctx, cancel := context.WithTimeout(context.Background(), fatalFlushTimeout)
+	defer cancel()
+	_ = l.flush(ctx)
 }
 
-func (l *Logger) logWithAttrs(timestamp time.Time, level logLevel, attrs []string) {
-	message := strings.Join(attrs, " ")
+// flush waits until logs logged so far are sent by outputs sending them asynchronously.
+func (l *Logger) flush(ctx context.Context) error {
+	var errs []error
 	for _, logger := range l.loggers {
-		logger.syncLog(timestamp, level, message)
+		if f, ok := logger.(flusher); ok {
+			errs = append(errs, f.flush(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l *Logger) flushAndExit() {
+	l.flushWithTimeout()
+	l.exit(1)
+}
+
+// wrapMessage adds the prefix and suffix to the message, separated by spaces.
+func (l *Logger) wrapMessage(message string) string {
+	if l.prefix == "" && l.suffix == "" {
+		return message
+	}
+	parts := make([]string, 0, 3)
+	for _, part := range []string{l.prefix, message, l.suffix} {
+		if part != "" {
+			parts = append(parts, part)
+		}
 	}
+	return strings.Join(parts, " ")
 }
 
 // formatMessage formats the log message arguments into a single string.
@@ -135,14 +738,78 @@ func formatMessage(args ...any) string {
 	return strings.Join(strArgs, " ")
 }
 
-func (l *Logger) Shutdown(ctx context.Context) error {
-	var errs []error
-	for _, logger := range l.loggers {
-		err := logger.Shutdown(ctx)
+// badKey is the key of values without a valid key, like in [log/slog].
+const badKey = "!BADKEY"
+
+// keysAndValuesData returns alternating keys and values as data, nil if there are none.
+func keysAndValuesData(keysAndValues []any) map[string]any {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+	data := make(map[string]any, (len(keysAndValues)+1)/2)
+	for len(keysAndValues) > 0 {
+		key, ok := keysAndValues[0].(string)
+		if !ok || len(keysAndValues) == 1 {
+			// the dangling value or the value in place of the key
+			data[badKey] = keysAndValues[0]
+			keysAndValues = keysAndValues[1:]
+			continue
+		}
+		data[key] = keysAndValues[1]
+		keysAndValues = keysAndValues[2:]
+	}
+	return data
+}
+
+// payloadData converts the payload to structured data sent along with the log message.
+//
+// JSON objects become the data as is, other JSON values are put under the "payload" key.
+// A []byte payload containing valid JSON is sent as is, otherwise it's base64 encoded.
+// Payloads which can't be marshaled (e.g. channels, functions) are sent as their string
+// representation under the "payload" key, with the marshaling error under the "payloadError" key.
+func payloadData(payload any) map[string]any {
+	var raw []byte
+	if b, ok := payload.([]byte); ok && json.Valid(b) {
+		raw = b
+	} else {
+		var err error
+		raw, err = json.Marshal(payload)
 		if err != nil {
-			errs = append(errs, err)
+			return map[string]any{
+				"payload":      fmt.Sprintf("%+v", payload),
+				"payloadError": err.Error(),
+			}
 		}
 	}
+
+	var data map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	// keep numbers as they are, e.g. big integers
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err == nil && data != nil {
+		return data
+	}
+	return map[string]any{"payload": json.RawMessage(raw)}
+}
+
+func (l *Logger) Shutdown(ctx context.Context) error {
+	// outputs are shut down concurrently, so a slow output doesn't delay the others
+	errs := make([]error, len(l.loggers))
+	var wg sync.WaitGroup
+	for i, logger := range l.loggers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loggerCtx := ctx
+			if l.outputShutdownTimeout > 0 {
+				var cancel context.CancelFunc
+				loggerCtx, cancel = context.WithTimeout(ctx, l.outputShutdownTimeout)
+				defer cancel()
+			}
+			errs[i] = logger.Shutdown(loggerCtx)
+		}()
+	}
+	wg.Wait()
 	return errors.Join(errs...)
 }
 
@@ -0,0 +1,31 @@
+package logdash
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLoggerConsoleOutput(t *testing.T) {
+	t.Run("should replace console output through wrappers", func(t *testing.T) {
+		// GIVEN
+		var originalOut, cloneOut bytes.Buffer
+		masker := &secretMasker{}
+		masker.add("secret-key")
+		console := newConsoleLogger(&options{})
+		console.out = &originalOut
+		logger := newLogger(withMinLevel(&maskingLogger{syncLogger: console, masker: masker}, LevelInfo))
+
+		// WHEN
+		clone := logger.Clone(WithLoggerConsoleOutput(&cloneOut))
+		clone.Info("key secret-key")
+		clone.Debug("discarded")
+
+		// THEN
+		assert.Empty(t, originalOut.String())
+		assert.Contains(t, cloneOut.String(), "key "+maskedSecret)
+		assert.NotContains(t, cloneOut.String(), "secret-key")
+		assert.NotContains(t, cloneOut.String(), "discarded")
+	})
+}
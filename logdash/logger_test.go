@@ -0,0 +1,1157 @@
+package logdash_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashLoggerJSON(t *testing.T) {
+	type user struct {
+		Name  string `json:"name"`
+		Age   int    `json:"age"`
+		Admin bool   `json:"admin"`
+	}
+
+	testCases := []struct {
+		name             string
+		log              func(l *logdash.Logger)
+		expectedBody     map[string]any
+		expectedFallback bool
+	}{
+		{
+			name: "should send struct payload as data",
+			log: func(l *logdash.Logger) {
+				l.InfoJSON(user{Name: "john", Age: 42, Admin: true}, "User", "created")
+			},
+			expectedBody: map[string]any{
+				"level":   "info",
+				"message": "User created",
+				"data":    map[string]any{"name": "john", "age": float64(42), "admin": true},
+			},
+		},
+		{
+			name: "should send map payload as data",
+			log: func(l *logdash.Logger) {
+				l.ErrorJSON(map[string]any{"code": 500, "tags": []string{"a", "b"}})
+			},
+			expectedBody: map[string]any{
+				"level":   "error",
+				"message": "",
+				"data":    map[string]any{"code": float64(500), "tags": []any{"a", "b"}},
+			},
+		},
+		{
+			name: "should send []byte payload with JSON as data",
+			log: func(l *logdash.Logger) {
+				l.DebugJSON([]byte(`{"raw":[1,2,3]}`), "raw")
+			},
+			expectedBody: map[string]any{
+				"level":   "debug",
+				"message": "raw",
+				"data":    map[string]any{"raw": []any{float64(1), float64(2), float64(3)}},
+			},
+		},
+		{
+			name: "should send binary []byte payload base64 encoded",
+			log: func(l *logdash.Logger) {
+				l.WarnJSON([]byte{0xff, 0x00}, "binary")
+			},
+			expectedBody: map[string]any{
+				"level":   "warning",
+				"message": "binary",
+				"data":    map[string]any{"payload": "/wA="},
+			},
+		},
+		{
+			name: "should send non-object payload under payload key",
+			log: func(l *logdash.Logger) {
+				l.InfoJSON([]int{1, 2}, "list")
+			},
+			expectedBody: map[string]any{
+				"level":   "info",
+				"message": "list",
+				"data":    map[string]any{"payload": []any{float64(1), float64(2)}},
+			},
+		},
+		{
+			name: "should send fallback for non-serializable payload",
+			log: func(l *logdash.Logger) {
+				l.InfoJSON(map[string]any{"fn": func() {}}, "func")
+			},
+			expectedBody: map[string]any{
+				"level":   "info",
+				"message": "func",
+				"data":    nil, // Will only check if field exists
+			},
+			expectedFallback: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+			)
+
+			// WHEN
+			beforeLogSent := time.Now()
+			tc.log(ld.Logger)
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			assert.Len(t, requestsCollector.requests, 1)
+			body := assertRequestAndBody(t, requestsCollector.requests[0], http.MethodPost, "/logs", "test-api-key", tc.expectedBody, beforeLogSent)
+			if tc.expectedFallback {
+				data := body["data"].(map[string]any)
+				assert.Contains(t, data, "payload")
+				assert.Contains(t, data, "payloadError")
+			}
+		})
+	}
+}
+
+func TestLogdashLoggerKeysAndValues(t *testing.T) {
+	testCases := []struct {
+		name           string
+		log            func(l *logdash.Logger)
+		expectedLevel  logdash.Level
+		expectedFields map[string]any
+	}{
+		{
+			name: "should attach even keys and values as fields",
+			log: func(l *logdash.Logger) {
+				l.Infow("Request handled", "status", 200, "path", "/users")
+			},
+			expectedLevel:  logdash.LevelInfo,
+			expectedFields: map[string]any{"status": 200, "path": "/users"},
+		},
+		{
+			name: "should attach dangling value under bad key",
+			log: func(l *logdash.Logger) {
+				l.Errorw("Request handled", "status", 500, "dangling")
+			},
+			expectedLevel:  logdash.LevelError,
+			expectedFields: map[string]any{"status": 500, "!BADKEY": "dangling"},
+		},
+		{
+			name: "should attach value in place of key under bad key",
+			log: func(l *logdash.Logger) {
+				l.Debugw("Request handled", 42, "path", "/users")
+			},
+			expectedLevel:  logdash.LevelDebug,
+			expectedFields: map[string]any{"!BADKEY": 42, "path": "/users"},
+		},
+		{
+			name: "should log without fields",
+			log: func(l *logdash.Logger) {
+				l.Warnw("Request handled")
+			},
+			expectedLevel: logdash.LevelWarn,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			sink := logdash.NewMemorySink()
+
+			// WHEN
+			captureStdout(t, func() {
+				ld := logdash.New(logdash.WithSink(sink))
+				tc.log(ld.Logger)
+				err := ld.Shutdown(context.Background())
+				assert.NoError(t, err)
+			})
+
+			// THEN
+			entries := sink.Entries()
+			assert.Len(t, entries, 1)
+			assert.Equal(t, tc.expectedLevel, entries[0].Level)
+			assert.Equal(t, "Request handled", entries[0].Message)
+			assert.Equal(t, tc.expectedFields, entries[0].Fields)
+		})
+	}
+}
+
+// captureStdout returns the console output printed by fn, without colors.
+//
+// Loggers created within fn print to the captured output.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return color.ClearCode(captureStdoutRaw(t, fn))
+}
+
+// captureStdoutRaw is like captureStdout, but keeps colors.
+func captureStdoutRaw(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		output <- string(b)
+	}()
+
+	fn()
+	w.Close()
+	return <-output
+}
+
+func TestLogdashLoggerMessagePrefixAndSuffix(t *testing.T) {
+	testCases := []struct {
+		name            string
+		opts            []logdash.Option
+		expectedMessage string
+	}{
+		{
+			name:            "should send message as is by default",
+			expectedMessage: "Hello, World!",
+		},
+		{
+			name:            "should add prefix",
+			opts:            []logdash.Option{logdash.WithMessagePrefix("[staging]")},
+			expectedMessage: "[staging] Hello, World!",
+		},
+		{
+			name:            "should add suffix",
+			opts:            []logdash.Option{logdash.WithMessageSuffix("seed")},
+			expectedMessage: "Hello, World! seed",
+		},
+		{
+			name: "should add prefix and suffix",
+			opts: []logdash.Option{
+				logdash.WithMessagePrefix("[staging]"),
+				logdash.WithMessageSuffix("seed"),
+			},
+			expectedMessage: "[staging] Hello, World! seed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			// WHEN
+			beforeLogSent := time.Now()
+			output := captureStdout(t, func() {
+				ld := logdash.New(append([]logdash.Option{
+					logdash.WithHost(httpServer.URL),
+					logdash.WithAPIKey("test-api-key"),
+				}, tc.opts...)...)
+
+				ld.Logger.Info("Hello, World!")
+				err := ld.Shutdown(context.Background())
+				assert.NoError(t, err)
+			})
+
+			// THEN
+			assert.Len(t, requestsCollector.requests, 1)
+			expectedBody := map[string]any{
+				"message": tc.expectedMessage,
+			}
+			assertRequestAndBody(t, requestsCollector.requests[0], http.MethodPost, "/logs", "test-api-key", expectedBody, beforeLogSent)
+			assert.True(t, strings.HasSuffix(output, "INFO "+tc.expectedMessage+"\n"), "unexpected console output: %q", output)
+		})
+	}
+}
+
+func TestLogdashMessageTemplate(t *testing.T) {
+	testCases := []struct {
+		name            string
+		opts            []logdash.Option
+		log             func(logger *logdash.Logger)
+		expectedMessage string
+	}{
+		{
+			name: "should render field of logger in message",
+			opts: []logdash.Option{
+				logdash.WithMessageTemplate("{component} | {message}"),
+				logdash.WithFields(map[string]any{"component": "api"}),
+			},
+			expectedMessage: "api | Hello, World!",
+		},
+		{
+			name: "should render field of log in message",
+			opts: []logdash.Option{logdash.WithMessageTemplate("{message} (user: {user}, attempt: {attempt})")},
+			log: func(logger *logdash.Logger) {
+				logger.Infow("Hello, World!", "user", "john", "attempt", 2)
+			},
+			expectedMessage: "Hello, World! (user: john, attempt: 2)",
+		},
+		{
+			name:            "should render missing field empty",
+			opts:            []logdash.Option{logdash.WithMessageTemplate("{component} | {message}")},
+			expectedMessage: " | Hello, World!",
+		},
+		{
+			name: "should render missing field as fallback",
+			opts: []logdash.Option{
+				logdash.WithMessageTemplate("{component} | {message}"),
+				logdash.WithMessageTemplateFallback("-"),
+			},
+			expectedMessage: "- | Hello, World!",
+		},
+		{
+			name: "should render message with prefix and suffix",
+			opts: []logdash.Option{
+				logdash.WithMessageTemplate("{message}!"),
+				logdash.WithMessagePrefix("[staging]"),
+				logdash.WithMessageSuffix("seed"),
+			},
+			expectedMessage: "[staging] Hello, World! seed!",
+		},
+		{
+			name: "should keep braces which aren't placeholders",
+			opts: []logdash.Option{
+				logdash.WithMessageTemplate("{} {component} {message"),
+				logdash.WithFields(map[string]any{"component": "api"}),
+			},
+			expectedMessage: "{} api {message",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			// WHEN
+			output := captureStdout(t, func() {
+				ld := logdash.New(append([]logdash.Option{
+					logdash.WithHost(httpServer.URL),
+					logdash.WithAPIKey("test-api-key"),
+				}, tc.opts...)...)
+
+				if tc.log != nil {
+					tc.log(ld.Logger)
+				} else {
+					ld.Logger.Info("Hello, World!")
+				}
+				err := ld.Shutdown(context.Background())
+				assert.NoError(t, err)
+			})
+
+			// THEN
+			if assert.Len(t, requestsCollector.requests, 1) {
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+				assert.Equal(t, tc.expectedMessage, body["message"])
+			}
+			assert.Contains(t, color.ClearCode(output), "INFO "+tc.expectedMessage)
+		})
+	}
+}
+
+func TestLogdashSetMinLevel(t *testing.T) {
+	t.Run("should send debug log only after verbosity is raised", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithMinLevel(logdash.LevelInfo),
+			)
+
+			ld.Logger.Debug("suppressed")
+			ld.SetMinLevel(logdash.LevelDebug)
+			ld.Logger.Debug("sent")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.NotContains(t, output, "suppressed")
+		assert.Contains(t, output, "sent")
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, "sent", body["message"])
+		assert.Equal(t, "debug", body["level"])
+	})
+
+	t.Run("should keep more severe logs", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+		)
+		ld.SetMinLevel(logdash.LevelWarn)
+
+		// WHEN
+		ld.Logger.Info("info")
+		ld.Logger.Warn("warn")
+		ld.Logger.ErrorF("%s", "error")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 2)
+	})
+
+	t.Run("should report and ignore unknown level", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithSink(sink),
+				logdash.WithVerbose(),
+				logdash.WithMinLevel("critical"),
+			)
+			ld.Logger.Debug("kept by default")
+			ld.SetMinLevel(logdash.LevelWarn)
+			ld.SetMinLevel("WARN")
+			ld.Logger.Info("suppressed")
+			assert.NoError(t, ld.Shutdown(context.Background()))
+		})
+
+		// THEN
+		assert.Contains(t, output, `Unknown minimum level "critical" ignored`)
+		assert.Contains(t, output, `Unknown minimum level "WARN" ignored`)
+		entries := sink.Entries()
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, "kept by default", entries[0].Message)
+		}
+	})
+}
+
+func TestLogdashOutputMinLevels(t *testing.T) {
+	t.Run("should print debug log to console but not send it when remote threshold is info", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithRemoteMinLevel(logdash.LevelInfo),
+			)
+
+			ld.Logger.Debug("local only")
+			ld.Logger.Info("everywhere")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.Contains(t, output, "local only")
+		assert.Contains(t, output, "everywhere")
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, "everywhere", body["message"])
+	})
+
+	t.Run("should send debug log but not print it when console threshold is info", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithConsoleMinLevel(logdash.LevelInfo),
+			)
+
+			ld.Logger.Debug("remote only")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.NotContains(t, output, "remote only")
+		assert.Len(t, requestsCollector.requests, 1)
+	})
+}
+
+func TestLogdashKeyedSampling(t *testing.T) {
+	t.Run("should always keep flagged logs and sample the rest", func(t *testing.T) {
+		// GIVEN
+		const logs = 1000
+		sink := logdash.NewMemorySink()
+
+		// WHEN
+		captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithSink(sink),
+				logdash.WithKeyedSampling("traceID", 0.5, func(value any) bool {
+					return value == "investigated"
+				}),
+			)
+			for range logs {
+				ld.Logger.WithFields(map[string]any{"traceID": "investigated"}).Info("flagged")
+				ld.Logger.WithFields(map[string]any{"traceID": "other"}).Info("other")
+				ld.Logger.Info("plain")
+			}
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		counts := make(map[string]int)
+		for _, entry := range sink.Entries() {
+			counts[entry.Message]++
+		}
+		assert.Equal(t, logs, counts["flagged"])
+		assert.InDelta(t, logs/2, counts["other"], logs/10)
+		assert.InDelta(t, logs/2, counts["plain"], logs/10)
+	})
+}
+
+func TestLogdashRuntimeStatsOnError(t *testing.T) {
+	t.Run("should attach runtime stats to error logs only", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+			logdash.WithRuntimeStatsOnError(),
+			logdash.WithFields(map[string]any{"service": "api"}),
+		)
+		defer ld.Close()
+
+		// WHEN
+		ld.Logger.Info("info")
+		ld.Logger.Warn("warn")
+		ld.Logger.Error("error")
+		ld.Logger.Errorw("error with data", "numGC", "overridden")
+
+		// THEN
+		entries := sink.Entries()
+		if assert.Len(t, entries, 4) {
+			assert.Equal(t, map[string]any{"service": "api"}, entries[0].Fields)
+			assert.Equal(t, map[string]any{"service": "api"}, entries[1].Fields)
+
+			assert.Equal(t, "api", entries[2].Fields["service"])
+			assert.Greater(t, entries[2].Fields["goroutines"], 0)
+			assert.Greater(t, entries[2].Fields["heapAllocBytes"], uint64(0))
+			assert.IsType(t, uint32(0), entries[2].Fields["numGC"])
+
+			assert.Equal(t, "overridden", entries[3].Fields["numGC"])
+			assert.Contains(t, entries[3].Fields, "goroutines")
+		}
+	})
+}
+
+func TestLogdashLoggerScope(t *testing.T) {
+	t.Run("should attach distinct worker index to logs of each concurrent scope", func(t *testing.T) {
+		// GIVEN
+		const workers, logs = 8, 10
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+		defer ld.Close()
+
+		// WHEN
+		var started, finished sync.WaitGroup
+		started.Add(workers)
+		finished.Add(workers)
+		for g := range workers {
+			go func() {
+				defer finished.Done()
+				logger, done := ld.Logger.Scope()
+				defer done()
+				// all scopes are held at once, so their indices are distinct
+				started.Done()
+				started.Wait()
+				for range logs {
+					logger.Info(fmt.Sprint(g))
+				}
+			}()
+		}
+		finished.Wait()
+
+		// THEN
+		workerByGoroutine := make(map[string]any)
+		goroutineByWorker := make(map[any]string)
+		for _, entry := range sink.Entries() {
+			worker := entry.Fields["worker"]
+			if previous, ok := workerByGoroutine[entry.Message]; ok {
+				assert.Equal(t, previous, worker, "worker of goroutine %s changed", entry.Message)
+			}
+			workerByGoroutine[entry.Message] = worker
+			goroutineByWorker[worker] = entry.Message
+		}
+		assert.Len(t, workerByGoroutine, workers)
+		assert.Len(t, goroutineByWorker, workers)
+		for i := range workers {
+			assert.Contains(t, goroutineByWorker, i)
+		}
+	})
+
+	t.Run("should reuse worker index after scope is done", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+		defer ld.Close()
+
+		// WHEN
+		first, doneFirst := ld.Logger.Scope()
+		second, doneSecond := ld.Logger.WithFields(map[string]any{"service": "api"}).Scope()
+		doneFirst()
+		doneFirst()
+		third, doneThird := ld.Logger.Scope()
+		defer doneSecond()
+		defer doneThird()
+		first.Info("first")
+		second.Info("second")
+		third.Info("third")
+
+		// THEN
+		entries := sink.Entries()
+		if assert.Len(t, entries, 3) {
+			assert.Equal(t, map[string]any{"worker": 0}, entries[0].Fields)
+			assert.Equal(t, map[string]any{"service": "api", "worker": 1}, entries[1].Fields)
+			assert.Equal(t, map[string]any{"worker": 0}, entries[2].Fields)
+		}
+	})
+
+	t.Run("should attach task ID to logs", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+		defer ld.Close()
+
+		// WHEN
+		var wg sync.WaitGroup
+		for _, id := range []string{"import", "export"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ld.Logger.Task(id).Info(id)
+			}()
+		}
+		wg.Wait()
+
+		// THEN
+		entries := sink.Entries()
+		if assert.Len(t, entries, 2) {
+			for _, entry := range entries {
+				assert.Equal(t, map[string]any{"task": entry.Message}, entry.Fields)
+			}
+		}
+	})
+}
+
+func TestLogdashRegisterLevel(t *testing.T) {
+	t.Run("should log registered level to console with its color and send its name", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		auditColor := color.RGB(200, 0, 200)
+		audit := logdash.RegisterLevel("audit", 55, logdash.WithLevelColor(auditColor))
+		defer color.ForceSetColorLevel(color.ForceOpenColor())
+
+		// WHEN
+		output := captureStdoutRaw(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithMinLevel(logdash.LevelInfo),
+			)
+
+			ld.Logger.LogLevel(audit, "user logged in")
+			ld.Logger.LogLevelF(audit, "%s", "second")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.Contains(t, output, auditColor.Sprint("AUDIT"))
+		assert.Contains(t, output, "user logged in")
+		assert.Len(t, requestsCollector.requests, 2)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, "audit", body["level"])
+	})
+
+	t.Run("should filter registered level by its severity", func(t *testing.T) {
+		// GIVEN
+		trace := logdash.RegisterLevel("trace", 5)
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(logdash.WithMinLevel(logdash.LevelSilly))
+			ld.Logger.LogLevel(trace, "suppressed")
+			ld.SetMinLevel(trace)
+			ld.Logger.LogLevel(trace, "printed")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.NotContains(t, output, "suppressed")
+		assert.Contains(t, output, "TRACE printed")
+	})
+}
+
+func TestLogdashLogNamed(t *testing.T) {
+	t.Run("should resolve aliases and level names and fall back to info", func(t *testing.T) {
+		// GIVEN
+		logdash.RegisterLevelAlias("CRITICAL", logdash.LevelError)
+		logdash.RegisterLevelAlias("notice", logdash.LevelInfo)
+		logdash.RegisterLevelAlias("Fine", logdash.LevelDebug)
+		sink := logdash.NewMemorySink()
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithMinLevel(logdash.LevelSilly),
+				logdash.WithSink(sink),
+				logdash.WithVerbose(),
+			)
+
+			ld.Logger.LogNamed("CRITICAL", "critical")
+			ld.Logger.LogNamed("Notice", "notice")
+			ld.Logger.LogNamed("fine", "fine")
+			ld.Logger.LogNamed("WARNING", "warning")
+			ld.Logger.LogNamed("emergency", "unknown")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		var levels []logdash.Level
+		for _, entry := range sink.Entries() {
+			levels = append(levels, entry.Level)
+		}
+		assert.Equal(t, []logdash.Level{
+			logdash.LevelError,
+			logdash.LevelInfo,
+			logdash.LevelDebug,
+			logdash.LevelWarn,
+			logdash.LevelInfo,
+		}, levels)
+		assert.Contains(t, output, `Unknown level "emergency"`)
+	})
+}
+
+func TestLogdashLogFilter(t *testing.T) {
+	t.Run("should drop filtered logs before console and server", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		// WHEN
+		output := captureStdout(t, func() {
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithLogFilter(func(level logdash.Level, message string) bool {
+					return !strings.Contains(message, "/healthz")
+				}),
+				logdash.WithLogFilter(func(level logdash.Level, message string) bool {
+					return level != logdash.LevelDebug
+				}),
+			)
+
+			ld.Logger.HTTP("GET /healthz 200")
+			ld.Logger.Debug("GET /users 200")
+			ld.Logger.HTTPF("GET %s 200", "/users")
+			err := ld.Shutdown(context.Background())
+			assert.NoError(t, err)
+		})
+
+		// THEN
+		assert.NotContains(t, output, "/healthz")
+		assert.Equal(t, 1, strings.Count(output, "GET /users 200"))
+		assert.Len(t, requestsCollector.requests, 1)
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+		assert.Equal(t, "GET /users 200", body["message"])
+		assert.Equal(t, "http", body["level"])
+	})
+}
+
+func TestLogdashLoggerFatalAndPanic(t *testing.T) {
+	testCases := []struct {
+		name          string
+		log           func(logger *logdash.Logger)
+		expectedExit  bool
+		expectedPanic string
+	}{
+		{
+			name:         "should flush log before exit on Fatal",
+			log:          func(logger *logdash.Logger) { logger.Fatal("fatal", "error") },
+			expectedExit: true,
+		},
+		{
+			name:         "should flush log before exit on FatalF",
+			log:          func(logger *logdash.Logger) { logger.FatalF("fatal %s", "error") },
+			expectedExit: true,
+		},
+		{
+			name:          "should flush log before panic on Panic",
+			log:           func(logger *logdash.Logger) { logger.Panic("fatal", "error") },
+			expectedPanic: "fatal error",
+		},
+		{
+			name:          "should flush log before panic on PanicF",
+			log:           func(logger *logdash.Logger) { logger.PanicF("fatal %s", "error") },
+			expectedPanic: "fatal error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				// slow server, so the log wouldn't be sent without flushing
+				time.Sleep(50 * time.Millisecond)
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			var exitCodes []int
+			requestsAtExit := -1
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithExitFunc(func(code int) {
+					exitCodes = append(exitCodes, code)
+					requestsAtExit = len(requestsCollector.requests)
+				}),
+			)
+			defer ld.Close()
+
+			// WHEN
+			if tc.expectedPanic != "" {
+				assert.PanicsWithValue(t, tc.expectedPanic, func() { tc.log(ld.Logger) })
+			} else {
+				tc.log(ld.Logger)
+			}
+
+			// THEN
+			if tc.expectedExit {
+				assert.Equal(t, []int{1}, exitCodes)
+				assert.Equal(t, 1, requestsAtExit)
+			} else {
+				assert.Empty(t, exitCodes)
+			}
+			assert.Len(t, requestsCollector.requests, 1)
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+			assert.Equal(t, "fatal error", body["message"])
+			assert.Equal(t, "error", body["level"])
+		})
+	}
+}
+
+func TestLogdashSource(t *testing.T) {
+	_, testFile, _, _ := runtime.Caller(0)
+
+	testCases := []struct {
+		name string
+		// log logs a message and returns the line of the log call
+		log func(l *logdash.Logger) int
+	}{
+		{
+			name: "should point at call site of Info",
+			log: func(l *logdash.Logger) int {
+				_, _, line, _ := runtime.Caller(0)
+				l.Info("message")
+				return line + 1
+			},
+		},
+		{
+			name: "should point at call site of InfoF",
+			log: func(l *logdash.Logger) int {
+				_, _, line, _ := runtime.Caller(0)
+				l.InfoF("message %d", 1)
+				return line + 1
+			},
+		},
+		{
+			name: "should point at call site of Infow",
+			log: func(l *logdash.Logger) int {
+				_, _, line, _ := runtime.Caller(0)
+				l.Infow("message", "key", "value")
+				return line + 1
+			},
+		},
+		{
+			name: "should point at call site of alias calling another method",
+			log: func(l *logdash.Logger) int {
+				_, _, line, _ := runtime.Caller(0)
+				l.LogF("message %d", 1)
+				return line + 1
+			},
+		},
+		{
+			name: "should point at call site of logger with fields",
+			log: func(l *logdash.Logger) int {
+				_, _, line, _ := runtime.Caller(0)
+				l.WithFields(map[string]any{"key": "value"}).Error("message")
+				return line + 1
+			},
+		},
+		{
+			name: "should point at call site of TryInfo",
+			log: func(l *logdash.Logger) int {
+				_, _, line, _ := runtime.Caller(0)
+				_ = l.TryInfo("message")
+				return line + 1
+			},
+		},
+		{
+			name: "should point at call site of TryErrorF",
+			log: func(l *logdash.Logger) int {
+				_, _, line, _ := runtime.Caller(0)
+				_ = l.TryErrorF("message %d", 1)
+				return line + 1
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			sink := logdash.NewMemorySink()
+			ld := logdash.New(
+				logdash.WithSink(sink),
+				logdash.WithSource(),
+				logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+			)
+
+			// WHEN
+			line := tc.log(ld.Logger)
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			entries := sink.Entries()
+			if assert.Len(t, entries, 1) {
+				assert.Equal(t, fmt.Sprintf("%s:%d", testFile, line), entries[0].Fields["source"])
+				assert.Contains(t, entries[0].Fields["sourceFunction"], "TestLogdashSource")
+			}
+		})
+	}
+
+	t.Run("should attach source only to logs at min level and above", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithSourceMinLevel(logdash.LevelError),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+
+		// WHEN
+		ld.Logger.Info("message")
+		ld.Logger.Error("message")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		entries := sink.Entries()
+		if assert.Len(t, entries, 2) {
+			assert.NotContains(t, entries[0].Fields, "source")
+			assert.Contains(t, entries[1].Fields["source"], testFile)
+		}
+	})
+
+	t.Run("should not attach source by default", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+
+		// WHEN
+		ld.Logger.Error("message")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		entries := sink.Entries()
+		if assert.Len(t, entries, 1) {
+			assert.Nil(t, entries[0].Fields)
+		}
+	})
+}
+
+func TestLogdashLoggerClone(t *testing.T) {
+	t.Run("should filter independently while sharing HTTP output", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithMinLevel(logdash.LevelWarn),
+			logdash.WithOverflowPolicy(logdash.OverflowPolicyBlock),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+		clone := ld.Logger.Clone(logdash.WithLoggerMinLevel(logdash.LevelDebug))
+
+		// WHEN
+		ld.Logger.Info("original info")
+		clone.Info("clone info")
+		ld.Logger.Error("original error")
+		clone.Debug("clone debug")
+		clone.Silly("clone silly")
+		ld.SetMinLevel(logdash.LevelError)
+		ld.Logger.Warn("original warn")
+		clone.Warn("clone warn")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		var messages []string
+		for _, r := range requestsCollector.requests {
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(r.body, &body))
+			messages = append(messages, body["message"].(string))
+		}
+		assert.ElementsMatch(t, []string{"clone info", "original error", "clone debug", "clone warn"}, messages)
+	})
+
+	t.Run("should override fields and console output of clone only", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		var originalOut, cloneOut bytes.Buffer
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithFields(map[string]any{"service": "api"}),
+			logdash.WithConsoleStreams(&originalOut, &originalOut, logdash.LevelError),
+		)
+		clone := ld.Logger.Clone(
+			logdash.WithLoggerFields(map[string]any{"component": "audit"}),
+			logdash.WithLoggerConsoleOutput(&cloneOut),
+		)
+
+		// WHEN
+		ld.Logger.Info("original")
+		clone.Error("clone")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Contains(t, originalOut.String(), "original")
+		assert.NotContains(t, originalOut.String(), "clone")
+		assert.Contains(t, cloneOut.String(), "clone")
+		assert.NotContains(t, cloneOut.String(), "original")
+		entries := sink.Entries()
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, map[string]any{"service": "api"}, entries[0].Fields)
+			assert.Equal(t, map[string]any{"component": "audit"}, entries[1].Fields)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package logdash
+
+import (
+	"fmt"
+	"strings"
+)
+
+// templateMessageKey is the placeholder of the log message in message templates.
+const templateMessageKey = "message"
+
+// messageTemplate renders log messages with placeholders filled from structured data (see: [WithMessageTemplate]).
+type messageTemplate struct {
+	segments []templateSegment
+	// fallback is rendered in place of placeholders of missing fields
+	fallback string
+}
+
+// templateSegment is a literal text or a placeholder of the template.
+type templateSegment struct {
+	text string
+	// key of the placeholder, empty for literal text
+	key string
+}
+
+// newMessageTemplate parses the template with {key} placeholders.
+//
+// Braces which don't form a placeholder, e.g. an unclosed or empty one, are kept as literal text.
+func newMessageTemplate(tmpl string, fallback string) *messageTemplate {
+	t := &messageTemplate{fallback: fallback}
+	for tmpl != "" {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			t.segments = append(t.segments, templateSegment{text: tmpl})
+			break
+		}
+		end := strings.IndexByte(tmpl[start+1:], '}')
+		if end < 0 {
+			t.segments = append(t.segments, templateSegment{text: tmpl})
+			break
+		}
+		end += start + 1
+		if end == start+1 {
+			// empty braces are literal text
+			t.segments = append(t.segments, templateSegment{text: tmpl[:end+1]})
+			tmpl = tmpl[end+1:]
+			continue
+		}
+		if start > 0 {
+			t.segments = append(t.segments, templateSegment{text: tmpl[:start]})
+		}
+		t.segments = append(t.segments, templateSegment{key: tmpl[start+1 : end]})
+		tmpl = tmpl[end+1:]
+	}
+	return t
+}
+
+// render returns the template filled with the message and fields of the data.
+func (t *messageTemplate) render(message string, data map[string]any) string {
+	var b strings.Builder
+	for _, segment := range t.segments {
+		switch {
+		case segment.key == "":
+			b.WriteString(segment.text)
+		case segment.key == templateMessageKey:
+			b.WriteString(message)
+		default:
+			if value, ok := data[segment.key]; ok {
+				fmt.Fprint(&b, value)
+			} else {
+				b.WriteString(t.fallback)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package logdash
+
+import (
+	"os"
+	"runtime/debug"
+)
+
+// MetadataOption is a function that configures detection of deployment metadata (see: [WithDeploymentMetadata]).
+type MetadataOption func(metadata map[string]any)
+
+// deploymentMetadataEnv lists environment variables of deployment metadata fields,
+// the first non-empty variable is used.
+var deploymentMetadataEnv = []struct {
+	field string
+	env   []string
+}{
+	// Kubernetes, Docker and most of Unix-like systems
+	{field: "hostname", env: []string{"HOSTNAME", "COMPUTERNAME"}},
+	// Kubernetes downward API
+	{field: "pod", env: []string{"POD_NAME", "KUBERNETES_POD_NAME"}},
+	// CI systems, Heroku, Render, Vercel
+	{field: "commit", env: []string{"GIT_COMMIT", "GIT_SHA", "COMMIT_SHA", "SOURCE_VERSION", "RENDER_GIT_COMMIT", "VERCEL_GIT_COMMIT_SHA", "GITHUB_SHA"}},
+	// Heroku, Fly.io, Cloud Run
+	{field: "instance", env: []string{"DYNO", "FLY_ALLOC_ID", "K_REVISION", "K_SERVICE"}},
+}
+
+// WithDeploymentMetadata attaches metadata of the deployment to the data of every log,
+// so logs can be correlated with the infrastructure.
+//
+// Metadata are detected from environment variables set by common platforms:
+//   - hostname: HOSTNAME or COMPUTERNAME, with fallback to [os.Hostname],
+//   - pod: POD_NAME or KUBERNETES_POD_NAME,
+//   - commit: GIT_COMMIT, GIT_SHA, COMMIT_SHA, SOURCE_VERSION, RENDER_GIT_COMMIT, VERCEL_GIT_COMMIT_SHA or GITHUB_SHA,
+//     with fallback to the VCS revision the binary was built from,
+//   - instance: DYNO, FLY_ALLOC_ID, K_REVISION or K_SERVICE.
+//
+// Fields which can't be detected are omitted. Use [WithMetadataValue] and [WithoutMetadataField]
+// to override or disable particular fields. Fields set with [WithFields] take precedence over metadata.
+func WithDeploymentMetadata(opts ...MetadataOption) Option {
+	return func(o *options) {
+		metadata := detectDeploymentMetadata()
+		for _, opt := range opts {
+			opt(metadata)
+		}
+		o.deploymentMetadata = metadata
+	}
+}
+
+// WithMetadataValue overrides the detected value of the deployment metadata field.
+func WithMetadataValue(field string, value any) MetadataOption {
+	return func(metadata map[string]any) {
+		metadata[field] = value
+	}
+}
+
+// WithoutMetadataField disables the deployment metadata field.
+func WithoutMetadataField(field string) MetadataOption {
+	return func(metadata map[string]any) {
+		delete(metadata, field)
+	}
+}
+
+// detectDeploymentMetadata detects deployment metadata from the environment.
+func detectDeploymentMetadata() map[string]any {
+	metadata := make(map[string]any, len(deploymentMetadataEnv))
+	for _, field := range deploymentMetadataEnv {
+		for _, env := range field.env {
+			if value := os.Getenv(env); value != "" {
+				metadata[field.field] = value
+				break
+			}
+		}
+	}
+
+	if _, ok := metadata["hostname"]; !ok {
+		if hostname, err := os.Hostname(); err == nil && hostname != "" {
+			metadata["hostname"] = hostname
+		}
+	}
+	if _, ok := metadata["commit"]; !ok {
+		if revision := buildRevision(); revision != "" {
+			metadata["commit"] = revision
+		}
+	}
+	return metadata
+}
+
+// buildRevision returns the VCS revision the binary was built from, if available.
+func buildRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return ""
+}
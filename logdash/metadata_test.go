@@ -0,0 +1,91 @@
+package logdash_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashDeploymentMetadata(t *testing.T) {
+	testCases := []struct {
+		name         string
+		opts         []logdash.Option
+		expectedData map[string]any
+	}{
+		{
+			name: "should attach detected metadata",
+			opts: []logdash.Option{logdash.WithDeploymentMetadata()},
+			expectedData: map[string]any{
+				"hostname": "test-host",
+				"pod":      "test-pod-7f9c",
+				"commit":   "0123abc",
+				"instance": "web.1",
+				"request":  "test-request",
+			},
+		},
+		{
+			name: "should override and disable metadata fields",
+			opts: []logdash.Option{logdash.WithDeploymentMetadata(
+				logdash.WithMetadataValue("commit", "v1.2.3"),
+				logdash.WithoutMetadataField("pod"),
+				logdash.WithoutMetadataField("instance"),
+			)},
+			expectedData: map[string]any{
+				"hostname": "test-host",
+				"commit":   "v1.2.3",
+				"request":  "test-request",
+			},
+		},
+		{
+			name: "should prefer global fields and log data over metadata",
+			opts: []logdash.Option{
+				logdash.WithFields(map[string]any{"hostname": "global-host", "request": "global-request"}),
+				logdash.WithDeploymentMetadata(logdash.WithoutMetadataField("pod"), logdash.WithoutMetadataField("commit")),
+			},
+			expectedData: map[string]any{
+				"hostname": "global-host",
+				"instance": "web.1",
+				"request":  "test-request",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			t.Setenv("HOSTNAME", "test-host")
+			t.Setenv("POD_NAME", "test-pod-7f9c")
+			t.Setenv("GIT_COMMIT", "0123abc")
+			t.Setenv("DYNO", "web.1")
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			ld := logdash.New(append([]logdash.Option{
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+			}, tc.opts...)...)
+
+			// WHEN
+			ld.Logger.InfoJSON(map[string]any{"request": "test-request"}, "Hello, deployment!")
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			assert.Len(t, requestsCollector.requests, 1)
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+			assert.Equal(t, tc.expectedData, body["data"])
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package logdash
+
+import "sync"
+
+// metricUnits keeps units declared for metric names (see: [ExtendedMetrics.DeclareUnit]).
+//
+// It's shared by the metrics front, which declares units, and backends which send them.
+type metricUnits struct {
+	mu             sync.RWMutex
+	units          map[string]string
+	internalLogger *Logger
+}
+
+// newMetricUnits creates a new metricUnits instance.
+func newMetricUnits(internalLogger *Logger) *metricUnits {
+	return &metricUnits{
+		units:          make(map[string]string),
+		internalLogger: internalLogger,
+	}
+}
+
+// declare sets the unit of the metric, the first declared unit is kept.
+func (u *metricUnits) declare(name, unit string) {
+	if unit == "" {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	declared, ok := u.units[name]
+	if !ok {
+		u.units[name] = unit
+		return
+	}
+	if declared != unit {
+		u.internalLogger.WarnF("Unit %q of metric %s conflicts with declared unit %q, keeping %q", unit, name, declared, declared)
+	}
+}
+
+// unit returns the declared unit of the metric, empty when not declared.
+func (u *metricUnits) unit(name string) string {
+	if u == nil {
+		return ""
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.units[name]
+}
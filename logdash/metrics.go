@@ -1,14 +1,117 @@
 package logdash
 
-// Metrics defines the interface for metrics functionality.
-//
-// This is created internally as a part of the [Logdash] object and accessed via the [Logdash.Metrics] field.
-type Metrics interface {
-	resourceManager
+import "net/http"
 
-	// Set sets a metric to an absolute value.
-	Set(name string, value float64)
+type (
+	// Metrics defines the interface for metrics functionality.
+	//
+	// This is created internally as a part of the [Logdash] object and accessed via the [Logdash.Metrics] field.
+	Metrics interface {
+		resourceManager
 
-	// Mutate changes a metric by a relative value.
-	Mutate(name string, value float64)
+		// Set sets a metric to an absolute value.
+		Set(name string, value float64)
+
+		// Mutate changes a metric by a relative value.
+		Mutate(name string, value float64)
+	}
+
+	// ExtendedMetrics extends [Metrics] with units, registration, measurements and metrics tracked locally.
+	//
+	// It's implemented by the metrics object of [Logdash] and accessed via [Logdash.ExtendedMetrics],
+	// so custom implementations of [Metrics] aren't required to implement it.
+	ExtendedMetrics interface {
+		Metrics
+
+		// SetWithUnit sets a metric to an absolute value, declaring its unit (see: [ExtendedMetrics.DeclareUnit]).
+		SetWithUnit(name string, value float64, unit string)
+
+		// DeclareUnit declares the unit of a metric, e.g. "bytes", "ms" or "count",
+		// which is sent to the server along with the metric, so dashboards can render it.
+		//
+		// The unit is declared once per metric name. Conflicting units are reported by the verbose output
+		// (see: [WithVerbose]) and the first declared unit is kept.
+		DeclareUnit(name string, unit string)
+
+		// Register declares metrics up front and sets them to their initial values, e.g.:
+		//
+		//	ld.ExtendedMetrics().Register([]logdash.MetricSpec{
+		//		{Name: "requests", Type: logdash.MetricTypeCounter, Unit: "count"},
+		//		{Name: "connections", Type: logdash.MetricTypeGauge},
+		//	})
+		//
+		// Units are declared (see: [ExtendedMetrics.DeclareUnit]) and types are recorded for the strict mode
+		// (see: [WithStrictMetricTypes]), so the initial values don't count as mixing operations.
+		// Registration is idempotent: metrics which are already registered are skipped,
+		// so their current values are not reset.
+		Register(specs []MetricSpec)
+
+		// Timing starts measuring a duration and returns the function which stops it
+		// and sets the metric to the measured duration in milliseconds, e.g.:
+		//
+		//	defer ld.ExtendedMetrics().Timing("db.query")()
+		//
+		// Only the first call of the returned function sets the metric, following calls do nothing.
+		Timing(name string) func()
+
+		// Observe records a single observation of the metric, e.g. the latency of a request.
+		//
+		// By default, the metric is set to the observed value. With [WithObserveAggregation],
+		// observations are aggregated into summary statistics sent once per interval instead.
+		Observe(name string, value float64)
+
+		// Snapshot returns the current values of all metrics, as tracked locally.
+		//
+		// Values reflect all Set and Mutate calls, no matter if they were sent to the server.
+		// Metrics beyond the limit of [WithMaxMetricNames] are not tracked.
+		Snapshot() map[string]float64
+
+		// PrometheusHandler returns a [http.Handler] exposing the [ExtendedMetrics.Snapshot]
+		// in the Prometheus text exposition format.
+		//
+		// Metrics are exposed as gauges without labels, as metrics have no tags. Names are converted
+		// to valid Prometheus names, and names colliding after the conversion get a numeric suffix.
+		PrometheusHandler() http.Handler
+	}
+
+	// MetricSpec describes a metric registered with [ExtendedMetrics.Register].
+	MetricSpec struct {
+		// Name of the metric.
+		Name string
+		// Type of the metric, it defines which operation is expected in the strict mode.
+		Type MetricType
+		// Unit of the metric, empty means no unit.
+		Unit string
+		// Initial value of the metric.
+		Initial float64
+	}
+
+	// MetricType defines the expected operation of a metric.
+	MetricType int
+
+	// metricsBackend defines the internal interface for metrics implementations wrapped by [Metrics].
+	metricsBackend interface {
+		resourceManager
+
+		// Set sets a metric to an absolute value.
+		Set(name string, value float64)
+
+		// Mutate changes a metric by a relative value.
+		Mutate(name string, value float64)
+	}
+)
+
+const (
+	// MetricTypeGauge is a metric which is set to absolute values with [Metrics.Set].
+	MetricTypeGauge MetricType = iota
+	// MetricTypeCounter is a metric which is changed by relative values with [Metrics.Mutate].
+	MetricTypeCounter
+)
+
+// operation returns the metric operation expected for the type.
+func (t MetricType) operation() string {
+	if t == MetricTypeCounter {
+		return metricOperationMutate
+	}
+	return metricOperationSet
 }
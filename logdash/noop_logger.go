@@ -13,4 +13,5 @@ func newNoopLogger() *noopLogger {
 }
 
 // syncLog implements the syncLogger interface (no-op).
-func (l *noopLogger) syncLog(timestamp time.Time, level logLevel, message string) {}
+func (l *noopLogger) syncLog(timestamp time.Time, level Level, message string, data map[string]any) {
+}
@@ -1,6 +1,6 @@
 package logdash
 
-// noopMetrics implements Metrics interface with no-op operations.
+// noopMetrics implements metricsBackend interface with no-op operations.
 type noopMetrics struct {
 	noopResourceManager
 }
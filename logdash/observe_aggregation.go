@@ -0,0 +1,148 @@
+package logdash
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// observeReservoirSize is the maximum number of observations of a metric kept per interval to compute percentiles.
+const observeReservoirSize = 1024
+
+// observePercentiles are percentiles of observations sent as summary statistics, with suffixes of their names.
+var observePercentiles = []struct {
+	suffix     string
+	percentile float64
+}{
+	{".p50", 0.50},
+	{".p95", 0.95},
+	{".p99", 0.99},
+}
+
+type (
+	// observeAggregator aggregates observations of metrics over an interval into summary statistics
+	// (see: [WithObserveAggregation]).
+	//
+	// Count, min, max and mean are exact, while percentiles are computed from a uniform sample
+	// of at most reservoirSize observations, so the memory used per metric stays flat.
+	observeAggregator struct {
+		reservoirSize int
+		// publish sends a summary statistic of a metric
+		publish func(name string, value float64)
+
+		// mu guards series
+		mu     sync.Mutex
+		series map[string]*observations
+
+		stopChan chan struct{}
+		doneChan chan struct{}
+		stopOnce sync.Once
+	}
+
+	// observations are aggregated observations of a single metric within the interval.
+	observations struct {
+		count    int
+		sum      float64
+		min, max float64
+		// samples is the reservoir of observations, each observation is kept with the same probability
+		samples []float64
+	}
+)
+
+// newObserveAggregator creates a new observeAggregator instance publishing summary statistics every interval.
+func newObserveAggregator(interval time.Duration, reservoirSize int, publish func(name string, value float64)) *observeAggregator {
+	a := &observeAggregator{
+		reservoirSize: reservoirSize,
+		publish:       publish,
+		series:        make(map[string]*observations),
+		stopChan:      make(chan struct{}),
+		doneChan:      make(chan struct{}),
+	}
+	go a.loop(interval)
+	return a
+}
+
+// observe records the observation of the metric.
+func (a *observeAggregator) observe(name string, value float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.series[name]
+	if !ok {
+		s = &observations{min: math.Inf(1), max: math.Inf(-1)}
+		a.series[name] = s
+	}
+	s.count++
+	s.sum += value
+	s.min = min(s.min, value)
+	s.max = max(s.max, value)
+	// reservoir sampling: the n-th observation replaces a random sample with probability size/n
+	if len(s.samples) < a.reservoirSize {
+		s.samples = append(s.samples, value)
+	} else if i := rand.IntN(s.count); i < a.reservoirSize {
+		s.samples[i] = value
+	}
+}
+
+// loop publishes summary statistics every interval until stopped.
+func (a *observeAggregator) loop(interval time.Duration) {
+	defer close(a.doneChan)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.stopChan:
+			return
+		case <-ticker.C:
+			a.flush()
+		}
+	}
+}
+
+// flush publishes summary statistics of observations since the last flush and starts a new interval.
+func (a *observeAggregator) flush() {
+	a.mu.Lock()
+	series := a.series
+	a.series = make(map[string]*observations)
+	a.mu.Unlock()
+
+	for name, s := range series {
+		a.publish(name+".count", float64(s.count))
+		a.publish(name+".min", s.min)
+		a.publish(name+".max", s.max)
+		a.publish(name+".mean", s.sum/float64(s.count))
+		slices.Sort(s.samples)
+		for _, p := range observePercentiles {
+			a.publish(name+p.suffix, percentile(s.samples, p.percentile))
+		}
+	}
+}
+
+// stop stops publishing every interval, observations since the last flush are discarded.
+func (a *observeAggregator) stop() {
+	if a == nil {
+		return
+	}
+	a.stopOnce.Do(func() {
+		close(a.stopChan)
+		<-a.doneChan
+	})
+}
+
+// close stops publishing every interval and publishes observations since the last flush.
+func (a *observeAggregator) close() {
+	if a == nil {
+		return
+	}
+	a.stop()
+	a.flush()
+}
+
+// percentile returns the nearest-rank percentile of sorted samples, which must not be empty.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
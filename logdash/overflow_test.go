@@ -0,0 +1,329 @@
+package logdash_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashLogOverflowPolicy(t *testing.T) {
+	testCases := []struct {
+		name            string
+		policy          logdash.OverflowPolicy
+		expectedAllLogs bool
+	}{
+		{
+			name:            "should drop logs when buffer is full",
+			policy:          logdash.OverflowPolicyDrop,
+			expectedAllLogs: false,
+		},
+		{
+			name:            "should block until logs fit in the buffer",
+			policy:          logdash.OverflowPolicyBlock,
+			expectedAllLogs: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			const logs = 10
+			var requests atomic.Int64
+			release := make(chan struct{})
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				<-release
+				requests.Add(1)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer httpServer.Close()
+
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithBufferSize(1),
+				logdash.WithLogOverflowPolicy(tc.policy),
+			)
+
+			// WHEN
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for range logs {
+					ld.Logger.Info("saturated")
+				}
+			}()
+			if tc.expectedAllLogs {
+				// logging is blocked by the stalled server
+				assert.Never(t, func() bool {
+					select {
+					case <-done:
+						return true
+					default:
+						return false
+					}
+				}, 50*time.Millisecond, 5*time.Millisecond)
+			} else {
+				<-done
+			}
+			close(release)
+			<-done
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			if tc.expectedAllLogs {
+				assert.Equal(t, int64(logs), requests.Load())
+			} else {
+				assert.Less(t, requests.Load(), int64(logs))
+			}
+		})
+	}
+}
+
+func TestLogdashOnDrop(t *testing.T) {
+	t.Run("should receive dropped logs with their fields", func(t *testing.T) {
+		// GIVEN
+		const logs = 10
+		var requests atomic.Int64
+		release := make(chan struct{})
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			<-release
+			requests.Add(1)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		var mu sync.Mutex
+		var dropped []logdash.LogEntry
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithBufferSize(1),
+			logdash.WithLogOverflowPolicy(logdash.OverflowPolicyDrop),
+			logdash.WithFields(map[string]any{"service": "api"}),
+			logdash.WithOnDrop(func(entry logdash.LogEntry) {
+				mu.Lock()
+				defer mu.Unlock()
+				dropped = append(dropped, entry)
+			}),
+		)
+
+		// WHEN
+		for i := range logs {
+			ld.Logger.WithFields(map[string]any{"index": i}).Info("saturated")
+		}
+		close(release)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.NotEmpty(t, dropped)
+		assert.Equal(t, int64(logs), requests.Load()+int64(len(dropped)))
+		for _, entry := range dropped {
+			assert.Equal(t, "info", entry.Level)
+			assert.Equal(t, "saturated", entry.Message)
+			assert.Equal(t, "api", entry.Data["service"])
+			assert.Contains(t, entry.Data, "index")
+		}
+	})
+}
+
+func TestLogdashTryInfo(t *testing.T) {
+	t.Run("should return ErrOverflow when buffer is saturated", func(t *testing.T) {
+		// GIVEN
+		release := make(chan struct{})
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			<-release
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithBufferSize(1),
+			logdash.WithLogOverflowPolicy(logdash.OverflowPolicyDrop),
+		)
+
+		// WHEN
+		var errs []error
+		for range 10 {
+			errs = append(errs, ld.Logger.TryInfo("saturated"))
+		}
+		close(release)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.NoError(t, errs[0])
+		// only the remote output failed, so its error is returned as is
+		assert.Equal(t, logdash.ErrOverflow, errs[len(errs)-1])
+	})
+
+	t.Run("should return nil for logs below the minimum level", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New(logdash.WithMinLevel(logdash.LevelWarn))
+		defer ld.Shutdown(context.Background())
+
+		// WHEN
+		err := ld.Logger.TryInfo("discarded")
+
+		// THEN
+		assert.NoError(t, err)
+	})
+
+	t.Run("should log at the level of each Try method", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithMinLevel(logdash.LevelSilly),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+
+		// WHEN
+		errs := []error{
+			ld.Logger.TryHTTP("http"),
+			ld.Logger.TryVerboseF("verbose %d", 1),
+			ld.Logger.TrySilly("silly"),
+			ld.Logger.TryLog("log"),
+			ld.Logger.TryWarnF("warn %d", 2),
+			ld.Logger.TryLogLevelF(logdash.LevelDebug, "debug %d", 3),
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, []error{nil, nil, nil, nil, nil, nil}, errs)
+		var logged []string
+		for _, entry := range sink.Entries() {
+			logged = append(logged, string(entry.Level)+": "+entry.Message)
+		}
+		assert.Equal(t, []string{
+			"http: http", "verbose: verbose 1", "silly: silly", "info: log", "warning: warn 2", "debug: debug 3",
+		}, logged)
+	})
+}
+
+func TestLogdashBufferWatermarks(t *testing.T) {
+	t.Run("should notify once about crossing each watermark", func(t *testing.T) {
+		// GIVEN
+		var received atomic.Int64
+		release := make(chan struct{})
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			received.Add(1)
+			<-release
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		var highs, lows atomic.Int64
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithBufferSize(10),
+			logdash.WithBufferWatermarks(0.8, 0.2, func() { highs.Add(1) }, func() { lows.Add(1) }),
+		)
+
+		// WHEN
+		ld.Logger.Info("sending")
+		assert.Eventually(t, func() bool { return received.Load() == 1 }, time.Second, time.Millisecond)
+		for range 10 {
+			ld.Logger.Info("buffered")
+		}
+		highsBeforeRelease, lowsBeforeRelease := highs.Load(), lows.Load()
+		close(release)
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), highsBeforeRelease)
+		assert.Equal(t, int64(0), lowsBeforeRelease)
+		assert.Equal(t, int64(1), highs.Load())
+		assert.Equal(t, int64(1), lows.Load())
+	})
+}
+
+func TestLogdashMetricsOverflowPolicy(t *testing.T) {
+	testCases := []struct {
+		name               string
+		opts               []logdash.Option
+		expectedAllMetrics bool
+	}{
+		{
+			name:               "should keep all metrics by default",
+			expectedAllMetrics: true,
+		},
+		{
+			name: "should keep all metrics when blocking with a buffer",
+			opts: []logdash.Option{
+				logdash.WithMetricsBufferSize(16),
+				logdash.WithMetricsOverflowPolicy(logdash.OverflowPolicyBlock),
+			},
+			expectedAllMetrics: true,
+		},
+		{
+			name: "should drop metrics when buffer is full",
+			opts: []logdash.Option{
+				logdash.WithMetricsBufferSize(0),
+				logdash.WithMetricsOverflowPolicy(logdash.OverflowPolicyDrop),
+			},
+			expectedAllMetrics: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			const mutations = 10000
+			var total atomic.Int64
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				var body map[string]any
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				total.Add(int64(body["value"].(float64)))
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer httpServer.Close()
+
+			ld := logdash.New(append([]logdash.Option{
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+			}, tc.opts...)...)
+
+			// WHEN
+			for range mutations {
+				ld.Metrics.Mutate("saturated", 1)
+			}
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			if tc.expectedAllMetrics {
+				assert.Equal(t, int64(mutations), total.Load())
+			} else {
+				assert.Less(t, total.Load(), int64(mutations))
+			}
+		})
+	}
+}
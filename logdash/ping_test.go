@@ -0,0 +1,309 @@
+package logdash_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		if r.URL.Path != "/auth/api-key" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "test-api-key", body["apiKey"])
+		w.WriteHeader(status)
+		if status == http.StatusCreated {
+			_, _ = w.Write([]byte(`{"token":"test-token","projectId":"test-project"}`))
+		}
+	}))
+}
+
+func TestLogdashStartupProbe(t *testing.T) {
+	closedServer := httptest.NewServer(http.NotFoundHandler())
+	closedServer.Close()
+
+	testCases := []struct {
+		name            string
+		host            func(t *testing.T) string
+		expectedSuccess bool
+		expectedError   error
+	}{
+		{
+			name: "should create instance when host is reachable",
+			host: func(t *testing.T) string {
+				httpServer := newAuthServer(t, http.StatusCreated)
+				t.Cleanup(httpServer.Close)
+				return httpServer.URL
+			},
+			expectedSuccess: true,
+		},
+		{
+			name: "should return error when host is unreachable",
+			host: func(t *testing.T) string {
+				return closedServer.URL
+			},
+		},
+		{
+			name: "should return error when API key is rejected",
+			host: func(t *testing.T) string {
+				httpServer := newAuthServer(t, http.StatusUnauthorized)
+				t.Cleanup(httpServer.Close)
+				return httpServer.URL
+			},
+			expectedError: logdash.ErrUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			host := tc.host(t)
+
+			// WHEN
+			ld, err := logdash.NewWithError(
+				logdash.WithHost(host),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithHTTPRetries(0),
+				logdash.WithStartupProbe(time.Second),
+			)
+
+			// THEN
+			if tc.expectedSuccess {
+				assert.NoError(t, err)
+				assert.NotNil(t, ld)
+				assert.NoError(t, ld.Shutdown(context.Background()))
+				return
+			}
+			assert.Error(t, err)
+			assert.Nil(t, ld)
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			}
+		})
+	}
+
+	t.Run("should return error when probe doesn't complete within timeout", func(t *testing.T) {
+		// GIVEN
+		release := make(chan struct{})
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			<-release
+		}))
+		defer httpServer.Close()
+		defer close(release)
+
+		// WHEN
+		start := time.Now()
+		ld, err := logdash.NewWithError(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithStartupProbe(50*time.Millisecond),
+		)
+
+		// THEN
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Nil(t, ld)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("should not probe by default", func(t *testing.T) {
+		// GIVEN
+		// WHEN
+		ld, err := logdash.NewWithError(
+			logdash.WithHost(closedServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+		)
+
+		// THEN
+		assert.NoError(t, err)
+		assert.NoError(t, ld.Close())
+	})
+}
+
+func TestLogdashPing(t *testing.T) {
+	t.Run("should return error when API key is not set", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New()
+		defer ld.Close()
+
+		// WHEN
+		err := ld.Ping(context.Background())
+
+		// THEN
+		assert.ErrorIs(t, err, logdash.ErrNoAPIKey)
+	})
+
+	t.Run("should succeed when API key is accepted", func(t *testing.T) {
+		// GIVEN
+		httpServer := newAuthServer(t, http.StatusCreated)
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+		)
+		defer ld.Close()
+
+		// WHEN
+		err := ld.Ping(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+	})
+}
+
+func TestLogdashDashboardURL(t *testing.T) {
+	t.Run("should point to the project and resolve it only once", func(t *testing.T) {
+		// GIVEN
+		var authRequests atomic.Int64
+		authServer := newAuthServer(t, http.StatusCreated)
+		defer authServer.Close()
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authRequests.Add(1)
+			authServer.Config.Handler.ServeHTTP(w, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+		)
+		defer ld.Close()
+
+		// WHEN
+		first := ld.DashboardURL()
+		second := ld.DashboardURL()
+
+		// THEN
+		assert.Equal(t, httpServer.URL+"/projects/test-project", first)
+		assert.Equal(t, first, second)
+		assert.Equal(t, int64(1), authRequests.Load())
+	})
+
+	t.Run("should return the dashboard when API key is rejected and not resolve it again", func(t *testing.T) {
+		// GIVEN
+		var authRequests atomic.Int64
+		authServer := newAuthServer(t, http.StatusUnauthorized)
+		defer authServer.Close()
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authRequests.Add(1)
+			authServer.Config.Handler.ServeHTTP(w, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+		)
+		defer ld.Close()
+
+		// WHEN
+		first := ld.DashboardURL()
+		second := ld.DashboardURL()
+
+		// THEN
+		assert.Equal(t, httpServer.URL, first)
+		assert.Equal(t, httpServer.URL, second)
+		assert.Equal(t, int64(1), authRequests.Load())
+	})
+
+	t.Run("should return the dashboard without waiting while project is resolved", func(t *testing.T) {
+		// GIVEN
+		received := make(chan struct{})
+		release := make(chan struct{})
+		authServer := newAuthServer(t, http.StatusCreated)
+		defer authServer.Close()
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(received)
+			<-release
+			authServer.Config.Handler.ServeHTTP(w, r)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+		)
+		defer ld.Close()
+		resolved := make(chan string)
+		go func() {
+			resolved <- ld.DashboardURL()
+		}()
+		<-received
+
+		// WHEN
+		concurrent := ld.DashboardURL()
+		close(release)
+
+		// THEN
+		assert.Equal(t, httpServer.URL, concurrent)
+		assert.Equal(t, httpServer.URL+"/projects/test-project", <-resolved)
+	})
+
+	t.Run("should return the dashboard when API key is not set", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New()
+		defer ld.Close()
+
+		// WHEN
+		dashboardURL := ld.DashboardURL()
+
+		// THEN
+		assert.Equal(t, "https://app.logdash.io", dashboardURL)
+	})
+}
+
+func TestLogdashInvalidHost(t *testing.T) {
+	t.Run("should return error for invalid host", func(t *testing.T) {
+		// WHEN
+		ld, err := logdash.NewWithError(
+			logdash.WithHost("ftp://api.logdash.io"),
+			logdash.WithAPIKey("test-api-key"),
+		)
+
+		// THEN
+		assert.ErrorIs(t, err, logdash.ErrInvalidHost)
+		assert.Nil(t, ld)
+	})
+
+	t.Run("should send logs to schemeless host with trailing slash", func(t *testing.T) {
+		// GIVEN
+		requestsCollector := &requestsCollector{}
+		httpServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			w.WriteHeader(http.StatusOK)
+			requestsCollector.add(t, r)
+		}))
+		defer httpServer.Close()
+
+		ld, err := logdash.NewWithError(
+			logdash.WithHost(strings.TrimPrefix(httpServer.URL, "https://")+"/"),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPClient(httpServer.Client()),
+		)
+		assert.NoError(t, err)
+
+		// WHEN
+		ld.Logger.Info("Hello, World!")
+		err = ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Len(t, requestsCollector.requests, 1)
+		assert.Equal(t, "/logs", requestsCollector.requests[0].request.URL.Path)
+	})
+}
@@ -0,0 +1,95 @@
+package logdash
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// prometheusContentType is the content type of the Prometheus text exposition format.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// newPrometheusHandler creates a handler exposing metrics returned by snapshot
+// in the Prometheus text exposition format.
+//
+// All metrics are exposed as gauges without labels, as metrics have no tags.
+func newPrometheusHandler(snapshot func() map[string]float64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", prometheusContentType)
+		w.Write(formatPrometheus(snapshot()))
+	})
+}
+
+// formatPrometheus formats metrics in the Prometheus text exposition format, sorted by name.
+func formatPrometheus(values map[string]float64) []byte {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	promNames := prometheusNames(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		promName := promNames[name]
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", promName)
+		fmt.Fprintf(&buf, "%s %s\n", promName, strconv.FormatFloat(values[name], 'g', -1, 64))
+	}
+	return buf.Bytes()
+}
+
+// prometheusNames converts sorted metric names to unique Prometheus metric names.
+//
+// Names which are valid already are kept, while converted names colliding with others, e.g. "a.b" with "a_b",
+// get the first free suffix "_2", "_3" and so on, so each metric is exposed as a separate family.
+func prometheusNames(names []string) map[string]string {
+	promNames := make(map[string]string, len(names))
+	used := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if prometheusName(name) == name {
+			promNames[name] = name
+			used[name] = struct{}{}
+		}
+	}
+	for _, name := range names {
+		if _, ok := promNames[name]; ok {
+			continue
+		}
+		promName := prometheusName(name)
+		for i := 2; ; i++ {
+			if _, ok := used[promName]; !ok {
+				break
+			}
+			promName = prometheusName(name) + "_" + strconv.Itoa(i)
+		}
+		promNames[name] = promName
+		used[promName] = struct{}{}
+	}
+	return promNames
+}
+
+// prometheusName converts the metric name to a valid Prometheus metric name,
+// replacing invalid characters with underscores.
+func prometheusName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package logdash
+
+import "time"
+
+// rateLimiter spaces out requests evenly, so at most perSecond requests are sent per second.
+//
+// It's a token bucket holding a single token, so bursts are smoothed.
+// It's not safe for concurrent use.
+type rateLimiter struct {
+	// interval between consecutive requests
+	interval time.Duration
+	// next is the earliest time of the next request
+	next time.Time
+}
+
+// newRateLimiter creates a new rateLimiter instance, nil when perSecond is not positive.
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the next request may be sent.
+//
+// It returns immediately once bypass is closed, e.g. to flush pending requests on shutdown.
+func (l *rateLimiter) wait(bypass <-chan struct{}) {
+	if l == nil {
+		return
+	}
+
+	now := time.Now()
+	if delay := l.next.Sub(now); delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-bypass:
+		}
+		timer.Stop()
+		now = time.Now()
+	}
+	l.next = now.Add(l.interval)
+}
@@ -0,0 +1,144 @@
+package logdash
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// readinessGate implements syncLogger interface, holding logs until the application is ready
+// (see: [Logdash.HoldUntilReady]).
+//
+// While held, it keeps the most recent logs in a bounded ring buffer. When released, it replays them in order
+// and forwards all following logs to the target, until it's held again.
+type readinessGate struct {
+	target  syncLogger
+	holding atomic.Bool
+	// disabled gates never hold logs, e.g. in audit mode, where no log may be discarded
+	disabled bool
+	// onDrop is called for every held log which is discarded, nil means drops are not reported
+	onDrop func()
+
+	// mu guards the buffer and releasing
+	mu     sync.Mutex
+	buffer []bufferedLog
+	size   int
+	// next is the index of the next buffered log in the ring buffer
+	next int
+	// full is true when the ring buffer wrapped around
+	full bool
+}
+
+// newReadinessGate creates a new readinessGate instance holding up to size logs.
+func newReadinessGate(target syncLogger, size int) *readinessGate {
+	return &readinessGate{
+		target: target,
+		size:   max(size, 0),
+	}
+}
+
+// hold starts holding logs until release, unless the gate is disabled.
+func (g *readinessGate) hold() {
+	if g.disabled {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.holding.Store(true)
+}
+
+// release replays held logs to the target in order and forwards all following logs to it.
+func (g *readinessGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	start := 0
+	if g.full {
+		start = g.next
+	}
+	for i := range g.buffer {
+		entry := g.buffer[(start+i)%len(g.buffer)]
+		g.target.syncLog(entry.timestamp, entry.level, entry.message, entry.data)
+	}
+	g.buffer, g.next, g.full = nil, 0, false
+
+	g.holding.Store(false)
+}
+
+// syncLog implements the syncLogger interface.
+func (g *readinessGate) syncLog(timestamp time.Time, level Level, message string, data map[string]any) {
+	if !g.holding.Load() {
+		g.target.syncLog(timestamp, level, message, data)
+		return
+	}
+
+	g.mu.Lock()
+	// the gate may be released while waiting for the lock
+	if !g.holding.Load() {
+		g.mu.Unlock()
+		g.target.syncLog(timestamp, level, message, data)
+		return
+	}
+	defer g.mu.Unlock()
+
+	if g.size == 0 {
+		g.drop(1)
+		return
+	}
+	entry := bufferedLog{timestamp: timestamp, level: level, message: message, data: data}
+	if len(g.buffer) < g.size {
+		g.buffer = append(g.buffer, entry)
+	} else {
+		// the oldest log is overwritten
+		g.drop(1)
+		g.buffer[g.next] = entry
+		g.full = true
+	}
+	g.next = (g.next + 1) % g.size
+}
+
+// drop reports the number of discarded held logs.
+func (g *readinessGate) drop(count int) {
+	if g.onDrop == nil {
+		return
+	}
+	for range count {
+		g.onDrop()
+	}
+}
+
+// trySyncLog implements the trySyncLogger interface, held logs are never reported as dropped.
+func (g *readinessGate) trySyncLog(timestamp time.Time, level Level, message string, data map[string]any) error {
+	if !g.holding.Load() {
+		return trySyncLog(g.target, timestamp, level, message, data)
+	}
+	g.syncLog(timestamp, level, message, data)
+	return nil
+}
+
+// flush flushes the target, logs which are held are not waited for.
+func (g *readinessGate) flush(ctx context.Context) error {
+	if f, ok := g.target.(flusher); ok {
+		return f.flush(ctx)
+	}
+	return nil
+}
+
+// Shutdown releases held logs, so they're sent, and shuts down the target.
+func (g *readinessGate) Shutdown(ctx context.Context) error {
+	g.release()
+	return g.target.Shutdown(ctx)
+}
+
+// Close closes the target, held logs are discarded and reported as dropped.
+func (g *readinessGate) Close() error {
+	g.mu.Lock()
+	g.drop(len(g.buffer))
+	g.buffer, g.next, g.full = nil, 0, false
+	g.mu.Unlock()
+
+	return g.target.Close()
+}
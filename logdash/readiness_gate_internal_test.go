@@ -0,0 +1,47 @@
+package logdash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadinessGateDrops(t *testing.T) {
+	t.Run("should report overwritten and closed held logs as dropped", func(t *testing.T) {
+		// GIVEN
+		var drops int
+		gate := newReadinessGate(newNoopLogger(), 2)
+		gate.onDrop = func() { drops++ }
+		gate.hold()
+
+		// WHEN
+		for range 5 {
+			gate.syncLog(time.Now(), LevelInfo, "held", nil)
+		}
+		overwritten := drops
+		err := gate.Close()
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, 3, overwritten)
+		assert.Equal(t, 5, drops)
+	})
+
+	t.Run("should not report released logs as dropped", func(t *testing.T) {
+		// GIVEN
+		var drops int
+		gate := newReadinessGate(newNoopLogger(), 2)
+		gate.onDrop = func() { drops++ }
+		gate.hold()
+		gate.syncLog(time.Now(), LevelInfo, "held", nil)
+
+		// WHEN
+		gate.release()
+		err := gate.Close()
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Zero(t, drops)
+	})
+}
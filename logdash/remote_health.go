@@ -0,0 +1,99 @@
+package logdash
+
+import "sync"
+
+// remoteHealth tracks whether sending data to the server succeeds
+// and notifies about transitions between healthy and unhealthy states.
+//
+// The remote is considered healthy until threshold consecutive sends fail.
+// Then recoveryThreshold consecutive successful sends make it healthy again,
+// so a single send succeeding while the remote keeps failing doesn't flip the state.
+type remoteHealth struct {
+	mu                sync.Mutex
+	threshold         int
+	recoveryThreshold int
+	failures          int
+	successes         int
+	unhealthy         bool
+	onHealthy         []func()
+	onUnhealthy       func(error)
+
+	// pending notifications are delivered in order by a single goroutine,
+	// which is started only when there is something to deliver
+	pending    []func()
+	delivering bool
+}
+
+// newRemoteHealth creates a new remoteHealth instance.
+func newRemoteHealth(threshold int, recoveryThreshold int, onHealthy func(), onUnhealthy func(error)) *remoteHealth {
+	h := &remoteHealth{
+		threshold:         max(threshold, 1),
+		recoveryThreshold: max(recoveryThreshold, 1),
+		onUnhealthy:       onUnhealthy,
+	}
+	if onHealthy != nil {
+		h.onHealthy = append(h.onHealthy, onHealthy)
+	}
+	return h
+}
+
+// addOnHealthy adds the callback invoked when the remote becomes healthy again.
+func (h *remoteHealth) addOnHealthy(fn func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onHealthy = append(h.onHealthy, fn)
+}
+
+// record registers the result of a single send.
+func (h *remoteHealth) record(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err == nil {
+		h.failures = 0
+		h.successes++
+		if h.unhealthy && h.successes >= h.recoveryThreshold {
+			h.unhealthy = false
+			for _, fn := range h.onHealthy {
+				h.notify(fn)
+			}
+		}
+		return
+	}
+
+	h.successes = 0
+	h.failures++
+	if !h.unhealthy && h.failures >= h.threshold {
+		h.unhealthy = true
+		if h.onUnhealthy != nil {
+			h.notify(func() { h.onUnhealthy(err) })
+		}
+	}
+}
+
+// notify enqueues the callback, so it's invoked off the sending goroutine.
+// Must be called with h.mu held.
+func (h *remoteHealth) notify(fn func()) {
+	h.pending = append(h.pending, fn)
+	if !h.delivering {
+		h.delivering = true
+		go h.deliver()
+	}
+}
+
+// deliver invokes pending callbacks one by one until the queue is empty.
+func (h *remoteHealth) deliver() {
+	for {
+		h.mu.Lock()
+		if len(h.pending) == 0 {
+			h.delivering = false
+			h.mu.Unlock()
+			return
+		}
+		fn := h.pending[0]
+		h.pending = h.pending[1:]
+		h.mu.Unlock()
+
+		fn()
+	}
+}
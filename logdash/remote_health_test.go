@@ -0,0 +1,167 @@
+package logdash_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashRemoteHealthCallbacks(t *testing.T) {
+	t.Run("should notify once per transition when server fails and recovers", func(t *testing.T) {
+		// GIVEN
+		var (
+			failing        atomic.Bool
+			requests       atomic.Int64
+			healthyCalls   atomic.Int64
+			unhealthyCalls atomic.Int64
+		)
+		failing.Store(true)
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			defer requests.Add(1)
+			if failing.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetries(0),
+			logdash.WithRemoteFailureThreshold(2),
+			logdash.WithOnRemoteHealthy(func() {
+				healthyCalls.Add(1)
+			}),
+			logdash.WithOnRemoteUnhealthy(func(err error) {
+				assert.Error(t, err)
+				unhealthyCalls.Add(1)
+			}),
+		)
+
+		// WHEN
+		for range 4 {
+			ld.Logger.Info("failing")
+		}
+		assert.Eventually(t, func() bool { return requests.Load() == 4 }, time.Second, time.Millisecond)
+
+		failing.Store(false)
+		for range 3 {
+			ld.Logger.Info("recovered")
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			return unhealthyCalls.Load() == 1 && healthyCalls.Load() == 1
+		}, time.Second, time.Millisecond)
+		// no more notifications are delivered
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, int64(1), unhealthyCalls.Load())
+		assert.Equal(t, int64(1), healthyCalls.Load())
+	})
+
+	t.Run("should not notify when failures don't reach the threshold", func(t *testing.T) {
+		// GIVEN
+		var (
+			requests       atomic.Int64
+			healthyCalls   atomic.Int64
+			unhealthyCalls atomic.Int64
+		)
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			// every other request fails
+			if requests.Add(1)%2 == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetries(0),
+			logdash.WithRemoteFailureThreshold(2),
+			logdash.WithOnRemoteHealthy(func() {
+				healthyCalls.Add(1)
+			}),
+			logdash.WithOnRemoteUnhealthy(func(err error) {
+				unhealthyCalls.Add(1)
+			}),
+		)
+
+		// WHEN
+		for range 10 {
+			ld.Logger.Info("flapping")
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), requests.Load())
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, int64(0), unhealthyCalls.Load())
+		assert.Equal(t, int64(0), healthyCalls.Load())
+	})
+
+	t.Run("should not notify recovery until consecutive sends succeed", func(t *testing.T) {
+		// GIVEN
+		var (
+			requests       atomic.Int64
+			healthyCalls   atomic.Int64
+			unhealthyCalls atomic.Int64
+		)
+
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			// the first 2 requests fail, then every other request fails
+			n := requests.Add(1)
+			if n <= 2 || n%2 == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetries(0),
+			logdash.WithRemoteFailureThreshold(2),
+			logdash.WithRemoteRecoveryThreshold(2),
+			logdash.WithOnRemoteHealthy(func() {
+				healthyCalls.Add(1)
+			}),
+			logdash.WithOnRemoteUnhealthy(func(err error) {
+				unhealthyCalls.Add(1)
+			}),
+		)
+
+		// WHEN
+		for range 10 {
+			ld.Logger.Info("flapping")
+		}
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), requests.Load())
+		assert.Eventually(t, func() bool { return unhealthyCalls.Load() == 1 }, time.Second, time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, int64(0), healthyCalls.Load())
+	})
+}
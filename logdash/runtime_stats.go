@@ -0,0 +1,49 @@
+package logdash
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// runtimeStatsInterval is the minimum interval of reading memory statistics, which stops the world.
+const runtimeStatsInterval = time.Second
+
+// runtimeStats provides runtime statistics attached to severe logs (see: [WithRuntimeStatsOnError]).
+//
+// The number of goroutines is cheap to read, so it's always current,
+// while memory statistics are read at most once per interval and cached.
+type runtimeStats struct {
+	interval time.Duration
+
+	// mu guards the cached memory statistics
+	mu        sync.Mutex
+	readAt    time.Time
+	heapAlloc uint64
+	numGC     uint32
+}
+
+// newRuntimeStats creates a new runtimeStats instance reading memory statistics at most once per interval.
+func newRuntimeStats(interval time.Duration) *runtimeStats {
+	return &runtimeStats{interval: interval}
+}
+
+// fields returns the current statistics as fields of a log.
+func (s *runtimeStats) fields() map[string]any {
+	s.mu.Lock()
+	if s.readAt.IsZero() || time.Since(s.readAt) >= s.interval {
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		s.readAt = time.Now()
+		s.heapAlloc = memStats.HeapAlloc
+		s.numGC = memStats.NumGC
+	}
+	heapAlloc, numGC := s.heapAlloc, s.numGC
+	s.mu.Unlock()
+
+	return map[string]any{
+		"goroutines":     runtime.NumGoroutine(),
+		"heapAllocBytes": heapAlloc,
+		"numGC":          numGC,
+	}
+}
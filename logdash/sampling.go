@@ -0,0 +1,18 @@
+package logdash
+
+import "math/rand/v2"
+
+// keyedSampler keeps logs flagged by the value of a field and samples the rest (see: [WithKeyedSampling]).
+type keyedSampler struct {
+	key  string
+	rate float64
+	keep func(value any) bool
+}
+
+// sample reports whether the log with the data is kept.
+func (s *keyedSampler) sample(data map[string]any) bool {
+	if value, ok := data[s.key]; ok && s.keep != nil && s.keep(value) {
+		return true
+	}
+	return rand.Float64() < s.rate
+}
@@ -0,0 +1,65 @@
+package logdash
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maskedSecret replaces secrets in the internal logs.
+const maskedSecret = "****"
+
+// sensitiveHeaderPattern matches values of headers carrying credentials, e.g. project-api-key: <key>.
+var sensitiveHeaderPattern = regexp.MustCompile(`(?i)((?:project-api-key|authorization|api-?key)["']?\s*[:=]\s*\[?["']?(?:(?:bearer|basic)\s+)?)[^"'\]\s,}]+`)
+
+type (
+	// secretMasker replaces API keys and values of sensitive headers in messages.
+	secretMasker struct {
+		mu      sync.RWMutex
+		secrets []string
+	}
+
+	// maskingLogger implements syncLogger interface, masking secrets in messages of the wrapped syncLogger.
+	maskingLogger struct {
+		syncLogger
+		masker *secretMasker
+	}
+)
+
+// add registers the secret to be masked, empty secrets are ignored.
+func (m *secretMasker) add(secret string) {
+	if secret == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets = append(m.secrets, secret)
+}
+
+// mask returns the message with registered secrets and values of sensitive headers masked.
+func (m *secretMasker) mask(message string) string {
+	m.mu.RLock()
+	for _, secret := range m.secrets {
+		message = strings.ReplaceAll(message, secret, maskedSecret)
+	}
+	m.mu.RUnlock()
+
+	return sensitiveHeaderPattern.ReplaceAllString(message, "${1}"+maskedSecret)
+}
+
+// unwrap implements the wrapperLogger interface.
+func (l *maskingLogger) unwrap() syncLogger {
+	return l.syncLogger
+}
+
+// wrap implements the wrapperLogger interface.
+func (l *maskingLogger) wrap(logger syncLogger) syncLogger {
+	return &maskingLogger{syncLogger: logger, masker: l.masker}
+}
+
+// syncLog implements the syncLogger interface.
+func (l *maskingLogger) syncLog(timestamp time.Time, level Level, message string, data map[string]any) {
+	l.syncLogger.syncLog(timestamp, level, l.masker.mask(message), data)
+}
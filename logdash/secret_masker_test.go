@@ -0,0 +1,51 @@
+package logdash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretMasker(t *testing.T) {
+	testCases := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{
+			name:     "should mask registered secret",
+			message:  "request with key secret-key failed",
+			expected: "request with key **** failed",
+		},
+		{
+			name:     "should mask value of API key header",
+			message:  "headers: map[Project-Api-Key:[unknown-key] Content-Type:[application/json]]",
+			expected: "headers: map[Project-Api-Key:[****] Content-Type:[application/json]]",
+		},
+		{
+			name:     "should mask value of authorization header",
+			message:  `{"authorization": "Bearer token"}`,
+			expected: `{"authorization": "Bearer ****"}`,
+		},
+		{
+			name:     "should keep message without secrets",
+			message:  "PUT http://localhost/metrics",
+			expected: "PUT http://localhost/metrics",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			masker := &secretMasker{}
+			masker.add("secret-key")
+			masker.add("")
+
+			// WHEN
+			masked := masker.mask(tc.message)
+
+			// THEN
+			assert.Equal(t, tc.expected, masked)
+		})
+	}
+}
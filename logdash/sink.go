@@ -0,0 +1,113 @@
+package logdash
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+type (
+	// Sink receives logs in addition to the console and the server (see: [WithSink]).
+	//
+	// Log is called synchronously on every log call, so it should be fast and non-blocking.
+	// It must be safe for concurrent use and must not modify the fields.
+	Sink interface {
+		Log(timestamp time.Time, level Level, message string, fields map[string]any)
+	}
+
+	// sinkLogger implements syncLogger interface for a [Sink].
+	sinkLogger struct {
+		noopResourceManager
+		sink Sink
+	}
+
+	// SinkEntry is a log recorded by [MemorySink].
+	SinkEntry struct {
+		Timestamp time.Time
+		Level     Level
+		Message   string
+		Fields    map[string]any
+	}
+
+	// MemorySink is a [Sink] keeping logs in memory, e.g. to assert on them in tests.
+	//
+	// The zero value is ready to use. MemorySink is safe for concurrent use.
+	MemorySink struct {
+		mu      sync.Mutex
+		entries []SinkEntry
+		// added is closed and replaced when an entry is added, so waiters are woken up
+		added chan struct{}
+	}
+)
+
+// syncLog implements the syncLogger interface.
+func (l *sinkLogger) syncLog(timestamp time.Time, level Level, message string, data map[string]any) {
+	l.sink.Log(timestamp, level, message, data)
+}
+
+// NewMemorySink creates a new MemorySink instance.
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+// Log implements the [Sink] interface.
+func (s *MemorySink) Log(timestamp time.Time, level Level, message string, fields map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, SinkEntry{
+		Timestamp: timestamp,
+		Level:     level,
+		Message:   message,
+		Fields:    maps.Clone(fields),
+	})
+	if s.added != nil {
+		close(s.added)
+		s.added = nil
+	}
+}
+
+// Entries returns a copy of the recorded logs, oldest first.
+func (s *MemorySink) Entries() []SinkEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]SinkEntry, len(s.entries))
+	copy(entries, s.entries)
+	return entries
+}
+
+// Reset forgets all recorded logs.
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = nil
+}
+
+// WaitFor waits until at least n logs are recorded.
+//
+// It returns false when the timeout passes first.
+func (s *MemorySink) WaitFor(n int, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		if len(s.entries) >= n {
+			s.mu.Unlock()
+			return true
+		}
+		if s.added == nil {
+			s.added = make(chan struct{})
+		}
+		added := s.added
+		s.mu.Unlock()
+
+		select {
+		case <-added:
+		case <-timer.C:
+			return false
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package logdash_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySink(t *testing.T) {
+	t.Run("should record logs written concurrently", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		const writers, logs = 10, 100
+
+		// WHEN
+		var wg sync.WaitGroup
+		for range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range logs {
+					sink.Log(time.Now(), logdash.LevelInfo, "message", nil)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// THEN
+		assert.Len(t, sink.Entries(), writers*logs)
+	})
+
+	t.Run("should forget logs on reset", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		sink.Log(time.Now(), logdash.LevelInfo, "message", nil)
+
+		// WHEN
+		sink.Reset()
+
+		// THEN
+		assert.Empty(t, sink.Entries())
+	})
+
+	t.Run("should wait for logs written later", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		go func() {
+			for range 3 {
+				time.Sleep(10 * time.Millisecond)
+				sink.Log(time.Now(), logdash.LevelInfo, "message", nil)
+			}
+		}()
+
+		// WHEN
+		ok := sink.WaitFor(3, time.Second)
+
+		// THEN
+		assert.True(t, ok)
+		assert.Len(t, sink.Entries(), 3)
+	})
+
+	t.Run("should stop waiting after timeout", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		sink.Log(time.Now(), logdash.LevelInfo, "message", nil)
+
+		// WHEN
+		start := time.Now()
+		ok := sink.WaitFor(2, 50*time.Millisecond)
+
+		// THEN
+		assert.False(t, ok)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("should receive logs of logger", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithMinLevel(logdash.LevelInfo),
+			logdash.WithFields(map[string]any{"service": "api"}),
+		)
+
+		// WHEN
+		ld.Logger.Debug("suppressed")
+		ld.Logger.Warn("disk low")
+		err := ld.Shutdown(context.Background())
+
+		// THEN
+		assert.NoError(t, err)
+		entries := sink.Entries()
+		assert.Len(t, entries, 1)
+		assert.Equal(t, logdash.LevelWarn, entries[0].Level)
+		assert.Equal(t, "disk low", entries[0].Message)
+		assert.Equal(t, map[string]any{"service": "api"}, entries[0].Fields)
+	})
+}
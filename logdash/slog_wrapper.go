@@ -2,10 +2,12 @@ package logdash
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"runtime"
 	"slices"
+	"sync"
 	"time"
 )
 
@@ -15,43 +17,97 @@ import (
 //
 // [slog.HandlerOptions] are fully supported.
 //
-// Basic mapping between [slog.Level] and [logdash.logLevel] is:
-//   - [slog.LevelDebug] (-4) → [logdash.Debug]
-//   - [slog.LevelInfo] (0) → [logdash.Info]
-//   - [slog.LevelWarn] (4) → [logdash.Warn]
-//   - [slog.LevelError] (8) → [logdash.Error]
+// Basic mapping between [slog.Level] and [Level] is:
+//   - [slog.LevelDebug] (-4) → [LevelDebug]
+//   - [slog.LevelInfo] (0) → [LevelInfo]
+//   - [slog.LevelWarn] (4) → [LevelWarn]
+//   - [slog.LevelError] (8) → [LevelError]
 //
 // Since [slog.Level] is an integer type, the mapping handles any intermediate or custom level values:
-//   - Levels < [slog.LevelDebug] (-4) → [logdash.Silly]
-//   - Levels ≥ [slog.LevelDebug] (-4) and < [slog.LevelInfo] (0) → [logdash.Debug]
-//   - Levels ≥ [slog.LevelInfo] (0) and < [slog.LevelWarn] (4) → [logdash.Info]
-//   - Levels ≥ [slog.LevelWarn] (4) and < [slog.LevelError] (8) → [logdash.Warn]
-//   - Levels ≥ [slog.LevelError] (8) → [logdash.Error]
+//   - Levels < [slog.LevelDebug] (-4) → [LevelSilly]
+//   - Levels ≥ [slog.LevelDebug] (-4) and < [slog.LevelInfo] (0) → [LevelDebug]
+//   - Levels ≥ [slog.LevelInfo] (0) and < [slog.LevelWarn] (4) → [LevelInfo]
+//   - Levels ≥ [slog.LevelWarn] (4) and < [slog.LevelError] (8) → [LevelWarn]
+//   - Levels ≥ [slog.LevelError] (8) → [LevelError]
 //
 // If you want to log with a custom level, you can use [slog.Level] directly.
+//
+// Attributes are formatted as text in the message, the same way as [slog.TextHandler] does.
+// Use [WithSlogStructuredAttrs] to send them as structured data instead.
 type SlogTextHandler struct {
 	opts              slog.HandlerOptions
-	preformattedAttrs []string // contains all attrs that are already formatted
-	groupPrefix       string   // contains all groups prefix with "."
-	groups            []string // all groups started from WithGroup
+	preformattedAttrs []string       // contains all attrs that are already formatted
+	fields            slogGroup      // contains all attrs added with WithAttrs, never modified in place
+	fieldsData        map[string]any // contains fields as sent, never modified in place
+	groupPrefix       string         // contains all groups prefix with "."
+	groups            []string       // all groups started from WithGroup
+	structuredAttrs   bool           // attrs are sent as structured data
 	logger            *Logger
 }
 
+// slogGroup is a group of attributes created by [SlogTextHandler], so it can be modified,
+// unlike maps passed by callers as attribute values.
+type slogGroup map[string]any
+
+// SlogHandlerOption is a function that configures [SlogTextHandler].
+type SlogHandlerOption func(*SlogTextHandler)
+
+// WithSlogStructuredAttrs makes [SlogTextHandler] send attributes as structured data preserving groups,
+// separately from the message, instead of formatting them as text in the message:
+//   - "fields" contains persistent attributes added with [SlogTextHandler.WithAttrs],
+//   - "attrs" contains attributes of the record.
+func WithSlogStructuredAttrs() SlogHandlerOption {
+	return func(h *SlogTextHandler) {
+		h.structuredAttrs = true
+	}
+}
+
 // NewSlogTextHandler creates a new [SlogTextHandler] with the given [Logger] and [slog.HandlerOptions].
-func NewSlogTextHandler(logger *Logger, opts slog.HandlerOptions) *SlogTextHandler {
-	return &SlogTextHandler{opts: opts, logger: logger}
+func NewSlogTextHandler(logger *Logger, opts slog.HandlerOptions, handlerOpts ...SlogHandlerOption) *SlogTextHandler {
+	h := &SlogTextHandler{opts: opts, logger: logger}
+	for _, opt := range handlerOpts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *SlogTextHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	return h.opts.Level.Level() <= level.Level()
 }
 
+// slogAttrsPool holds slices in which attributes formatted as text are collected.
+var slogAttrsPool = sync.Pool{
+	New: func() any {
+		attrs := make([]string, 0, 16)
+		return &attrs
+	},
+}
+
+// maxPooledSlogAttrs limits the capacity of slices returned to the pool,
+// so a single record with many attributes doesn't keep its memory around.
+const maxPooledSlogAttrs = 256
+
 func (h *SlogTextHandler) Handle(ctx context.Context, r slog.Record) error {
-	// +1 for message
-	// +1 for the source
-	attrs := make([]string, len(h.preformattedAttrs)+1, len(h.preformattedAttrs)+r.NumAttrs()+2)
-	attrs[0] = fmt.Sprintf("%q", r.Message)
-	copy(attrs[1:], h.preformattedAttrs)
+	if !h.logger.keepForTrace(ctx, convertSlogLevel(r.Level)) {
+		return nil
+	}
+	baggage := h.logger.baggageFields(ctx)
+	if h.structuredAttrs {
+		return h.handleStructured(r, baggage)
+	}
+
+	// the attrs are joined into the message before logWithAttrs returns, so the slice can be reused
+	pooled := slogAttrsPool.Get().(*[]string)
+	defer func() {
+		if cap(*pooled) <= maxPooledSlogAttrs {
+			clear(*pooled)
+			*pooled = (*pooled)[:0]
+			slogAttrsPool.Put(pooled)
+		}
+	}()
+
+	attrs := append((*pooled)[:0], fmt.Sprintf("%q", r.Message))
+	attrs = append(attrs, h.preformattedAttrs...)
 	r.Attrs(func(a slog.Attr) bool {
 		a = h.safeReplaceAttr(h.groups, a)
 		if a.Equal(slog.Attr{}) {
@@ -73,15 +129,72 @@ func (h *SlogTextHandler) Handle(ctx context.Context, r slog.Record) error {
 
 	// time is not added as text, because we put it into logdash logger as time.Time
 	if r.Time.IsZero() {
-		r.Time = time.Now()
+		r.Time = h.logger.now()
+	}
+
+	h.logger.logWithAttrs(r.Time, convertSlogLevel(r.Level), attrs, baggage)
+	*pooled = attrs
+	return nil
+}
+
+// handleStructured logs the record with attributes as structured data, along with fields of the baggage.
+func (h *SlogTextHandler) handleStructured(r slog.Record, baggage map[string]any) error {
+	var attrs slogGroup
+	if r.NumAttrs() > 0 {
+		attrs = make(slogGroup, r.NumAttrs())
+		group := groupMap(attrs, h.groups)
+		r.Attrs(func(a slog.Attr) bool {
+			h.addAttr(group, h.groups, a)
+			return true
+		})
+	}
+	// add source
+	if h.opts.AddSource && r.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := fs.Next()
+		if attrs == nil {
+			attrs = make(slogGroup, 1)
+		}
+		h.addAttr(groupMap(attrs, h.groups), h.groups, slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", f.File, f.Line)))
+	}
+	pruneEmptyGroups(attrs)
+
+	data := baggage
+	if len(h.fields) > 0 || len(attrs) > 0 {
+		if data == nil {
+			data = make(map[string]any, 2)
+		}
+		if len(h.fields) > 0 {
+			data["fields"] = h.fieldsData
+		}
+		if len(attrs) > 0 {
+			data["attrs"] = attrs.plain()
+		}
+	}
+
+	if r.Time.IsZero() {
+		r.Time = h.logger.now()
 	}
 
-	h.logger.logWithAttrs(r.Time, convertSlogLevel(r.Level), attrs)
+	h.logger.logWithData(r.Time, convertSlogLevel(r.Level), r.Message, data)
 	return nil
 }
 
 func (h *SlogTextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	h2 := *h
+	if h.structuredAttrs {
+		fields := h.fields.clone()
+		group := groupMap(fields, h.groups)
+		for _, a := range attrs {
+			h.addAttr(group, h.groups, a)
+		}
+		pruneEmptyGroups(fields)
+		h2.fields = fields
+		// the sent copy is converted once, so logs share it
+		h2.fieldsData = fields.clone().plain()
+		return &h2
+	}
+
 	pre := make([]string, len(h.preformattedAttrs), len(h.preformattedAttrs)+len(attrs))
 	copy(pre, h.preformattedAttrs)
 	for _, a := range attrs {
@@ -134,21 +247,119 @@ func (h *SlogTextHandler) safeReplaceAttr(groups []string, a slog.Attr) slog.Att
 	return h.opts.ReplaceAttr(groups, a)
 }
 
-// convertSlogLevel converts slog.Level to logdash.logLevel
-func convertSlogLevel(level slog.Level) logLevel {
+// addAttr adds the attribute to the group, nesting attributes of its groups.
+func (h *SlogTextHandler) addAttr(group slogGroup, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		a = h.safeReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		group[a.Key] = slogValue(a.Value)
+		return
+	}
+	// attributes of group with empty key are inlined
+	if a.Key != "" {
+		group = groupMap(group, []string{a.Key})
+		groups = append(slices.Clip(groups), a.Key)
+	}
+	for _, attr := range a.Value.Group() {
+		h.addAttr(group, groups, attr)
+	}
+}
+
+// slogValue converts resolved [slog.Value] to a value which can be marshaled to JSON.
+func slogValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		switch value := v.Any().(type) {
+		case error:
+			return value.Error()
+		case fmt.Stringer:
+			return value.String()
+		default:
+			if _, err := json.Marshal(value); err != nil {
+				return fmt.Sprintf("%+v", value)
+			}
+			return value
+		}
+	default:
+		return v.Any()
+	}
+}
+
+// groupMap returns the nested group, creating missing groups.
+//
+// Values of attributes are never merged with groups, even if they're maps, as they belong to the caller.
+func groupMap(m slogGroup, groups []string) slogGroup {
+	for _, name := range groups {
+		nested, ok := m[name].(slogGroup)
+		if !ok {
+			nested = make(slogGroup)
+			m[name] = nested
+		}
+		m = nested
+	}
+	return m
+}
+
+// clone returns a deep copy of the group, values of attributes are shared.
+func (g slogGroup) clone() slogGroup {
+	clone := make(slogGroup, len(g))
+	for key, value := range g {
+		if nested, ok := value.(slogGroup); ok {
+			value = nested.clone()
+		}
+		clone[key] = value
+	}
+	return clone
+}
+
+// plain converts the group and its nested groups in place to plain maps, as they're sent.
+func (g slogGroup) plain() map[string]any {
+	for key, value := range g {
+		if nested, ok := value.(slogGroup); ok {
+			g[key] = nested.plain()
+		}
+	}
+	return map[string]any(g)
+}
+
+// pruneEmptyGroups removes groups without attributes, the same way as [slog.TextHandler] ignores them.
+func pruneEmptyGroups(m slogGroup) {
+	for key, value := range m {
+		if nested, ok := value.(slogGroup); ok {
+			pruneEmptyGroups(nested)
+			if len(nested) == 0 {
+				delete(m, key)
+			}
+		}
+	}
+}
+
+// convertSlogLevel converts slog.Level to Level
+func convertSlogLevel(level slog.Level) Level {
 	// slog.Level is an int, so we can use comparison operators
 	// slog.LevelDebug = -4, slog.LevelInfo = 0, slog.LevelWarn = 4, slog.LevelError = 8
 
 	switch {
 	case level < slog.LevelDebug:
-		return logLevelSilly
+		return LevelSilly
 	case level < slog.LevelInfo:
-		return logLevelDebug
+		return LevelDebug
 	case level < slog.LevelWarn:
-		return logLevelInfo
+		return LevelInfo
 	case level < slog.LevelError:
-		return logLevelWarn
+		return LevelWarn
 	default:
-		return logLevelError
+		return LevelError
 	}
 }
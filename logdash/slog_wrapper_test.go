@@ -0,0 +1,288 @@
+package logdash_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogTextHandlerAttrs(t *testing.T) {
+	replaceToken := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "token" {
+			a.Value = slog.StringValue("********")
+		}
+		return a
+	}
+
+	testCases := []struct {
+		name            string
+		handlerOpts     []logdash.SlogHandlerOption
+		log             func(logger *slog.Logger)
+		expectedMessage string
+		expectedData    map[string]any
+	}{
+		{
+			name:        "should send message without attributes separately when configured",
+			handlerOpts: []logdash.SlogHandlerOption{logdash.WithSlogStructuredAttrs()},
+			log: func(logger *slog.Logger) {
+				logger.Info("Hello, slog!")
+			},
+			expectedMessage: "Hello, slog!",
+		},
+		{
+			name:        "should send persistent fields and record attributes separately when configured",
+			handlerOpts: []logdash.SlogHandlerOption{logdash.WithSlogStructuredAttrs()},
+			log: func(logger *slog.Logger) {
+				logger.With("service", "api").Info("Hello, slog!", "id", 7, "ok", true)
+			},
+			expectedMessage: "Hello, slog!",
+			expectedData: map[string]any{
+				"fields": map[string]any{"service": "api"},
+				"attrs":  map[string]any{"id": float64(7), "ok": true},
+			},
+		},
+		{
+			name:        "should preserve group nesting of structured attributes",
+			handlerOpts: []logdash.SlogHandlerOption{logdash.WithSlogStructuredAttrs()},
+			log: func(logger *slog.Logger) {
+				logger.
+					With("service", "api").
+					WithGroup("request").
+					With("method", "GET").
+					WithGroup("empty").
+					Info("Hello, slog!", slog.Group("user", "name", "john", "token", "secret"), slog.Group("", "inlined", 1))
+			},
+			expectedMessage: "Hello, slog!",
+			expectedData: map[string]any{
+				"fields": map[string]any{
+					"service": "api",
+					"request": map[string]any{"method": "GET"},
+				},
+				"attrs": map[string]any{
+					"request": map[string]any{
+						"empty": map[string]any{
+							"user":    map[string]any{"name": "john", "token": "********"},
+							"inlined": float64(1),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "should format attributes as text in message by default",
+			log: func(logger *slog.Logger) {
+				logger.With("service", "api").WithGroup("request").Info("Hello, slog!", "id", 7, "token", "secret")
+			},
+			expectedMessage: `"Hello, slog!" service="api" request.id=7 request.token="********"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			requestsCollector := &requestsCollector{}
+
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer r.Body.Close()
+				w.WriteHeader(http.StatusOK)
+				requestsCollector.add(t, r)
+			}))
+			defer httpServer.Close()
+
+			ld := logdash.New(
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+			)
+			handler := logdash.NewSlogTextHandler(ld.Logger, slog.HandlerOptions{Level: slog.LevelInfo, ReplaceAttr: replaceToken}, tc.handlerOpts...)
+
+			// WHEN
+			tc.log(slog.New(handler))
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			assert.Len(t, requestsCollector.requests, 1)
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(requestsCollector.requests[0].body, &body))
+			assert.Equal(t, tc.expectedMessage, body["message"])
+			if tc.expectedData == nil {
+				assert.NotContains(t, body, "data")
+				return
+			}
+			assert.Equal(t, tc.expectedData, body["data"])
+		})
+	}
+}
+
+func BenchmarkSlogTextHandler(b *testing.B) {
+	ld := logdash.New(logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError))
+	defer ld.Shutdown(context.Background())
+	handler := logdash.NewSlogTextHandler(ld.Logger, slog.HandlerOptions{})
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "Processing request", 0)
+	record.AddAttrs(slog.String("user", "john"), slog.Int("attempt", 3))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		_ = handler.Handle(context.Background(), record)
+	}
+}
+
+func TestSlogTextHandlerStructuredAttrsOfMaps(t *testing.T) {
+	t.Run("should not modify maps passed as attribute values", func(t *testing.T) {
+		// GIVEN
+		sink := logdash.NewMemorySink()
+		ld := logdash.New(
+			logdash.WithSink(sink),
+			logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+		)
+		defer ld.Close()
+		logger := slog.New(logdash.NewSlogTextHandler(ld.Logger, slog.HandlerOptions{Level: slog.LevelInfo}, logdash.WithSlogStructuredAttrs()))
+		user := map[string]any{"name": "john", "roles": map[string]any{}}
+
+		// WHEN
+		logger.With("user", user).Info("first", slog.Group("user", "id", 7))
+		logger.Info("second", "request", user, slog.Group("request", "method", "GET"))
+
+		// THEN
+		assert.Equal(t, map[string]any{"name": "john", "roles": map[string]any{}}, user)
+		entries := sink.Entries()
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, map[string]any{
+				"fields": map[string]any{"user": user},
+				"attrs":  map[string]any{"user": map[string]any{"id": int64(7)}},
+			}, entries[0].Fields)
+			assert.Equal(t, map[string]any{
+				"attrs": map[string]any{"request": map[string]any{"method": "GET"}},
+			}, entries[1].Fields)
+		}
+	})
+}
+
+// spanContextKey carries a fake sampling decision of a span in tests of following trace sampling.
+type spanContextKey struct{}
+
+// otherBaggageKey carries baggage of another library in tests of baggage fields.
+type otherBaggageKey struct{}
+
+func TestSlogTextHandlerBaggageFields(t *testing.T) {
+	baggageSource := func(ctx context.Context, key string) (string, bool) {
+		baggage, _ := ctx.Value(otherBaggageKey{}).(map[string]string)
+		value, ok := baggage[key]
+		return value, ok
+	}
+
+	testCases := []struct {
+		name        string
+		handlerOpts []logdash.SlogHandlerOption
+		expected    map[string]any
+	}{
+		{
+			name:        "should attach selected baggage keys next to structured attributes",
+			handlerOpts: []logdash.SlogHandlerOption{logdash.WithSlogStructuredAttrs()},
+			expected: map[string]any{
+				"tenant": "acme",
+				"cohort": "beta",
+				"region": "eu",
+				"attrs":  map[string]any{"user": "john"},
+			},
+		},
+		{
+			name:     "should attach selected baggage keys to text attributes",
+			expected: map[string]any{"tenant": "acme", "cohort": "beta", "region": "eu"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			sink := logdash.NewMemorySink()
+			ld := logdash.New(
+				logdash.WithSink(sink),
+				logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+				logdash.WithBaggageFields("tenant", "cohort", "region", "absent"),
+				logdash.WithBaggageSource(baggageSource),
+			)
+			defer ld.Close()
+			logger := slog.New(logdash.NewSlogTextHandler(ld.Logger, slog.HandlerOptions{Level: slog.LevelInfo}, tc.handlerOpts...))
+			ctx := logdash.ContextWithBaggage(context.Background(), map[string]string{"tenant": "acme"})
+			ctx = logdash.ContextWithBaggage(ctx, map[string]string{"cohort": "beta", "ignored": "value"})
+			ctx = context.WithValue(ctx, otherBaggageKey{}, map[string]string{"region": "eu"})
+
+			// WHEN
+			logger.InfoContext(ctx, "with baggage", "user", "john")
+			logger.InfoContext(context.Background(), "without baggage")
+
+			// THEN
+			entries := sink.Entries()
+			if assert.Len(t, entries, 2) {
+				assert.Equal(t, tc.expected, entries[0].Fields)
+				assert.Nil(t, entries[1].Fields)
+			}
+		})
+	}
+}
+
+func TestSlogTextHandlerFollowTraceSampling(t *testing.T) {
+	traceSampled := func(ctx context.Context) (bool, bool) {
+		sampled, ok := ctx.Value(spanContextKey{}).(bool)
+		return sampled, ok
+	}
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		expected []string
+	}{
+		{
+			name:     "should keep all logs of a sampled span",
+			ctx:      context.WithValue(context.Background(), spanContextKey{}, true),
+			expected: []string{"debug", "info", "warn", "error"},
+		},
+		{
+			name:     "should drop logs below info of an unsampled span",
+			ctx:      context.WithValue(context.Background(), spanContextKey{}, false),
+			expected: []string{"info", "warn", "error"},
+		},
+		{
+			name:     "should keep all logs without a span",
+			ctx:      context.Background(),
+			expected: []string{"debug", "info", "warn", "error"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			sink := logdash.NewMemorySink()
+			ld := logdash.New(
+				logdash.WithSink(sink),
+				logdash.WithConsoleStreams(io.Discard, io.Discard, logdash.LevelError),
+				logdash.WithFollowTraceSampling(traceSampled),
+			)
+			defer ld.Close()
+			logger := slog.New(logdash.NewSlogTextHandler(ld.Logger, slog.HandlerOptions{Level: slog.LevelDebug}, logdash.WithSlogStructuredAttrs()))
+
+			// WHEN
+			logger.DebugContext(tc.ctx, "debug")
+			logger.InfoContext(tc.ctx, "info")
+			logger.WarnContext(tc.ctx, "warn")
+			logger.ErrorContext(tc.ctx, "error")
+
+			// THEN
+			var messages []string
+			for _, entry := range sink.Entries() {
+				messages = append(messages, entry.Message)
+			}
+			assert.Equal(t, tc.expected, messages)
+		})
+	}
+}
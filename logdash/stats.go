@@ -0,0 +1,280 @@
+package logdash
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+type (
+	// Stats contains statistics of the Logdash SDK itself.
+	//
+	// All counters are cumulative since the creation of the [Logdash] instance.
+	Stats struct {
+		// Logs contains statistics of HTTP requests sending logs.
+		Logs RequestStats
+		// Metrics contains statistics of HTTP requests sending metrics.
+		Metrics RequestStats
+	}
+
+	// RequestStats contains statistics of HTTP requests sent to the Logdash server.
+	//
+	// A request is counted once, no matter how many times it was retried.
+	RequestStats struct {
+		// Requests is the number of completed requests.
+		Requests int64
+		// Status2xx is the number of requests completed with 2xx status.
+		Status2xx int64
+		// Status3xx is the number of requests completed with 3xx status.
+		Status3xx int64
+		// Status4xx is the number of requests completed with 4xx status.
+		Status4xx int64
+		// Status5xx is the number of requests completed with 5xx status.
+		Status5xx int64
+		// Failures is the number of requests completed without any response, e.g. connection errors.
+		Failures int64
+		// Retries is the number of retried attempts.
+		Retries int64
+		// LatencyP50 is the estimated median latency of requests, including retries.
+		LatencyP50 time.Duration
+		// LatencyP95 is the estimated 95th percentile latency of requests, including retries.
+		LatencyP95 time.Duration
+	}
+
+	// Rates contains current rates of the Logdash SDK itself, in events per second.
+	//
+	// Rates are exponentially weighted moving averages over about the last minute,
+	// so unlike [Stats] they show the current load rather than lifetime totals.
+	Rates struct {
+		// Logs contains rates of logs.
+		Logs RequestRates
+		// Metrics contains rates of metrics.
+		Metrics RequestRates
+	}
+
+	// RequestRates contains rates of items sent to the Logdash server, in items per second.
+	RequestRates struct {
+		// Sent is the rate of items successfully sent.
+		Sent float64
+		// Dropped is the rate of items dropped, e.g. due to buffer overflow or rejected as too large.
+		Dropped float64
+	}
+
+	// requestStats collects statistics of HTTP requests to a single endpoint.
+	requestStats struct {
+		requests atomic.Int64
+		// statuses counts requests by status class, indexed by status / 100
+		statuses [6]atomic.Int64
+		failures atomic.Int64
+		retries  atomic.Int64
+		latency  latencyHistogram
+		sent     ewmaRate
+		dropped  ewmaRate
+	}
+
+	// ewmaRate is a lock-free exponentially weighted moving average of a rate.
+	//
+	// Events are counted as they come and the average is updated lazily,
+	// once per ewmaTickInterval, by whoever marks or reads the rate first.
+	ewmaRate struct {
+		uncounted atomic.Int64
+		// rate is float64 bits of the average rate in events per second
+		rate atomic.Uint64
+		// lastTick is the time of the last update in Unix nanoseconds, 0 before the first event
+		lastTick atomic.Int64
+	}
+
+	// latencyHistogram counts latencies in fixed buckets (see: latencyBuckets).
+	latencyHistogram struct {
+		// the last bucket counts latencies above the last bound
+		counts [len(latencyBuckets) + 1]atomic.Int64
+	}
+
+	// requestTrace tracks a single request across its retries.
+	requestTrace struct {
+		stats *requestStats
+		// lastStatus is the status of the last received response, 0 if there was none
+		lastStatus int
+	}
+
+	// requestTraceKey is the context key for requestTrace of the request.
+	requestTraceKey struct{}
+)
+
+const (
+	// ewmaTickInterval is the interval of ewmaRate updates.
+	ewmaTickInterval = time.Second
+	// ewmaWindow is the time window of ewmaRate average.
+	ewmaWindow = time.Minute
+)
+
+// ewmaAlpha is the weight of the last interval in ewmaRate average.
+var ewmaAlpha = 1 - math.Exp(-ewmaTickInterval.Seconds()/ewmaWindow.Seconds())
+
+// latencyBuckets are upper bounds of latencyHistogram buckets.
+var latencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// record registers a completed request.
+// Status is 0 when the request completed without any response.
+func (s *requestStats) record(status int, latency time.Duration) {
+	s.requests.Add(1)
+	if class := status / 100; status > 0 && class < len(s.statuses) {
+		s.statuses[class].Add(1)
+	} else {
+		s.failures.Add(1)
+	}
+	if status/100 == 2 {
+		s.sent.mark(time.Now())
+	}
+	s.latency.observe(latency)
+}
+
+// recordDrop registers an item dropped before sending.
+func (s *requestStats) recordDrop() {
+	s.dropped.mark(time.Now())
+}
+
+// rates returns the current rates.
+func (s *requestStats) rates() RequestRates {
+	now := time.Now()
+	return RequestRates{
+		Sent:    s.sent.perSecond(now),
+		Dropped: s.dropped.perSecond(now),
+	}
+}
+
+// snapshot returns the current statistics.
+func (s *requestStats) snapshot() RequestStats {
+	return RequestStats{
+		Requests:   s.requests.Load(),
+		Status2xx:  s.statuses[2].Load(),
+		Status3xx:  s.statuses[3].Load(),
+		Status4xx:  s.statuses[4].Load(),
+		Status5xx:  s.statuses[5].Load(),
+		Failures:   s.failures.Load(),
+		Retries:    s.retries.Load(),
+		LatencyP50: s.latency.percentile(0.5),
+		LatencyP95: s.latency.percentile(0.95),
+	}
+}
+
+// mark registers a single event.
+func (r *ewmaRate) mark(now time.Time) {
+	r.tick(now)
+	r.uncounted.Add(1)
+}
+
+// perSecond returns the average rate in events per second.
+func (r *ewmaRate) perSecond(now time.Time) float64 {
+	r.tick(now)
+	return math.Float64frombits(r.rate.Load())
+}
+
+// tick updates the average for every interval elapsed since the last update.
+//
+// Only one caller wins the update of lastTick, so concurrent callers don't count intervals twice.
+func (r *ewmaRate) tick(now time.Time) {
+	nowNano := now.UnixNano()
+	last := r.lastTick.Load()
+	if last == 0 {
+		r.lastTick.CompareAndSwap(0, nowNano)
+		return
+	}
+	age := nowNano - last
+	if age < int64(ewmaTickInterval) {
+		return
+	}
+	ticks := age / int64(ewmaTickInterval)
+	if !r.lastTick.CompareAndSwap(last, last+ticks*int64(ewmaTickInterval)) {
+		return
+	}
+
+	rate := math.Float64frombits(r.rate.Load())
+	// events are attributed to the first elapsed interval, following intervals are idle
+	instant := float64(r.uncounted.Swap(0)) / ewmaTickInterval.Seconds()
+	rate += ewmaAlpha * (instant - rate)
+	// idle intervals decay the rate
+	rate *= math.Pow(1-ewmaAlpha, float64(ticks-1))
+	r.rate.Store(math.Float64bits(rate))
+}
+
+// observe registers a single latency.
+func (h *latencyHistogram) observe(latency time.Duration) {
+	for i, bound := range latencyBuckets {
+		if latency <= bound {
+			h.counts[i].Add(1)
+			return
+		}
+	}
+	h.counts[len(latencyBuckets)].Add(1)
+}
+
+// percentile estimates the p-th percentile (0 to 1) as the upper bound of the bucket containing it.
+//
+// Latencies above the last bucket bound are estimated as the last bucket bound.
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	var counts [len(latencyBuckets) + 1]int64
+	var total int64
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(p * float64(total)))
+	var cumulative int64
+	for i, bound := range latencyBuckets {
+		cumulative += counts[i]
+		if cumulative >= rank {
+			return bound
+		}
+	}
+	return latencyBuckets[len(latencyBuckets)-1]
+}
+
+// withRequestTrace returns a context carrying the trace of the request.
+func withRequestTrace(ctx context.Context, trace *requestTrace) context.Context {
+	return context.WithValue(ctx, requestTraceKey{}, trace)
+}
+
+// requestTraceFromContext returns the trace of the request, or nil if there is none.
+func requestTraceFromContext(ctx context.Context) *requestTrace {
+	trace, _ := ctx.Value(requestTraceKey{}).(*requestTrace)
+	return trace
+}
+
+// traceRequestHook counts retried attempts of the traced request.
+func traceRequestHook(_ retryablehttp.Logger, req *http.Request, attempt int) {
+	if trace := requestTraceFromContext(req.Context()); trace != nil && attempt > 0 {
+		trace.stats.retries.Add(1)
+	}
+}
+
+// traceResponseHook remembers the status of the last response of the traced request.
+func traceResponseHook(_ retryablehttp.Logger, resp *http.Response) {
+	if trace := requestTraceFromContext(resp.Request.Context()); trace != nil {
+		trace.lastStatus = resp.StatusCode
+	}
+}
@@ -0,0 +1,100 @@
+package logdash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistogramPercentile(t *testing.T) {
+	t.Run("should estimate percentiles with bucket bounds", func(t *testing.T) {
+		// GIVEN
+		h := &latencyHistogram{}
+
+		// WHEN
+		for range 90 {
+			h.observe(3 * time.Millisecond)
+		}
+		for range 10 {
+			h.observe(700 * time.Millisecond)
+		}
+
+		// THEN
+		assert.Equal(t, 5*time.Millisecond, h.percentile(0.5))
+		assert.Equal(t, time.Second, h.percentile(0.95))
+	})
+
+	t.Run("should estimate latencies above the last bucket as the last bound", func(t *testing.T) {
+		// GIVEN
+		h := &latencyHistogram{}
+
+		// WHEN
+		h.observe(time.Hour)
+
+		// THEN
+		assert.Equal(t, time.Minute, h.percentile(0.5))
+	})
+
+	t.Run("should return zero without observations", func(t *testing.T) {
+		assert.Equal(t, time.Duration(0), (&latencyHistogram{}).percentile(0.5))
+	})
+}
+
+func TestEWMARate(t *testing.T) {
+	t.Run("should converge to the driven rate", func(t *testing.T) {
+		// GIVEN
+		r := &ewmaRate{}
+		now := time.Unix(1_000_000, 0)
+
+		// WHEN
+		// 20 events per second for 5 minutes
+		for range 5 * 60 * 20 {
+			r.mark(now)
+			now = now.Add(50 * time.Millisecond)
+		}
+
+		// THEN
+		assert.InDelta(t, 20, r.perSecond(now), 0.5)
+	})
+
+	t.Run("should follow a change of the rate", func(t *testing.T) {
+		// GIVEN
+		r := &ewmaRate{}
+		now := time.Unix(1_000_000, 0)
+		for range 5 * 60 * 20 {
+			r.mark(now)
+			now = now.Add(50 * time.Millisecond)
+		}
+
+		// WHEN
+		// 2 events per second for 5 minutes
+		for range 5 * 60 * 2 {
+			r.mark(now)
+			now = now.Add(500 * time.Millisecond)
+		}
+
+		// THEN
+		assert.InDelta(t, 2, r.perSecond(now), 0.2)
+	})
+
+	t.Run("should decay when there are no events", func(t *testing.T) {
+		// GIVEN
+		r := &ewmaRate{}
+		now := time.Unix(1_000_000, 0)
+		for range 5 * 60 * 20 {
+			r.mark(now)
+			now = now.Add(50 * time.Millisecond)
+		}
+
+		// WHEN
+		now = now.Add(10 * time.Minute)
+
+		// THEN
+		assert.InDelta(t, 0, r.perSecond(now), 0.01)
+	})
+
+	t.Run("should return zero without events", func(t *testing.T) {
+		assert.Equal(t, float64(0), (&ewmaRate{}).perSecond(time.Now()))
+	})
+}
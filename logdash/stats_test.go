@@ -0,0 +1,85 @@
+package logdash_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashStats(t *testing.T) {
+	t.Run("should count successful and failing requests", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			if r.URL.Path == "/metrics" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+			logdash.WithHTTPRetries(1),
+			logdash.WithHTTPRetryMin(time.Millisecond),
+			logdash.WithHTTPRetryMax(time.Millisecond),
+		)
+
+		// WHEN
+		for range 3 {
+			ld.Logger.Info("Hello, World!")
+		}
+		ld.Metrics.Set("first-metric", 1)
+		ld.Metrics.Set("second-metric", 1)
+		err := ld.Shutdown(context.Background())
+		stats := ld.Stats()
+
+		// THEN
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(3), stats.Logs.Requests)
+		assert.Equal(t, int64(3), stats.Logs.Status2xx)
+		assert.Equal(t, int64(0), stats.Logs.Status5xx)
+		assert.Equal(t, int64(0), stats.Logs.Retries)
+		assert.Greater(t, stats.Logs.LatencyP50, time.Duration(0))
+		assert.GreaterOrEqual(t, stats.Logs.LatencyP95, stats.Logs.LatencyP50)
+
+		assert.Equal(t, int64(2), stats.Metrics.Requests)
+		assert.Equal(t, int64(0), stats.Metrics.Status2xx)
+		assert.Equal(t, int64(2), stats.Metrics.Status5xx)
+		assert.Equal(t, int64(2), stats.Metrics.Retries)
+	})
+
+	t.Run("should count requests without response as failures", func(t *testing.T) {
+		// GIVEN
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		httpServer.Close()
+
+		ld := logdash.New(
+			logdash.WithHost(httpServer.URL),
+			logdash.WithAPIKey("test-api-key"),
+		)
+
+		// WHEN
+		ld.Logger.Info("Hello, World!")
+		err := ld.Shutdown(context.Background())
+		stats := ld.Stats()
+
+		// THEN
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), stats.Logs.Requests)
+		assert.Equal(t, int64(1), stats.Logs.Failures)
+	})
+
+	t.Run("should return zero stats without API key", func(t *testing.T) {
+		ld := logdash.New()
+		assert.Equal(t, logdash.Stats{}, ld.Stats())
+	})
+}
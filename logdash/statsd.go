@@ -0,0 +1,127 @@
+package logdash
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// statsdBufferSize is the number of lines waiting to be written to statsd, following lines are dropped.
+const statsdBufferSize = 1024
+
+// statsdNameReplacer replaces characters which delimit statsd lines in metric names.
+var statsdNameReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "\n", "_")
+
+// statsdMirror mirrors metric operations to a statsd endpoint over UDP (see: [WithStatsdMirror]).
+//
+// Lines are written by a background goroutine, so mirroring never blocks operations on metrics.
+type statsdMirror struct {
+	conn           net.Conn
+	internalLogger *Logger
+
+	// linesChan is closed under the write lock, senders hold the read lock
+	linesChan   chan string
+	linesChanMu sync.RWMutex
+	closed      bool
+	stoppedChan chan struct{}
+}
+
+// newStatsdMirror creates a new statsdMirror instance sending to the address.
+func newStatsdMirror(addr string, internalLogger *Logger) (*statsdMirror, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	m := &statsdMirror{
+		conn:           conn,
+		internalLogger: internalLogger,
+		linesChan:      make(chan string, statsdBufferSize),
+		stoppedChan:    make(chan struct{}),
+	}
+	go m.writeLoop()
+	return m, nil
+}
+
+func (m *statsdMirror) writeLoop() {
+	defer close(m.stoppedChan)
+	defer m.conn.Close()
+
+	for line := range m.linesChan {
+		if _, err := m.conn.Write([]byte(line)); err != nil {
+			m.internalLogger.VerboseF("Failed to mirror metric to statsd: %v", err)
+		}
+	}
+}
+
+// gauge mirrors setting the metric to the absolute value.
+func (m *statsdMirror) gauge(name string, value float64) {
+	if m == nil {
+		return
+	}
+	line := statsdLine(name, value, "g")
+	if value < 0 {
+		// a signed gauge value is a change in statsd, so the gauge is reset first
+		line = statsdLine(name, 0, "g") + "\n" + line
+	}
+	m.send(line)
+}
+
+// count mirrors changing the metric by the relative value.
+func (m *statsdMirror) count(name string, value float64) {
+	if m == nil {
+		return
+	}
+	m.send(statsdLine(name, value, "c"))
+}
+
+// timing mirrors the measured duration in milliseconds.
+func (m *statsdMirror) timing(name string, milliseconds float64) {
+	if m == nil {
+		return
+	}
+	m.send(statsdLine(name, milliseconds, "ms"))
+}
+
+// histogram mirrors a single observation of the metric.
+func (m *statsdMirror) histogram(name string, value float64) {
+	if m == nil {
+		return
+	}
+	m.send(statsdLine(name, value, "h"))
+}
+
+// send enqueues the line to be written, dropping it when the buffer is full or the mirror is closed.
+func (m *statsdMirror) send(line string) {
+	m.linesChanMu.RLock()
+	defer m.linesChanMu.RUnlock()
+
+	if m.closed {
+		return
+	}
+	select {
+	case m.linesChan <- line:
+	default:
+		m.internalLogger.VerboseF("Metric not mirrored to statsd: %v", ErrOverflow)
+	}
+}
+
+// close stops the mirror after enqueued lines are written.
+func (m *statsdMirror) close() {
+	if m == nil {
+		return
+	}
+
+	m.linesChanMu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.linesChan)
+	}
+	m.linesChanMu.Unlock()
+	<-m.stoppedChan
+}
+
+// statsdLine formats the metric as a statsd line of the type.
+func statsdLine(name string, value float64, metricType string) string {
+	return statsdNameReplacer.Replace(name) + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + metricType
+}
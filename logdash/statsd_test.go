@@ -0,0 +1,118 @@
+package logdash_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashStatsdMirror(t *testing.T) {
+	// readLines reads statsd lines from packets until the expected number of lines is read or reading times out.
+	readLines := func(t *testing.T, conn net.PacketConn, expected int) []string {
+		t.Helper()
+
+		var lines []string
+		buf := make([]byte, 1024)
+		for len(lines) < expected {
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				break
+			}
+			lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+		}
+		return lines
+	}
+
+	tests := []struct {
+		name      string
+		operation func(ld *logdash.Logdash, now *time.Time)
+		expected  []string
+	}{
+		{
+			name: "should mirror set as gauge",
+			operation: func(ld *logdash.Logdash, _ *time.Time) {
+				ld.Metrics.Set("users", 42)
+			},
+			expected: []string{"users:42|g"},
+		},
+		{
+			name: "should reset gauge before mirroring negative set",
+			operation: func(ld *logdash.Logdash, _ *time.Time) {
+				ld.Metrics.Set("temperature", -3.5)
+			},
+			expected: []string{"temperature:0|g", "temperature:-3.5|g"},
+		},
+		{
+			name: "should mirror mutate as count",
+			operation: func(ld *logdash.Logdash, _ *time.Time) {
+				ld.Metrics.Mutate("requests", 1)
+				ld.Metrics.Mutate("requests", -2)
+			},
+			expected: []string{"requests:1|c", "requests:-2|c"},
+		},
+		{
+			name: "should mirror timing in milliseconds",
+			operation: func(ld *logdash.Logdash, now *time.Time) {
+				stop := ld.ExtendedMetrics().Timing("db.query")
+				*now = now.Add(1500 * time.Microsecond)
+				stop()
+			},
+			expected: []string{"db.query:1.5|ms"},
+		},
+		{
+			name: "should mirror observation as histogram",
+			operation: func(ld *logdash.Logdash, _ *time.Time) {
+				ld.ExtendedMetrics().Observe("latency", 12.5)
+			},
+			expected: []string{"latency:12.5|h"},
+		},
+		{
+			name: "should replace reserved characters in names",
+			operation: func(ld *logdash.Logdash, _ *time.Time) {
+				ld.Metrics.Set("a:b|c@d", 1)
+			},
+			expected: []string{"a_b_c_d:1|g"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Close()
+
+			now := time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)
+			ld := logdash.New(
+				logdash.WithStatsdMirror(conn.LocalAddr().String()),
+				logdash.WithTimeSource(func() time.Time { return now }),
+			)
+			defer ld.Close()
+
+			// WHEN
+			tt.operation(ld, &now)
+
+			// THEN
+			assert.Equal(t, tt.expected, readLines(t, conn, len(tt.expected)))
+		})
+	}
+
+	t.Run("should keep tracking metrics when statsd endpoint is invalid", func(t *testing.T) {
+		// GIVEN
+		ld := logdash.New(logdash.WithStatsdMirror("invalid address"))
+		defer ld.Close()
+
+		// WHEN
+		ld.Metrics.Set("users", 42)
+
+		// THEN
+		assert.Equal(t, map[string]float64{"users": 42}, ld.ExtendedMetrics().Snapshot())
+	})
+}
@@ -0,0 +1,83 @@
+package logdash_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/logdash-io/go-sdk/logdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogdashTLSConfig(t *testing.T) {
+	httpServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer httpServer.Close()
+
+	pinnedPool := x509.NewCertPool()
+	pinnedPool.AddCert(httpServer.Certificate())
+
+	testCases := []struct {
+		name             string
+		opts             []logdash.Option
+		expectedRequests int64
+		expectedFailures int64
+	}{
+		{
+			name: "should send logs when server certificate is pinned",
+			opts: []logdash.Option{
+				logdash.WithTLSConfig(&tls.Config{RootCAs: pinnedPool}),
+			},
+			expectedRequests: 1,
+		},
+		{
+			name: "should not send logs when server certificate is not pinned",
+			opts: []logdash.Option{
+				logdash.WithTLSConfig(&tls.Config{RootCAs: x509.NewCertPool()}),
+			},
+			expectedRequests: 1,
+			expectedFailures: 1,
+		},
+		{
+			name: "should apply TLS config to transport of provided HTTP client",
+			opts: []logdash.Option{
+				logdash.WithHTTPClient(&http.Client{Transport: &http.Transport{}}),
+				logdash.WithTLSConfig(&tls.Config{RootCAs: pinnedPool}),
+			},
+			expectedRequests: 1,
+		},
+		{
+			name: "should use provided HTTP client when TLS config is not set",
+			opts: []logdash.Option{
+				logdash.WithHTTPClient(httpServer.Client()),
+			},
+			expectedRequests: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// GIVEN
+			ld := logdash.New(append([]logdash.Option{
+				logdash.WithHost(httpServer.URL),
+				logdash.WithAPIKey("test-api-key"),
+				logdash.WithHTTPRetries(0),
+			}, tc.opts...)...)
+
+			// WHEN
+			ld.Logger.Info("Hello, TLS!")
+			err := ld.Shutdown(context.Background())
+
+			// THEN
+			assert.NoError(t, err)
+			stats := ld.Stats()
+			assert.Equal(t, tc.expectedRequests, stats.Logs.Requests)
+			assert.Equal(t, tc.expectedFailures, stats.Logs.Failures)
+		})
+	}
+}
@@ -4,10 +4,10 @@ import "context"
 
 type verboseLogMetricsWrapper struct {
 	logger  *Logger
-	metrics Metrics
+	metrics metricsBackend
 }
 
-func newVerboseLogMetricsWrapper(logger *Logger, metrics Metrics) *verboseLogMetricsWrapper {
+func newVerboseLogMetricsWrapper(logger *Logger, metrics metricsBackend) *verboseLogMetricsWrapper {
 	return &verboseLogMetricsWrapper{
 		logger:  logger,
 		metrics: metrics,